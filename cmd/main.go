@@ -1,18 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"effective-mobile/database"
+	"effective-mobile/internal/events"
+	"effective-mobile/internal/gql"
+	"effective-mobile/internal/grpcserver"
 	"effective-mobile/internal/handler"
 	"effective-mobile/internal/model"
+	"effective-mobile/internal/openapi"
+	"effective-mobile/internal/outbox"
 	"effective-mobile/internal/repository"
 	"effective-mobile/internal/service"
 	"effective-mobile/pkg/logger"
 
 	httpSwagger "github.com/swaggo/http-swagger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // @title           People Info API
@@ -25,21 +40,232 @@ func main() {
 	defer logger.Log.Sync()
 
 	db := database.NewDB()
-	db.AutoMigrate(&model.Person{})
+	db.AutoMigrate(&model.Person{}, &model.PersonTombstone{}, &model.IdempotencyRecord{}, &model.PersonHistory{}, &model.View{}, &model.Webhook{}, &model.WebhookDelivery{}, &model.OutboxMessage{}, &model.OutboxDelivery{})
 
 	repo := repository.NewPersonRepository(db)
-	svc := service.NewPersonService(repo)
-	personHandler := handler.NewPersonHandler(svc)
+	if err := repo.CheckChangeFeed(); err != nil {
+		log.Fatalf("failed to check the change feed: %v", err)
+	}
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	historyRepo := repository.NewHistoryRepository(db)
+	viewRepo := repository.NewViewRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookSvc := service.NewWebhookService(webhookRepo).WithMaxAttempts(envInt("WEBHOOK_MAX_ATTEMPTS", 8))
+	webhookHandler := handler.NewWebhookHandler(webhookSvc).WithAdminToken(os.Getenv("ADMIN_TOKEN"))
+	outboxRepo := repository.NewOutboxRepository(db)
+	lastEventID, err := outboxRepo.LastDispatchedID()
+	if err != nil {
+		log.Fatalf("failed to read outbox: %v", err)
+	}
+	broker := events.NewBroker(envInt("EVENT_BACKLOG", 1000)).WithStartID(lastEventID)
+	dispatcher := outbox.NewDispatcher(outboxRepo, outboxSinks(os.Getenv("OUTBOX_SINKS"), broker, webhookSvc)...).
+		WithMaxAttempts(envInt("OUTBOX_MAX_ATTEMPTS", 10))
+	svc := service.NewPersonService(repo).WithHistory(historyRepo).WithViews(viewRepo).WithOutbox(dispatcher.Notify)
+	personHandler := handler.NewPersonHandler(svc).
+		WithEvents(broker).
+		WithSubscriberLimit(envInt("MAX_SUBSCRIBERS", 1000)).
+		WithBatchLimit(envInt("BATCH_MAX_SIZE", 500)).
+		WithAdminToken(os.Getenv("ADMIN_TOKEN"))
+	schema, err := gql.NewSchema(svc)
+	if err != nil {
+		log.Fatalf("failed to build GraphQL schema: %v", err)
+	}
+	graphqlHandler := handler.NewGraphQLHandler(schema.WithLimits(envInt("GRAPHQL_MAX_DEPTH", 10), envInt("GRAPHQL_MAX_COMPLEXITY", 1000)))
+
+	go func() {
+		n, err := svc.RefreshSearchKeys(context.Background())
+		if err != nil {
+			logger.Log.Error("failed to refresh search keys", zap.Error(err))
+			return
+		}
+		if n > 0 {
+			logger.Log.Info("search keys refreshed", zap.Int("count", n))
+		}
+	}()
+
+	if days := envInt("TRASH_RETENTION_DAYS", 30); days > 0 {
+		go svc.RunTrashRetention(context.Background(), time.Duration(days)*24*time.Hour, time.Hour)
+	}
+
+	go dispatcher.Run(context.Background(), envDuration("OUTBOX_POLL_INTERVAL", time.Second), envDuration("OUTBOX_RETENTION", 7*24*time.Hour))
+	go webhookSvc.Run(context.Background(), 5*time.Second)
+
+	idempotencyTTL := envDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+
+	v1 := handler.V1(personHandler, webhookHandler, graphqlHandler, func(h http.HandlerFunc) http.HandlerFunc {
+		return handler.Idempotent(idempotencyRepo, idempotencyTTL, h)
+	})
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /person", personHandler.CreatePerson)
-	mux.HandleFunc("GET /person", personHandler.GetAllPersons)
-	mux.HandleFunc("GET /person/{id}", personHandler.GetPersonByID)
-	mux.HandleFunc("PUT /person/{id}", personHandler.UpdatePerson)
-	mux.HandleFunc("DELETE /person/{id}", personHandler.DeletePerson)
+	root := validateOpenAPI(os.Getenv("OPENAPI_VALIDATE"), mux)
+	handler.Mount(mux, "/v1", v1)
+	handler.MountAliases(mux, "/v1", v1, handler.Deprecation{
+		Since:  envDate("LEGACY_ROUTES_DEPRECATED", "2026-10-19"),
+		Sunset: envDate("LEGACY_ROUTES_SUNSET", "2027-04-19"),
+	}, root)
 
+	mux.HandleFunc("GET /openapi.json", openapi.ServeDocument)
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
 
-	fmt.Println("Server running on :8080")
-	log.Fatal(http.ListenAndServe(":8080", mux))
+	grpcAddr := ":" + envString("GRPC_PORT", "9090")
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", grpcAddr, err)
+	}
+	grpcServer := grpcserver.NewServer(svc)
+	if envBool("GRPC_REFLECTION", false) {
+		grpcserver.RegisterReflection(grpcServer)
+	}
+	grpcErr := make(chan error, 1)
+	go func() {
+		logger.Log.Info("gRPC server running", zap.String("addr", grpcAddr))
+		grpcErr <- grpcServer.Serve(lis)
+	}()
+
+	srv := &http.Server{Addr: ":8080", Handler: root}
+	httpErr := make(chan error, 1)
+	go func() {
+		fmt.Println("Server running on :8080")
+		httpErr <- srv.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-grpcErr:
+		srv.Close()
+		log.Fatalf("gRPC server failed: %v", err)
+	case err := <-httpErr:
+		grpcServer.Stop()
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	logger.Log.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Error("failed to shut down the HTTP server", zap.Error(err))
+	}
+	stopGRPC(shutdownCtx, grpcServer)
+}
+
+const shutdownTimeout = 15 * time.Second
+
+// stopGRPC waits for running calls to finish, cutting them off when ctx is
+// done.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Log.Warn("gRPC calls still running at shutdown, stopping them")
+		srv.Stop()
+	}
+}
+
+// outboxSinks builds the sinks named in the comma-separated list: feed
+// (SSE and WebSocket), webhooks, log and file (a message broker stand-in
+// writing to OUTBOX_FILE). The default is feed and webhooks.
+func outboxSinks(names string, broker *events.Broker, webhooks *service.WebhookService) []outbox.Sink {
+	if names == "" {
+		names = "feed,webhooks"
+	}
+
+	var sinks []outbox.Sink
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "feed":
+			sinks = append(sinks, outbox.PublisherSink("feed", broker))
+		case "webhooks":
+			sinks = append(sinks, webhooks)
+		case "log":
+			sinks = append(sinks, outbox.LogSink{})
+		case "file":
+			path := os.Getenv("OUTBOX_FILE")
+			if path == "" {
+				path = "events.jsonl"
+			}
+			file, err := outbox.NewFileBroker(path)
+			if err != nil {
+				log.Fatalf("failed to open %s: %v", path, err)
+			}
+			sinks = append(sinks, outbox.BrokerSink{Broker: file, Prefix: "person"})
+		default:
+			log.Fatalf("unknown outbox sink %q", name)
+		}
+	}
+	return sinks
+}
+
+// validateOpenAPI wraps h in the OpenAPI validator: "requests" checks the
+// requests, "all" the responses too, and an empty mode neither.
+func validateOpenAPI(mode string, h http.Handler) http.Handler {
+	if mode == "" {
+		return h
+	}
+	if mode != "requests" && mode != "all" {
+		log.Fatalf("invalid OPENAPI_VALIDATE %q: expected requests or all", mode)
+	}
+	v, err := openapi.NewValidator(openapi.Document())
+	if err != nil {
+		log.Fatalf("failed to load OpenAPI document: %v", err)
+	}
+	return v.WithResponses(mode == "all").Middleware(h)
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return d
+}
+
+// envDate reads a date in the YYYY-MM-DD form.
+func envDate(key, fallback string) time.Time {
+	t, err := time.Parse(time.DateOnly, envString(key, fallback))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return t
+}
+
+func envString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return b
+}
+
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return n
 }
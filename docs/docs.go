@@ -15,9 +15,69 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/person": {
+        "/openapi.json": {
             "get": {
-                "description": "Возвращает список всех сохранённых людей",
+                "description": "Описание API в формате OpenAPI 3.1 с телами ошибок; по нему проверяются запросы, если включён OPENAPI_VALIDATE",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "openapi"
+                ],
+                "summary": "Документ OpenAPI 3.1",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/graphql": {
+            "post": {
+                "description": "Выполняет GraphQL-запрос: person(id), people(filter, sort, first, after) с курсорной пагинацией, stats и мутации createPerson, updatePerson, deletePerson. Клиент выбирает только нужные поля. Запросы глубже или сложнее лимитов отклоняются до выполнения; сложность считает каждое поле, stats — как сто полей, а поля внутри people — столько раз, сколько людей может вернуть страница. Ошибки отдельных полей возвращаются в errors с кодом в extensions.code при статусе 200",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL",
+                "parameters": [
+                    {
+                        "description": "Запрос",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/gql.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data и errors",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "невалидный запрос или превышены лимиты",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person": {
+            "get": {
+                "description": "Возвращает список сохранённых людей с фильтрами и пагинацией",
                 "produces": [
                     "application/json"
                 ],
@@ -25,6 +85,68 @@ const docTemplate = `{
                     "persons"
                 ],
                 "summary": "Получение всех людей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -35,6 +157,12 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "failed to get persons",
                         "schema": {
@@ -44,7 +172,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Создаёт нового человека и обогащает его данными через внешние API",
+                "description": "Создаёт нового человека и обогащает его данными через внешние API. Если человек с таким же нормализованным ФИО уже есть, возвращает 409 со списком кандидатов, пока не передан force=true; проверка выполняется до обращения к внешним API",
                 "consumes": [
                     "application/json"
                 ],
@@ -56,6 +184,24 @@ const docTemplate = `{
                 ],
                 "summary": "Создание человека",
                 "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Создать, даже если найдены возможные дубликаты",
+                        "name": "force",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности для безопасных повторов",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Автор изменения",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
                     {
                         "description": "Данные человека",
                         "name": "person",
@@ -67,14 +213,26 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/model.Person"
                         }
                     },
                     "400": {
-                        "description": "invalid JSON",
+                        "description": "invalid JSON or force",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "person may already exist, or request with this Idempotency-Key is in progress",
+                        "schema": {
+                            "$ref": "#/definitions/model.DuplicateConflict"
+                        }
+                    },
+                    "422": {
+                        "description": "Idempotency-Key was used with a different payload",
                         "schema": {
                             "type": "string"
                         }
@@ -86,50 +244,113 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/person/{id}": {
-            "get": {
-                "description": "Возвращает данные конкретного человека",
+            },
+            "delete": {
+                "description": "Удаляет всех людей, подходящих под фильтр. Сначала нужно выполнить запрос с dry_run=true, чтобы узнать количество и получить confirm_token",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "persons"
                 ],
-                "summary": "Получение человека по ID",
+                "summary": "Массовое удаление людей",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID человека",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только подсчитать совпадения",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен подтверждения из dry run",
+                        "name": "confirm",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Person"
+                            "$ref": "#/definitions/model.BulkResult"
                         }
                     },
                     "400": {
-                        "description": "invalid ID",
+                        "description": "invalid filter",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "person not found",
+                    "409": {
+                        "description": "confirmation token does not match the current selection",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "confirmation token required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to delete",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             },
-            "put": {
-                "description": "Обновляет существующего человека по ID",
+            "patch": {
+                "description": "Обновляет всех людей, подходящих под фильтр. Сначала нужно выполнить запрос с dry_run=true, чтобы узнать количество и получить confirm_token",
                 "consumes": [
                     "application/json"
                 ],
@@ -139,17 +360,70 @@ const docTemplate = `{
                 "tags": [
                     "persons"
                 ],
-                "summary": "Обновление человека",
+                "summary": "Массовое обновление людей",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "ID человека",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
                     },
                     {
-                        "description": "Обновлённые данные",
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Только подсчитать совпадения",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен подтверждения из dry run",
+                        "name": "confirm",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Новые значения полей",
                         "name": "person",
                         "in": "body",
                         "required": true,
@@ -162,11 +436,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Person"
+                            "$ref": "#/definitions/model.BulkResult"
                         }
                     },
                     "400": {
-                        "description": "invalid ID or JSON",
+                        "description": "invalid filter or JSON",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "confirmation token does not match the current selection",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "428": {
+                        "description": "confirmation token required",
                         "schema": {
                             "type": "string"
                         }
@@ -178,49 +464,1959 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаляет человека по ID",
+            }
+        },
+        "/v1/person/batch": {
+            "post": {
+                "description": "Создаёт и обогащает несколько людей за один запрос. В режиме atomic (по умолчанию) ошибка любого элемента отменяет весь пакет, в режиме partial успешные элементы сохраняются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "persons"
                 ],
-                "summary": "Удаление человека",
+                "summary": "Пакетное создание людей",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID человека",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "enum": [
+                            "atomic",
+                            "partial"
+                        ],
+                        "type": "string",
+                        "description": "Режим вставки",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ идемпотентности для безопасных повторов",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Список людей",
+                        "name": "persons",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.CreatePersonRequest"
+                            }
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "no content",
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BatchItemResult"
+                            }
+                        }
+                    },
+                    "207": {
+                        "description": "Multi-Status",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BatchItemResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid JSON",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "batch too large",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BatchItemResult"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/changes": {
+            "get": {
+                "description": "Возвращает людей, созданных или изменённых после токена since, и надгробия (deleted) удалённых после него, в порядке изменений. Без since возвращается полная выгрузка живых людей. Токен из ответа передаётся как since в следующем запросе; пока has_more равно true, следующая страница уже готова. Применяя страницы по порядку, клиент поддерживает точную копию без повторной выгрузки",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Изменения для синхронизации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен из предыдущего ответа",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы, по умолчанию 500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PersonChanges"
+                        }
+                    },
                     "400": {
-                        "description": "invalid ID",
+                        "description": "invalid change token or limit",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "failed to delete",
+                        "description": "failed to get changes",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.CreatePersonRequest": {
-            "type": "object",
-            "required": [
+        },
+        "/v1/person/duplicates": {
+            "get": {
+                "description": "Группирует людей с одинаковым нормализованным ФИО, по тому же правилу, что и проверка дубликатов при создании. Фильтры сужают выборку людей, limit и offset листают группы",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "duplicates"
+                ],
+                "summary": "Возможные дубликаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Число групп",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение по группам",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.DuplicateCluster"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to find duplicates",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/events": {
+            "get": {
+                "description": "Поток Server-Sent Events о создании, изменении, удалении и обогащении людей. Фильтры списка отбирают события по состоянию человека после изменения. При переподключении заголовок Last-Event-ID (или параметр last_event_id) продолжает поток с пропущенных событий; если их уже нет в буфере, сначала приходит событие reset, и клиенту нужно перечитать список",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Лента изменений (SSE)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "поток событий",
+                        "schema": {
+                            "$ref": "#/definitions/model.PersonEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter or event ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "event feed is not enabled or too many subscribers",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/export": {
+            "get": {
+                "description": "Потоково выгружает людей, подходящих под фильтры списка, в формате CSV, NDJSON или XLSX",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Выгрузка людей",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "description": "Формат выгрузки",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "persons",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid format or filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to export persons",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/import": {
+            "post": {
+                "description": "Принимает CSV (multipart поле file или тело text/csv) с заголовком name,surname[,patronymic,gender,age,nationality]. Строки проверяются и сохраняются пакетами, недостающие данные обогащаются через внешние API",
+                "consumes": [
+                    "multipart/form-data",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Импорт людей из CSV",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV файл",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid CSV",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "unsupported content type",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/merge": {
+            "post": {
+                "description": "Объединяет людей из source_ids с target_id и переносит их в корзину. Целевая запись сохраняет свои значения, пустые поля заполняются из источников; fields задаёт для поля ID человека, чьё значение побеждает. История всех записей сохраняется",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "duplicates"
+                ],
+                "summary": "Слияние дубликатов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Автор изменения",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Параметры слияния",
+                        "name": "merge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MergeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid JSON or merge request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "person not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "person changed concurrently",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to merge",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/search": {
+            "get": {
+                "description": "Ищет людей по похожести запроса на имя, фамилию, отчество или полное имя (pg_trgm) и сортирует по релевантности. В режиме match=phonetic ищет фамилии, которые звучат так же, как слова запроса (Shevchenko, Schewtschenko, Шевченко). Поддерживает фильтры и пагинацию списка",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Нечёткий поиск людей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "fuzzy",
+                            "phonetic"
+                        ],
+                        "type": "string",
+                        "default": "fuzzy",
+                        "description": "Режим поиска",
+                        "name": "match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.PersonSearchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid query, match mode or filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to search",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/stats": {
+            "get": {
+                "description": "Считает в базе распределение по полу, гистограмму возрастов, самые частые национальности и средний возраст по национальности и полу для людей, подходящих под фильтры списка. Люди без известного возраста не учитываются в гистограмме и средних",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Статистика по людям",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "18,25,35,45,55,65",
+                        "description": "Границы корзин гистограммы через запятую по возрастанию",
+                        "name": "age_buckets",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Число национальностей в рейтинге",
+                        "name": "top",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.PersonStats"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter or stats options",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get stats",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/trash": {
+            "get": {
+                "description": "Возвращает удалённых людей, которых ещё можно восстановить, с временем удаления. Поддерживает фильтры и пагинацию списка",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Корзина",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Фамилия",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Отчество",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Пол",
+                        "name": "gender",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Национальность",
+                        "name": "nationality",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальный возраст",
+                        "name": "age_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальный возраст",
+                        "name": "age_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Выражение фильтра, например: age \u003e= 30 and (nationality in ('RU', 'KZ') or gender = 'female')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.DeletedPerson"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get trash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/ws": {
+            "get": {
+                "description": "Открывает WebSocket, по которому клиент управляет подписками на изменения людей. Команды: {\"type\": \"subscribe\", \"id\": \"s1\", \"ids\": [1, 2]} или {\"type\": \"subscribe\", \"id\": \"s2\", \"filter\": \"age \u003e= 18\"}, {\"type\": \"unsubscribe\", \"id\": \"s1\"}, {\"type\": \"ping\"}. Сервер отвечает сообщениями subscribed, unsubscribed, pong и error, а события присылает как {\"type\": \"event\", \"subscriptions\": [...], \"event\": {...}}. Сервер шлёт ping-кадры и закрывает соединение, если клиент не отвечает или не успевает читать события; параметр last_event_id продолжает поток после переподключения",
+                "tags": [
+                    "events"
+                ],
+                "summary": "Подписка на изменения (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "переключение на WebSocket",
+                        "schema": {
+                            "$ref": "#/definitions/model.StreamMessage"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid event ID or not a WebSocket handshake",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "503": {
+                        "description": "event feed is not enabled or too many subscribers",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/{id}": {
+            "get": {
+                "description": "Возвращает данные конкретного человека",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Получение человека по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag известной версии",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Person"
+                        }
+                    },
+                    "304": {
+                        "description": "not modified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "person not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Обновляет существующего человека по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Обновление человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag ожидаемой версии",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Автор изменения",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Обновлённые данные",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdatePersonRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID or JSON",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "person not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "version mismatch",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Помещает человека в корзину по ID. С hard=true удаляет его безвозвратно (только для администратора)",
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Удаление человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Удалить безвозвратно",
+                        "name": "hard",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag ожидаемой версии",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Автор изменения",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора для hard=true",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "no content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "admin token required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "person not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "version mismatch",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to delete",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Обновляет существующего человека по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "persons"
+                ],
+                "summary": "Обновление человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag ожидаемой версии",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Автор изменения",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Обновлённые данные",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdatePersonRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID or JSON",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "person not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "version mismatch",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to update",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/{id}/history": {
+            "get": {
+                "description": "Возвращает все изменения человека: создание, обогащение, обновления, удаление и восстановление, с изменёнными полями, автором и источником",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "history"
+                ],
+                "summary": "История изменений человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.PersonHistory"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get history",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/{id}/restore": {
+            "post": {
+                "description": "Восстанавливает человека из корзины",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Восстановление человека",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "person not found in trash",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to restore",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/person/{id}/rollback": {
+            "post": {
+                "description": "Возвращает поля человека к состоянию указанной ревизии из истории. Откат создаёт новую ревизию",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "history"
+                ],
+                "summary": "Откат человека к ревизии",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID человека",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Автор изменения",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Ревизия",
+                        "name": "rollback",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RollbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Person"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID or JSON",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "person or revision not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "person changed concurrently",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to rollback",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/views": {
+            "get": {
+                "description": "Возвращает последнюю версию каждого сохранённого представления",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "views"
+                ],
+                "summary": "Список представлений",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.View"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get views",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Сохраняет под именем выражение фильтра, сортировку и набор колонок. Повторное сохранение с тем же именем создаёт новую версию, старые версии остаются доступны",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "views"
+                ],
+                "summary": "Сохранение представления",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Автор изменения",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Представление",
+                        "name": "view",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SaveViewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.View"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid JSON or view",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to save view",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/views/{name}": {
+            "get": {
+                "description": "Возвращает указанную версию представления или последнюю, если версия не задана",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "views"
+                ],
+                "summary": "Представление",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя представления",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Версия",
+                        "name": "version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.View"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "view not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get view",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/views/{name}/persons": {
+            "get": {
+                "description": "Выполняет фильтр и сортировку представления и возвращает страницу людей. Если в представлении заданы колонки, у каждого человека возвращаются только они. Использованная версия передаётся в заголовке X-View-Version",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "views"
+                ],
+                "summary": "Люди из представления",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя представления",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Версия, по умолчанию последняя",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Person"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid version or page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "view not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get view persons",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/views/{name}/versions": {
+            "get": {
+                "description": "Возвращает все версии представления, от старых к новым",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "views"
+                ],
+                "summary": "Версии представления",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя представления",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.View"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "view not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get view",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Список вебхуков",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Webhook"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "admin token required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get webhooks",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Регистрирует URL, на который POST-запросами отправляются события created, updated, deleted и enriched (без events — все). Каждая доставка подписана: заголовок X-Webhook-Signature содержит sha256=HMAC-SHA256 строки '\u003cX-Webhook-Timestamp\u003e.\u003cтело\u003e' с секретом вебхука. Секрет возвращается только в ответе на этот запрос. Неудачные доставки повторяются с нарастающей задержкой. Адреса в локальных и частных сетях не принимаются. Управление вебхуками доступно только администратору",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Регистрация вебхука",
+                "parameters": [
+                    {
+                        "description": "Вебхук",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateWebhookRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid JSON or webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "admin token required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to create webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}": {
+            "delete": {
+                "description": "Удаляет вебхук вместе с журналом доставок",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Удаление вебхука",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "no content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "admin token required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to delete webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Возвращает доставки вебхука, от новых к старым",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Журнал доставок",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статус: pending, delivered или failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID, status or page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "admin token required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to get deliveries",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries/{delivery}/replay": {
+            "post": {
+                "description": "Ставит событие из журнала в очередь на повторную отправку новой доставкой; исходная запись журнала не меняется",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Повтор доставки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID вебхука",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID доставки",
+                        "name": "delivery",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Токен администратора",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid ID",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "admin token required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "delivery not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "failed to replay delivery",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "gql.Request": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {}
+                }
+            }
+        },
+        "model.AgeBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 312
+                },
+                "max": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "min": {
+                    "type": "integer",
+                    "example": 18
+                }
+            }
+        },
+        "model.AverageAge": {
+            "type": "object",
+            "properties": {
+                "average": {
+                    "type": "number",
+                    "example": 41.5
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 210
+                },
+                "gender": {
+                    "type": "string",
+                    "example": "male"
+                },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                }
+            }
+        },
+        "model.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "person": {
+                    "$ref": "#/definitions/model.Person"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "created"
+                }
+            }
+        },
+        "model.BulkResult": {
+            "type": "object",
+            "properties": {
+                "affected": {
+                    "type": "integer"
+                },
+                "confirm_token": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "matched": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.CreatePersonRequest": {
+            "type": "object",
+            "required": [
                 "name",
                 "surname"
             ],
@@ -236,7 +2432,352 @@ const docTemplate = `{
                 }
             }
         },
-        "model.Person": {
+        "model.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "maxItems": 4,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 16
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/person"
+                }
+            }
+        },
+        "model.DeletedPerson": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.DuplicateCluster": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "persons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Person"
+                    }
+                }
+            }
+        },
+        "model.DuplicateConflict": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        12,
+                        31
+                    ]
+                },
+                "error": {
+                    "type": "string",
+                    "example": "person may already exist"
+                }
+            }
+        },
+        "model.FieldChange": {
+            "type": "object",
+            "properties": {
+                "new": {},
+                "old": {}
+            }
+        },
+        "model.GenderCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 640
+                },
+                "gender": {
+                    "type": "string",
+                    "example": "female"
+                }
+            }
+        },
+        "model.ImportReport": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ImportRowResult"
+                    }
+                }
+            }
+        },
+        "model.ImportRowResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "accepted"
+                }
+            }
+        },
+        "model.MergeRequest": {
+            "type": "object",
+            "required": [
+                "source_ids",
+                "target_id"
+            ],
+            "properties": {
+                "fields": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "source_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        31
+                    ]
+                },
+                "target_id": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "model.MergeResult": {
+            "type": "object",
+            "properties": {
+                "conflicts": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "merged_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "person": {
+                    "$ref": "#/definitions/model.Person"
+                }
+            }
+        },
+        "model.NationalityCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 420
+                },
+                "nationality": {
+                    "type": "string",
+                    "example": "RU"
+                }
+            }
+        },
+        "model.Person": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.PersonChanges": {
+            "type": "object",
+            "properties": {
+                "changed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Person"
+                    }
+                },
+                "deleted": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PersonTombstone"
+                    }
+                },
+                "has_more": {
+                    "type": "boolean"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "7731.1523"
+                }
+            }
+        },
+        "model.PersonEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.FieldChange"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "person": {
+                    "$ref": "#/definitions/model.PersonSnapshot"
+                },
+                "person_id": {
+                    "type": "integer"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "api"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "created"
+                }
+            }
+        },
+        "model.PersonHistory": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "update"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.FieldChange"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "person_id": {
+                    "type": "integer"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "snapshot": {
+                    "$ref": "#/definitions/model.PersonSnapshot"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "api"
+                }
+            }
+        },
+        "model.PersonSearchResult": {
             "type": "object",
             "properties": {
                 "age": {
@@ -260,11 +2801,154 @@ const docTemplate = `{
                 "patronymic": {
                     "type": "string"
                 },
+                "score": {
+                    "type": "number",
+                    "example": 0.72
+                },
                 "surname": {
                     "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.PersonSnapshot": {
+            "type": "object",
+            "properties": {
+                "age": {
+                    "type": "integer"
+                },
+                "gender": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nationality": {
+                    "type": "string"
+                },
+                "patronymic": {
+                    "type": "string"
+                },
+                "surname": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PersonStats": {
+            "type": "object",
+            "properties": {
+                "age_histogram": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AgeBucket"
+                    }
+                },
+                "average_age": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AverageAge"
+                    }
+                },
+                "genders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.GenderCount"
+                    }
+                },
+                "top_nationalities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.NationalityCount"
+                    }
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 1250
+                }
+            }
+        },
+        "model.PersonTombstone": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "model.RollbackRequest": {
+            "type": "object",
+            "required": [
+                "revision"
+            ],
+            "properties": {
+                "revision": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.SaveViewRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "id",
+                        "name",
+                        "surname",
+                        "age"
+                    ]
+                },
+                "filter": {
+                    "type": "string",
+                    "example": "age \u003e= 18 and nationality = 'RU'"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "example": "adults-ru"
+                },
+                "sort": {
+                    "type": "string",
+                    "example": "-age,surname"
+                }
+            }
+        },
+        "model.StreamMessage": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "$ref": "#/definitions/model.PersonEvent"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "event"
                 }
             }
         },
@@ -290,6 +2974,104 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "model.View": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "filter": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "sort": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "event_type": {
+                    "type": "string",
+                    "example": "created"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "replay_of": {
+                    "type": "integer"
+                },
+                "response_code": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -304,8 +3086,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "REST API для работы с информацией о людях (создание, чтение, обновление, удаление)",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
-	// LeftDelim:        "{{",
-	// RightDelim:       "}}",
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
 func init() {
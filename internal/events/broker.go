@@ -0,0 +1,137 @@
+// Package events fans person change events out to live subscribers such as
+// the SSE feed.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"effective-mobile/internal/model"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before it
+// is dropped. A dropped subscriber resumes from the backlog by ID.
+const subscriberBuffer = 256
+
+// Broker publishes events to subscribers and keeps the most recent ones so
+// that a subscriber can resume after a disconnect. It is safe for
+// concurrent use.
+type Broker struct {
+	mu      sync.Mutex
+	lastID  uint64
+	floor   uint64 // events up to this ID are not in the backlog
+	backlog []model.PersonEvent
+	ids     map[uint64]struct{} // IDs in the backlog
+	size    int
+	subs    map[*Subscription]struct{}
+}
+
+// NewBroker returns a broker that keeps the last backlog events. Event IDs
+// start from the current time in microseconds, so they keep increasing
+// across restarts and IDs from an earlier run are recognized as too old to
+// resume from.
+func NewBroker(backlog int) *Broker {
+	seed := uint64(time.Now().UnixMicro())
+	return &Broker{
+		lastID: seed,
+		floor:  seed,
+		size:   backlog,
+		ids:    map[uint64]struct{}{},
+		subs:   map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives published events on C until it is closed, either
+// by Close or by the broker when the subscriber falls too far behind.
+type Subscription struct {
+	C <-chan model.PersonEvent
+
+	c      chan model.PersonEvent
+	broker *Broker
+}
+
+// WithStartID continues the numbering of events whose IDs are assigned
+// elsewhere, such as by the outbox, from id.
+func (b *Broker) WithStartID(id uint64) *Broker {
+	b.lastID = id
+	b.floor = id
+	return b
+}
+
+// Publish delivers the events to all subscribers. Events without an ID are
+// assigned the next one; events with an ID that is still in the backlog
+// are taken as repeated deliveries and skipped. It never blocks on a slow
+// subscriber.
+func (b *Broker) Publish(events ...model.PersonEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ev := range events {
+		if ev.ID == 0 {
+			ev.ID = b.lastID + 1
+		}
+		if _, seen := b.ids[ev.ID]; seen {
+			continue
+		}
+		b.lastID = max(b.lastID, ev.ID)
+		if ev.Time.IsZero() {
+			ev.Time = time.Now()
+		}
+
+		b.backlog = append(b.backlog, ev)
+		b.ids[ev.ID] = struct{}{}
+		if n := len(b.backlog) - b.size; n > 0 {
+			for _, old := range b.backlog[:n] {
+				delete(b.ids, old.ID)
+				b.floor = max(b.floor, old.ID)
+			}
+			b.backlog = b.backlog[n:]
+		}
+
+		for sub := range b.subs {
+			select {
+			case sub.c <- ev:
+			default:
+				b.drop(sub)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscriber. With a non-zero lastID it also returns
+// the buffered events published after lastID; complete is false when some
+// of them are no longer buffered, and the subscriber should then reload
+// its state instead of relying on the replay.
+func (b *Broker) Subscribe(lastID uint64) (sub *Subscription, missed []model.PersonEvent, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := make(chan model.PersonEvent, subscriberBuffer)
+	sub = &Subscription{C: c, c: c, broker: b}
+	b.subs[sub] = struct{}{}
+
+	if lastID == 0 || lastID >= b.lastID {
+		return sub, nil, true
+	}
+	for _, ev := range b.backlog {
+		if ev.ID > lastID {
+			missed = append(missed, ev)
+		}
+	}
+	complete = lastID >= b.floor
+	return sub, missed, complete
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s)
+}
+
+func (b *Broker) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
@@ -0,0 +1,83 @@
+package events_test
+
+import (
+	"testing"
+
+	"effective-mobile/internal/events"
+	"effective-mobile/internal/model"
+)
+
+func TestBrokerResume(t *testing.T) {
+	b := events.NewBroker(2)
+
+	live, _, _ := b.Subscribe(0)
+	defer live.Close()
+
+	b.Publish(model.PersonEvent{PersonID: 1}, model.PersonEvent{PersonID: 2}, model.PersonEvent{PersonID: 3})
+	first := <-live.C
+	second := <-live.C
+	third := <-live.C
+	if second.ID != first.ID+1 || third.ID != second.ID+1 {
+		t.Fatalf("expected consecutive IDs, got %d %d %d", first.ID, second.ID, third.ID)
+	}
+
+	sub, missed, complete := b.Subscribe(second.ID)
+	sub.Close()
+	if !complete || len(missed) != 1 || missed[0].PersonID != 3 {
+		t.Fatalf("expected complete replay of the last event, got %v %v", complete, missed)
+	}
+
+	sub, missed, complete = b.Subscribe(first.ID)
+	sub.Close()
+	if !complete || len(missed) != 2 {
+		t.Fatalf("expected complete replay of two events, got %v %v", complete, missed)
+	}
+
+	sub, missed, complete = b.Subscribe(first.ID - 1)
+	sub.Close()
+	if complete || len(missed) != 2 {
+		t.Fatalf("expected incomplete replay once the backlog is exceeded, got %v %v", complete, missed)
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	b := events.NewBroker(10)
+	sub, _, _ := b.Subscribe(0)
+
+	for i := 0; i < 1000; i++ {
+		b.Publish(model.PersonEvent{PersonID: uint(i)})
+	}
+
+	n := 0
+	for range sub.C {
+		n++
+	}
+	if n == 0 || n >= 1000 {
+		t.Fatalf("expected the slow subscriber to be dropped after a buffer of events, got %d", n)
+	}
+	sub.Close()
+}
+
+func TestBrokerKeepsAssignedIDs(t *testing.T) {
+	b := events.NewBroker(10).WithStartID(41)
+	sub, _, _ := b.Subscribe(0)
+	defer sub.Close()
+
+	b.Publish(model.PersonEvent{ID: 42}, model.PersonEvent{ID: 42}, model.PersonEvent{ID: 45})
+
+	if ev := <-sub.C; ev.ID != 42 {
+		t.Fatalf("expected event 42, got %d", ev.ID)
+	}
+	if ev := <-sub.C; ev.ID != 45 {
+		t.Fatalf("expected repeated events to be skipped, got %d", ev.ID)
+	}
+	if _, missed, complete := b.Subscribe(41); !complete || len(missed) != 2 {
+		t.Fatalf("expected complete replay from the start ID, got %v %v", complete, missed)
+	}
+	if _, missed, complete := b.Subscribe(42); !complete || len(missed) != 1 {
+		t.Fatalf("expected gaps in the IDs not to break the replay, got %v %v", complete, missed)
+	}
+	if _, _, complete := b.Subscribe(30); complete {
+		t.Fatal("expected incomplete replay from before the start ID")
+	}
+}
@@ -0,0 +1,130 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// limits measures the operation of a validated document before it runs.
+//
+// Depth counts nested field selections: { people { nodes { name } } } is 3
+// deep. Complexity charges 1 for every field and statsCost for stats, whose
+// aggregates scan every matching person. The fields below a list of people
+// are charged once per person it can hold, that is first (or the default
+// page size) times. Introspection fields are free.
+// statsCost is what the stats field is charged, as much as a page of a
+// hundred people with one field.
+const statsCost = 100
+
+type limits struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+func checkLimits(doc *ast.Document, operationName string, variables map[string]any, maxDepth, maxComplexity int) error {
+	l := &limits{fragments: map[string]*ast.FragmentDefinition{}, variables: map[string]any{}}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			l.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operationName == "" || def.Name != nil && def.Name.Value == operationName {
+				op = def
+			}
+		}
+	}
+	if op == nil {
+		// Execute reports the missing operation.
+		return nil
+	}
+
+	for _, v := range op.VariableDefinitions {
+		if v.DefaultValue != nil {
+			l.variables[v.Variable.Name.Value] = l.value(v.DefaultValue)
+		}
+	}
+	for name, v := range variables {
+		l.variables[name] = v
+	}
+
+	depth, complexity := l.measure(op.SelectionSet)
+	if depth > maxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, maxDepth)
+	}
+	if complexity > maxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", complexity, maxComplexity)
+	}
+	return nil
+}
+
+func (l *limits) measure(set *ast.SelectionSet) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var d, c int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			d, c = l.measure(sel.SelectionSet)
+			d, c = d+1, fieldCost(sel)+c*l.multiplier(sel)
+		case *ast.InlineFragment:
+			d, c = l.measure(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if frag, ok := l.fragments[sel.Name.Value]; ok {
+				d, c = l.measure(frag.SelectionSet)
+			}
+		}
+		depth = max(depth, d)
+		complexity += c
+	}
+	return depth, complexity
+}
+
+func fieldCost(field *ast.Field) int {
+	if field.Name.Value == "stats" {
+		return statsCost
+	}
+	return 1
+}
+
+// multiplier is how many times the selections of a field are resolved.
+func (l *limits) multiplier(field *ast.Field) int {
+	if field.Name.Value != "people" {
+		return 1
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value == "first" {
+			if n, ok := l.value(arg.Value).(int); ok && n >= 0 {
+				return max(n, 1)
+			}
+		}
+	}
+	return defaultPageSize
+}
+
+func (l *limits) value(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		if err != nil {
+			return nil
+		}
+		return n
+	case *ast.Variable:
+		switch n := l.variables[v.Name.Value].(type) {
+		case int:
+			return n
+		case float64:
+			// Variables decoded from JSON.
+			return int(n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package gql
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/filterexpr"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/validator"
+
+	"github.com/graphql-go/graphql"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageSize     = 20
+	maxPageSize         = 100
+	maxFilterExprLength = 1000
+	maxAgeBuckets       = 50
+	maxTopNationalities = 100
+)
+
+// Error codes reported in the extensions of field errors.
+const (
+	CodeBadInput        = "BAD_USER_INPUT"
+	CodeNotFound        = "NOT_FOUND"
+	CodeVersionConflict = "VERSION_CONFLICT"
+	CodeDuplicate       = "DUPLICATE"
+	CodeInternal        = "INTERNAL"
+)
+
+// Error is a field error with a code in its extensions.
+type Error struct {
+	Code    string
+	Message string
+	Details map[string]any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]any {
+	ext := map[string]any{"code": e.Code}
+	for k, v := range e.Details {
+		ext[k] = v
+	}
+	return ext
+}
+
+func badInput(format string, args ...any) error {
+	return &Error{Code: CodeBadInput, Message: fmt.Sprintf(format, args...)}
+}
+
+// fieldError maps service errors to field errors. Unexpected errors are
+// logged and reported with the failed prefix.
+func fieldError(err error, failed string) error {
+	var duplicate *service.DuplicateError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return &Error{Code: CodeNotFound, Message: "person not found"}
+	case errors.Is(err, repository.ErrVersionConflict):
+		return &Error{Code: CodeVersionConflict, Message: "version mismatch"}
+	case errors.As(err, &duplicate):
+		return &Error{Code: CodeDuplicate, Message: err.Error(), Details: map[string]any{"candidates": duplicate.Candidates}}
+	}
+	logger.Log.Error(failed, zap.Error(err))
+	return &Error{Code: CodeInternal, Message: failed + ": " + err.Error()}
+}
+
+type resolver struct {
+	service service.PersonServiceInterface
+}
+
+// connection is the source of a PersonConnection.
+type connection struct {
+	Edges    []map[string]any
+	Nodes    []*model.Person
+	PageInfo map[string]any
+	filter   model.PersonFilter
+}
+
+func (r *resolver) person(p graphql.ResolveParams) (any, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	person, err := r.service.GetPersonByID(p.Context, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fieldError(err, "failed to get person")
+	}
+	return person, nil
+}
+
+func (r *resolver) people(p graphql.ResolveParams) (any, error) {
+	filter, err := personFilter(p.Args["filter"])
+	if err != nil {
+		return nil, err
+	}
+	if filter.Sort, err = personSort(p.Args["sort"]); err != nil {
+		return nil, err
+	}
+
+	first, _ := p.Args["first"].(int)
+	if first < 0 || first > maxPageSize {
+		return nil, badInput("first must be between 0 and %d", maxPageSize)
+	}
+	offset := 0
+	if after, ok := p.Args["after"].(string); ok {
+		if offset, err = decodeCursor(after); err != nil {
+			return nil, err
+		}
+	}
+
+	// One more than the page tells whether another page follows.
+	filter.Limit, filter.Offset = first+1, offset
+	people, err := r.service.GetAllPersons(p.Context, filter)
+	if err != nil {
+		return nil, fieldError(err, "failed to get persons")
+	}
+	hasNext := len(people) > first
+	if hasNext {
+		people = people[:first]
+	}
+
+	conn := &connection{
+		Edges:    make([]map[string]any, len(people)),
+		Nodes:    make([]*model.Person, len(people)),
+		PageInfo: map[string]any{"hasNextPage": hasNext, "endCursor": nil},
+		filter:   filter,
+	}
+	for i := range people {
+		cursor := encodeCursor(offset + i + 1)
+		conn.Edges[i] = map[string]any{"cursor": cursor, "node": &people[i]}
+		conn.Nodes[i] = &people[i]
+		conn.PageInfo["endCursor"] = cursor
+	}
+	return conn, nil
+}
+
+// totalCount counts all people matching the filter of a connection, only
+// when the query asks for it.
+func (r *resolver) totalCount(p graphql.ResolveParams) (any, error) {
+	n, err := r.service.CountPersons(p.Context, p.Source.(*connection).filter)
+	if err != nil {
+		return nil, fieldError(err, "failed to count persons")
+	}
+	return n, nil
+}
+
+func (r *resolver) stats(p graphql.ResolveParams) (any, error) {
+	filter, err := personFilter(p.Args["filter"])
+	if err != nil {
+		return nil, err
+	}
+
+	var opts model.StatsOptions
+	if buckets, ok := p.Args["ageBuckets"].([]any); ok {
+		if len(buckets) > maxAgeBuckets {
+			return nil, badInput("ageBuckets must not have more than %d bounds", maxAgeBuckets)
+		}
+		for _, b := range buckets {
+			n := b.(int)
+			if n <= 0 || len(opts.AgeBuckets) > 0 && n <= opts.AgeBuckets[len(opts.AgeBuckets)-1] {
+				return nil, badInput("ageBuckets must be positive and in ascending order")
+			}
+			opts.AgeBuckets = append(opts.AgeBuckets, n)
+		}
+	}
+	if top, ok := p.Args["top"].(int); ok {
+		if top <= 0 || top > maxTopNationalities {
+			return nil, badInput("top must be between 1 and %d", maxTopNationalities)
+		}
+		opts.TopNationalities = top
+	}
+
+	stats, err := r.service.GetPersonStats(p.Context, filter, opts)
+	if err != nil {
+		return nil, fieldError(err, "failed to compute stats")
+	}
+	return stats, nil
+}
+
+func (r *resolver) createPerson(p graphql.ResolveParams) (any, error) {
+	input := p.Args["input"].(map[string]any)
+	req := model.CreatePersonRequest{
+		Name:       stringArg(input, "name"),
+		Surname:    stringArg(input, "surname"),
+		Patronymic: stringArg(input, "patronymic"),
+	}
+	req.Force, _ = input["force"].(bool)
+	if err := validator.Validate.Struct(req); err != nil {
+		return nil, badInput("validation failed: %v", err)
+	}
+
+	person, err := r.service.CreatePerson(p.Context, req)
+	if err != nil {
+		return nil, fieldError(err, "failed to create person")
+	}
+	logger.Log.Info("person created over GraphQL", zap.Uint("id", person.ID))
+	return person, nil
+}
+
+func (r *resolver) updatePerson(p graphql.ResolveParams) (any, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	version, err := versionArg(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]any)
+	req := model.UpdatePersonRequest{
+		Name:        stringArg(input, "name"),
+		Surname:     stringArg(input, "surname"),
+		Patronymic:  stringArg(input, "patronymic"),
+		Gender:      stringArg(input, "gender"),
+		Nationality: stringArg(input, "nationality"),
+		Version:     version,
+	}
+	if age, ok := input["age"].(int); ok {
+		if age < 0 {
+			return nil, badInput("invalid age: %d", age)
+		}
+		req.Age = age
+	}
+
+	person, err := r.service.UpdatePerson(p.Context, id, req)
+	if err != nil {
+		return nil, fieldError(err, "failed to update person")
+	}
+	return person, nil
+}
+
+func (r *resolver) deletePerson(p graphql.ResolveParams) (any, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	version, err := versionArg(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.service.DeletePerson(p.Context, id, version); err != nil {
+		return nil, fieldError(err, "failed to delete person")
+	}
+	logger.Log.Info("person deleted over GraphQL", zap.Uint("id", id))
+	return id, nil
+}
+
+// personFilter checks a PersonFilter input the way GET /person checks its
+// query.
+func personFilter(arg any) (model.PersonFilter, error) {
+	var f model.PersonFilter
+	input, ok := arg.(map[string]any)
+	if !ok {
+		return f, nil
+	}
+
+	f.Name = stringArg(input, "name")
+	f.Surname = stringArg(input, "surname")
+	f.Patronymic = stringArg(input, "patronymic")
+	f.Gender = stringArg(input, "gender")
+	f.Nationality = stringArg(input, "nationality")
+	f.AgeMin, _ = input["ageMin"].(int)
+	f.AgeMax, _ = input["ageMax"].(int)
+	if f.AgeMin < 0 || f.AgeMax < 0 {
+		return f, badInput("ageMin and ageMax must not be negative")
+	}
+	if f.AgeMin > 0 && f.AgeMax > 0 && f.AgeMin > f.AgeMax {
+		return f, badInput("ageMin must not exceed ageMax")
+	}
+
+	if src := stringArg(input, "expr"); src != "" {
+		if len(src) > maxFilterExprLength {
+			return f, badInput("expr must not exceed %d bytes", maxFilterExprLength)
+		}
+		expr, err := filterexpr.Parse(src, model.PersonFilterFields)
+		if err != nil {
+			return f, badInput("invalid filter: %v", err)
+		}
+		f.Expr = expr
+	}
+	return f, nil
+}
+
+func personSort(arg any) ([]model.SortField, error) {
+	items, _ := arg.([]any)
+	if len(items) > len(model.PersonColumns) {
+		return nil, badInput("sort must not have more than %d fields", len(model.PersonColumns))
+	}
+	var sort []model.SortField
+	for _, item := range items {
+		input := item.(map[string]any)
+		desc, _ := input["desc"].(bool)
+		sort = append(sort, model.SortField{Field: input["field"].(string), Desc: desc})
+	}
+	return sort, nil
+}
+
+func parseID(arg any) (uint, error) {
+	s, _ := arg.(string)
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || id == 0 {
+		return 0, badInput("invalid ID: %q", s)
+	}
+	return uint(id), nil
+}
+
+func versionArg(args map[string]any) (uint, error) {
+	version, ok := args["version"].(int)
+	if !ok {
+		return 0, nil
+	}
+	if version <= 0 {
+		return 0, badInput("invalid version: %d", version)
+	}
+	return uint(version), nil
+}
+
+func stringArg(input map[string]any, name string) string {
+	s, _ := input[name].(string)
+	return s
+}
+
+// Cursors are opaque to clients; they hold the offset after an edge.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if v, ok := strings.CutPrefix(string(data), "offset:"); ok {
+			if offset, err := strconv.Atoi(v); err == nil && offset >= 0 {
+				return offset, nil
+			}
+		}
+	}
+	return 0, badInput("invalid cursor: %q", cursor)
+}
@@ -0,0 +1,328 @@
+// Package gql is the GraphQL schema of the person API. Resolvers delegate to
+// the person service; queries are checked against depth and complexity
+// limits before they run.
+package gql
+
+import (
+	"context"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/service"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const (
+	defaultMaxDepth      = 10
+	defaultMaxComplexity = 1000
+)
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// RequestError reports a request that was not executed because it does not
+// parse, is invalid against the schema or exceeds the limits.
+type RequestError struct {
+	Errors []gqlerrors.FormattedError
+}
+
+func (e *RequestError) Error() string {
+	return e.Errors[0].Message
+}
+
+type Schema struct {
+	schema        graphql.Schema
+	maxDepth      int
+	maxComplexity int
+}
+
+func NewSchema(svc service.PersonServiceInterface) (*Schema, error) {
+	r := &resolver{service: svc}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType(r),
+		Mutation: mutationType(r),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{schema: schema, maxDepth: defaultMaxDepth, maxComplexity: defaultMaxComplexity}, nil
+}
+
+// WithLimits sets the maximum selection depth and complexity of a query.
+// See complexity for how the latter is counted.
+func (s *Schema) WithLimits(depth, complexity int) *Schema {
+	s.maxDepth = depth
+	s.maxComplexity = complexity
+	return s
+}
+
+// Execute runs the request. Errors of single fields are reported in the
+// result; a request that can't run at all fails with a RequestError.
+func (s *Schema) Execute(ctx context.Context, req Request) (*graphql.Result, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return nil, &RequestError{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if res := graphql.ValidateDocument(&s.schema, doc, nil); !res.IsValid {
+		return nil, &RequestError{Errors: res.Errors}
+	}
+	if err := checkLimits(doc, req.OperationName, req.Variables, s.maxDepth, s.maxComplexity); err != nil {
+		return nil, &RequestError{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	}), nil
+}
+
+var personType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Person",
+	Fields: graphql.Fields{
+		"id":          personField(graphql.NewNonNull(graphql.ID), func(p *model.Person) any { return p.ID }),
+		"name":        personField(graphql.NewNonNull(graphql.String), func(p *model.Person) any { return p.Name }),
+		"surname":     personField(graphql.NewNonNull(graphql.String), func(p *model.Person) any { return p.Surname }),
+		"patronymic":  personField(graphql.String, func(p *model.Person) any { return optional(p.Patronymic) }),
+		"gender":      personField(graphql.String, func(p *model.Person) any { return optional(p.Gender) }),
+		"age":         personField(graphql.Int, func(p *model.Person) any { return optional(p.Age) }),
+		"nationality": personField(graphql.String, func(p *model.Person) any { return optional(p.Nationality) }),
+		"version":     personField(graphql.NewNonNull(graphql.Int), func(p *model.Person) any { return p.Version }),
+		"createdAt":   personField(graphql.NewNonNull(graphql.DateTime), func(p *model.Person) any { return p.CreatedAt }),
+		"updatedAt":   personField(graphql.NewNonNull(graphql.DateTime), func(p *model.Person) any { return p.UpdatedAt }),
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+var personEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PersonEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"node":   &graphql.Field{Type: graphql.NewNonNull(personType)},
+	},
+})
+
+// personFieldEnum names the columns people can be sorted by.
+var personFieldEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "PersonField",
+	Values: graphql.EnumValueConfigMap{
+		"ID":          {Value: "id"},
+		"NAME":        {Value: "name"},
+		"SURNAME":     {Value: "surname"},
+		"PATRONYMIC":  {Value: "patronymic"},
+		"GENDER":      {Value: "gender"},
+		"AGE":         {Value: "age"},
+		"NATIONALITY": {Value: "nationality"},
+		"VERSION":     {Value: "version"},
+		"CREATED_AT":  {Value: "created_at"},
+		"UPDATED_AT":  {Value: "updated_at"},
+	},
+})
+
+var personSortInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "PersonSort",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field": {Type: graphql.NewNonNull(personFieldEnum)},
+		"desc":  {Type: graphql.Boolean, DefaultValue: false},
+	},
+})
+
+var personFilterInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "PersonFilter",
+	Description: "Filters of GET /person. expr is a filter expression, e.g. age >= 30 and nationality in ('RU', 'KZ').",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":        {Type: graphql.String},
+		"surname":     {Type: graphql.String},
+		"patronymic":  {Type: graphql.String},
+		"gender":      {Type: graphql.String},
+		"nationality": {Type: graphql.String},
+		"ageMin":      {Type: graphql.Int},
+		"ageMax":      {Type: graphql.Int},
+		"expr":        {Type: graphql.String},
+	},
+})
+
+var createPersonInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreatePersonInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":       {Type: graphql.NewNonNull(graphql.String)},
+		"surname":    {Type: graphql.NewNonNull(graphql.String)},
+		"patronymic": {Type: graphql.String},
+		"force":      {Type: graphql.Boolean, DefaultValue: false, Description: "Skip the duplicate check."},
+	},
+})
+
+var updatePersonInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "UpdatePersonInput",
+	Description: "Fields to change; absent fields are left as they are.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":        {Type: graphql.String},
+		"surname":     {Type: graphql.String},
+		"patronymic":  {Type: graphql.String},
+		"gender":      {Type: graphql.String},
+		"age":         {Type: graphql.Int},
+		"nationality": {Type: graphql.String},
+	},
+})
+
+func personConnectionType(r *resolver) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "PersonConnection",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(personEdgeType)))},
+			"nodes":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(personType)))},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: r.totalCount},
+		},
+	})
+}
+
+func statsType() *graphql.Object {
+	count := func(name, key string, typ graphql.Output) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: name,
+			Fields: graphql.Fields{
+				key:     &graphql.Field{Type: typ},
+				"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			},
+		})
+	}
+	list := func(t graphql.Type) graphql.Output {
+		return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(t)))
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "PersonStats",
+		Fields: graphql.Fields{
+			"total":            &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"genders":          &graphql.Field{Type: list(count("GenderCount", "gender", graphql.NewNonNull(graphql.String)))},
+			"topNationalities": &graphql.Field{Type: list(count("NationalityCount", "nationality", graphql.NewNonNull(graphql.String)))},
+			"ageHistogram": &graphql.Field{Type: list(graphql.NewObject(graphql.ObjectConfig{
+				Name: "AgeBucket",
+				Fields: graphql.Fields{
+					"min":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+					"max":   &graphql.Field{Type: graphql.Int},
+					"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				},
+			}))},
+			"averageAge": &graphql.Field{Type: list(graphql.NewObject(graphql.ObjectConfig{
+				Name: "AverageAge",
+				Fields: graphql.Fields{
+					"nationality": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+					"gender":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+					"average":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+					"count":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				},
+			}))},
+		},
+	})
+}
+
+func queryType(r *resolver) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"person": &graphql.Field{
+				Type:        personType,
+				Description: "The person with the ID, or null if there is none.",
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: r.person,
+			},
+			"people": &graphql.Field{
+				Type:        graphql.NewNonNull(personConnectionType(r)),
+				Description: "A page of the people matching filter. Pass the endCursor of a page as after to get the next one.",
+				Args: graphql.FieldConfigArgument{
+					"filter": {Type: personFilterInput},
+					"sort":   {Type: graphql.NewList(graphql.NewNonNull(personSortInput))},
+					"first":  {Type: graphql.Int, DefaultValue: defaultPageSize},
+					"after":  {Type: graphql.String},
+				},
+				Resolve: r.people,
+			},
+			"stats": &graphql.Field{
+				Type:        graphql.NewNonNull(statsType()),
+				Description: "Aggregates over the people matching filter, as GET /person/stats computes them.",
+				Args: graphql.FieldConfigArgument{
+					"filter":     {Type: personFilterInput},
+					"ageBuckets": {Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+					"top":        {Type: graphql.Int},
+				},
+				Resolve: r.stats,
+			},
+		},
+	})
+}
+
+func mutationType(r *resolver) *graphql.Object {
+	version := &graphql.ArgumentConfig{Type: graphql.Int, Description: "Expected current version; the change fails if the person has another one."}
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createPerson": &graphql.Field{
+				Type: graphql.NewNonNull(personType),
+				Args: graphql.FieldConfigArgument{
+					"input": {Type: graphql.NewNonNull(createPersonInput)},
+				},
+				Resolve: r.createPerson,
+			},
+			"updatePerson": &graphql.Field{
+				Type: graphql.NewNonNull(personType),
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.ID)},
+					"input":   {Type: graphql.NewNonNull(updatePersonInput)},
+					"version": version,
+				},
+				Resolve: r.updatePerson,
+			},
+			"deletePerson": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.ID),
+				Description: "Moves the person to the trash and returns its ID.",
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.ID)},
+					"version": version,
+				},
+				Resolve: r.deletePerson,
+			},
+		},
+	})
+}
+
+func personField(typ graphql.Output, get func(p *model.Person) any) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return get(p.Source.(*model.Person)), nil
+		},
+	}
+}
+
+// optional returns nil for the zero value, which GraphQL reports as null
+// the way the REST API omits it.
+func optional[T comparable](v T) any {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
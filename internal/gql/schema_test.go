@@ -0,0 +1,215 @@
+package gql_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"effective-mobile/internal/gql"
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/logger"
+)
+
+func init() {
+	logger.Init()
+}
+
+// fakeService holds five people; any call the tests don't make panics.
+type fakeService struct {
+	service.PersonServiceInterface
+	filter model.PersonFilter
+	counts int
+}
+
+func (f *fakeService) GetAllPersons(ctx context.Context, filter model.PersonFilter) ([]model.Person, error) {
+	f.filter = filter
+	var people []model.Person
+	for i := filter.Offset + 1; i <= 5 && len(people) < filter.Limit; i++ {
+		people = append(people, model.Person{ID: uint(i), Name: "Alice", Surname: "Smith", Version: 1})
+	}
+	return people, nil
+}
+
+func (f *fakeService) CountPersons(ctx context.Context, filter model.PersonFilter) (int64, error) {
+	f.counts++
+	return 5, nil
+}
+
+func (f *fakeService) GetPersonByID(ctx context.Context, id uint) (*model.Person, error) {
+	if id != 1 {
+		return nil, repository.ErrNotFound
+	}
+	return &model.Person{ID: 1, Name: "Alice", Surname: "Smith", Age: 30, Version: 2}, nil
+}
+
+func (f *fakeService) UpdatePerson(ctx context.Context, id uint, req model.UpdatePersonRequest) (*model.Person, error) {
+	return nil, repository.ErrVersionConflict
+}
+
+func (f *fakeService) CreatePerson(ctx context.Context, req model.CreatePersonRequest) (*model.Person, error) {
+	if req.Name == "Duplicate" {
+		return nil, &service.DuplicateError{Candidates: []uint{4}}
+	}
+	return &model.Person{ID: 6, Name: req.Name, Surname: req.Surname, Version: 1}, nil
+}
+
+func (f *fakeService) GetPersonStats(ctx context.Context, filter model.PersonFilter, opts model.StatsOptions) (*model.PersonStats, error) {
+	max := opts.AgeBuckets[0]
+	return &model.PersonStats{
+		Total:        5,
+		AgeHistogram: []model.AgeBucket{{Min: 0, Max: &max, Count: 2}, {Min: max, Count: 3}},
+	}, nil
+}
+
+func execute(t *testing.T, s *gql.Schema, query string, vars map[string]any) map[string]any {
+	t.Helper()
+	res, err := s.Execute(context.Background(), gql.Request{Query: query, Variables: vars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(res)
+	var out map[string]any
+	json.Unmarshal(data, &out)
+	return out
+}
+
+func newSchema(t *testing.T, svc service.PersonServiceInterface) *gql.Schema {
+	t.Helper()
+	s, err := gql.NewSchema(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPerson(t *testing.T) {
+	s := newSchema(t, &fakeService{})
+
+	out := execute(t, s, `{ person(id: 1) { id name age gender } missing: person(id: 2) { id } }`, nil)
+	data, _ := json.Marshal(out["data"])
+	want := `{"missing":null,"person":{"age":30,"gender":null,"id":"1","name":"Alice"}}`
+	if string(data) != want {
+		t.Fatalf("expected %s, got %s (errors: %v)", want, data, out["errors"])
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := newSchema(t, &fakeService{})
+
+	out := execute(t, s, `{ stats(ageBuckets: [18]) { total ageHistogram { min max count } } }`, nil)
+	data, _ := json.Marshal(out["data"])
+	want := `{"stats":{"ageHistogram":[{"count":2,"max":18,"min":0},{"count":3,"max":null,"min":18}],"total":5}}`
+	if string(data) != want {
+		t.Fatalf("expected %s, got %s (errors: %v)", want, data, out["errors"])
+	}
+
+	out = execute(t, s, `{ stats(ageBuckets: [30, 18]) { total } }`, nil)
+	if out["errors"] == nil {
+		t.Fatal("expected descending age buckets to be rejected")
+	}
+}
+
+func TestPeople_PagesWithCursors(t *testing.T) {
+	svc := &fakeService{}
+	s := newSchema(t, svc)
+	query := `query($after: String) {
+		people(first: 2, after: $after, filter: {nationality: "RU", expr: "age >= 18"}, sort: [{field: AGE, desc: true}]) {
+			edges { cursor node { id } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	var ids []string
+	var after any
+	for pages := 0; pages < 5; pages++ {
+		out := execute(t, s, query, map[string]any{"after": after})
+		people := out["data"].(map[string]any)["people"].(map[string]any)
+		for _, e := range people["edges"].([]any) {
+			ids = append(ids, e.(map[string]any)["node"].(map[string]any)["id"].(string))
+		}
+		info := people["pageInfo"].(map[string]any)
+		if info["hasNextPage"] != true {
+			break
+		}
+		after = info["endCursor"]
+	}
+
+	if strings.Join(ids, ",") != "1,2,3,4,5" {
+		t.Fatalf("expected all five people over three pages, got %v", ids)
+	}
+	f := svc.filter
+	if f.Nationality != "RU" || f.Expr == nil || len(f.Sort) != 1 || f.Sort[0].Field != "age" || !f.Sort[0].Desc {
+		t.Fatalf("unexpected filter %+v", f)
+	}
+	if svc.counts != 0 {
+		t.Fatal("expected no count when totalCount is not selected")
+	}
+
+	out := execute(t, s, `{ people { totalCount } }`, nil)
+	if out["data"].(map[string]any)["people"].(map[string]any)["totalCount"] != float64(5) {
+		t.Fatalf("unexpected total count: %v", out)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s := newSchema(t, &fakeService{}).WithLimits(3, 50)
+
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{`{ people { edges { node { name } } } }`, "depth 4 exceeds"},
+		{`{ people(first: 100) { nodes { name } } }`, "complexity 201 exceeds"},
+		{`query($n: Int) { people(first: $n) { nodes { ...names } } } fragment names on Person { name surname }`, "complexity 121 exceeds"},
+		{`{ stats { total } }`, "complexity 101 exceeds"},
+	}
+	for _, c := range cases {
+		vars := map[string]any{"n": float64(40)}
+		_, err := s.Execute(context.Background(), gql.Request{Query: c.query, Variables: vars})
+		var reqErr *gql.RequestError
+		if !errors.As(err, &reqErr) || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("expected %q for %s, got %v", c.err, c.query, err)
+		}
+	}
+
+	if _, err := s.Execute(context.Background(), gql.Request{Query: `{ people(first: 10) { nodes { name } } }`}); err != nil {
+		t.Fatalf("expected a query within the limits to run, got %v", err)
+	}
+	if _, err := s.Execute(context.Background(), gql.Request{Query: `{ people { nope } }`}); err == nil {
+		t.Fatal("expected an invalid query to be rejected")
+	}
+}
+
+func TestMutationErrors(t *testing.T) {
+	s := newSchema(t, &fakeService{})
+
+	cases := []struct {
+		query string
+		code  string
+	}{
+		{`mutation { updatePerson(id: 1, version: 1, input: {name: "Bob"}) { id } }`, gql.CodeVersionConflict},
+		{`mutation { createPerson(input: {name: "Duplicate", surname: "Smith"}) { id } }`, gql.CodeDuplicate},
+		{`mutation { createPerson(input: {name: "", surname: "Smith"}) { id } }`, gql.CodeBadInput},
+		{`mutation { deletePerson(id: "x") }`, gql.CodeBadInput},
+	}
+	for _, c := range cases {
+		out := execute(t, s, c.query, nil)
+		errs, _ := out["errors"].([]any)
+		if len(errs) != 1 {
+			t.Fatalf("expected one error for %s, got %v", c.query, out)
+		}
+		ext, _ := errs[0].(map[string]any)["extensions"].(map[string]any)
+		if ext["code"] != c.code {
+			t.Fatalf("expected code %s for %s, got %v", c.code, c.query, errs[0])
+		}
+	}
+
+	out := execute(t, s, `mutation { createPerson(input: {name: "Alice", surname: "Smith"}) { id version } }`, nil)
+	if out["data"].(map[string]any)["createPerson"].(map[string]any)["id"] != "6" {
+		t.Fatalf("unexpected result %v", out)
+	}
+}
@@ -0,0 +1,250 @@
+// Package grpcserver serves person.v1.PersonService, the gRPC counterpart of
+// the REST API, on top of the person service.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	personv1 "effective-mobile/api/person/v1"
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/filterexpr"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/validator"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// ActorMetadata is the metadata key that names the author of a change,
+	// like the X-Actor header of the REST API.
+	ActorMetadata = "x-actor"
+
+	maxActorLength      = 255
+	maxFilterExprLength = 1000
+	maxListLimit        = 1000 // the largest page GET /person returns
+)
+
+type PersonServer struct {
+	personv1.UnimplementedPersonServiceServer
+	service service.PersonServiceInterface
+}
+
+func NewPersonServer(s service.PersonServiceInterface) *PersonServer {
+	return &PersonServer{service: s}
+}
+
+// NewServer returns a gRPC server with PersonService registered.
+func NewServer(s service.PersonServiceInterface, opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(opts...)
+	personv1.RegisterPersonServiceServer(srv, NewPersonServer(s))
+	return srv
+}
+
+// RegisterReflection lets clients such as grpcurl discover the services of
+// srv without the proto files. It describes the whole API to anyone who can
+// connect, so it is meant for development.
+func RegisterReflection(srv *grpc.Server) {
+	reflection.Register(srv)
+}
+
+func (s *PersonServer) Create(ctx context.Context, req *personv1.CreateRequest) (*personv1.Person, error) {
+	create := model.CreatePersonRequest{
+		Name:       req.GetName(),
+		Surname:    req.GetSurname(),
+		Patronymic: req.GetPatronymic(),
+		Force:      req.GetForce(),
+	}
+	if err := validator.Validate.Struct(create); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "validation failed: "+err.Error())
+	}
+
+	p, err := s.service.CreatePerson(callContext(ctx), create)
+	if err != nil {
+		return nil, statusError(err, "failed to create person")
+	}
+	logger.Log.Info("person created over gRPC", zap.Uint("id", p.ID))
+	return toProto(p), nil
+}
+
+func (s *PersonServer) Get(ctx context.Context, req *personv1.GetRequest) (*personv1.Person, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	p, err := s.service.GetPersonByID(callContext(ctx), uint(req.GetId()))
+	if err != nil {
+		return nil, statusError(err, "failed to get person")
+	}
+	return toProto(p), nil
+}
+
+// List streams the matching people straight from a database cursor, so
+// that large results are not held in memory.
+func (s *PersonServer) List(req *personv1.ListRequest, stream grpc.ServerStreamingServer[personv1.Person]) error {
+	filter, err := listFilter(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	count := 0
+	err = s.service.ExportPersons(callContext(stream.Context()), filter, func(p *model.Person) error {
+		count++
+		return stream.Send(toProto(p))
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return statusError(err, "failed to list persons")
+	}
+	logger.Log.Debug("persons listed over gRPC", zap.Int("count", count))
+	return nil
+}
+
+func (s *PersonServer) Update(ctx context.Context, req *personv1.UpdateRequest) (*personv1.Person, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	if req.GetAge() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid age")
+	}
+
+	p, err := s.service.UpdatePerson(callContext(ctx), uint(req.GetId()), model.UpdatePersonRequest{
+		Name:        req.GetName(),
+		Surname:     req.GetSurname(),
+		Patronymic:  req.GetPatronymic(),
+		Gender:      req.GetGender(),
+		Age:         int(req.GetAge()),
+		Nationality: req.GetNationality(),
+		Version:     uint(req.GetVersion()),
+	})
+	if err != nil {
+		return nil, statusError(err, "failed to update person")
+	}
+	return toProto(p), nil
+}
+
+func (s *PersonServer) Delete(ctx context.Context, req *personv1.DeleteRequest) (*personv1.DeleteResponse, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	if err := s.service.DeletePerson(callContext(ctx), uint(req.GetId()), uint(req.GetVersion())); err != nil {
+		return nil, statusError(err, "failed to delete person")
+	}
+	logger.Log.Info("person deleted over gRPC", zap.Uint32("id", req.GetId()))
+	return &personv1.DeleteResponse{}, nil
+}
+
+func (s *PersonServer) Enrich(ctx context.Context, req *personv1.EnrichRequest) (*personv1.Person, error) {
+	if req.GetId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid ID")
+	}
+	p, err := s.service.ReenrichPerson(callContext(ctx), uint(req.GetId()))
+	if err != nil {
+		return nil, statusError(err, "failed to enrich person")
+	}
+	return toProto(p), nil
+}
+
+// callContext attributes the changes of a call to the actor from its
+// metadata.
+func callContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var actor string
+	if values := md.Get(ActorMetadata); len(values) > 0 {
+		actor = strings.TrimSpace(values[0])
+	}
+	if len(actor) > maxActorLength {
+		actor = actor[:maxActorLength]
+	}
+	return service.WithActor(ctx, actor)
+}
+
+// statusError maps service errors to gRPC status codes. Unexpected errors
+// are logged and reported as Internal with the failed prefix.
+func statusError(err error, failed string) error {
+	var duplicate *service.DuplicateError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, "person not found")
+	case errors.Is(err, repository.ErrVersionConflict):
+		return status.Error(codes.FailedPrecondition, "version mismatch")
+	case errors.As(err, &duplicate):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	logger.Log.Error(failed, zap.Error(err))
+	return status.Error(codes.Internal, failed+": "+err.Error())
+}
+
+// listFilter checks a ListRequest the way GET /person checks its query.
+func listFilter(req *personv1.ListRequest) (model.PersonFilter, error) {
+	f := model.PersonFilter{
+		Name:        req.GetName(),
+		Surname:     req.GetSurname(),
+		Patronymic:  req.GetPatronymic(),
+		Gender:      req.GetGender(),
+		Nationality: req.GetNationality(),
+		AgeMin:      int(req.GetAgeMin()),
+		AgeMax:      int(req.GetAgeMax()),
+		Limit:       int(req.GetLimit()),
+		Offset:      int(req.GetOffset()),
+	}
+
+	ints := []struct {
+		name  string
+		value int
+	}{
+		{"age_min", f.AgeMin},
+		{"age_max", f.AgeMax},
+		{"limit", f.Limit},
+		{"offset", f.Offset},
+	}
+	for _, v := range ints {
+		if v.value < 0 {
+			return f, fmt.Errorf("invalid %s: %d", v.name, v.value)
+		}
+	}
+	if f.AgeMin > 0 && f.AgeMax > 0 && f.AgeMin > f.AgeMax {
+		return f, fmt.Errorf("age_min must not exceed age_max")
+	}
+	f.Limit = min(f.Limit, maxListLimit)
+
+	if src := req.GetFilter(); src != "" {
+		if len(src) > maxFilterExprLength {
+			return f, fmt.Errorf("filter must not exceed %d bytes", maxFilterExprLength)
+		}
+		expr, err := filterexpr.Parse(src, model.PersonFilterFields)
+		if err != nil {
+			return f, fmt.Errorf("invalid filter: %w", err)
+		}
+		f.Expr = expr
+	}
+	return f, nil
+}
+
+func toProto(p *model.Person) *personv1.Person {
+	return &personv1.Person{
+		Id:          uint32(p.ID),
+		Name:        p.Name,
+		Surname:     p.Surname,
+		Patronymic:  p.Patronymic,
+		Gender:      p.Gender,
+		Age:         int32(p.Age),
+		Nationality: p.Nationality,
+		Version:     uint32(p.Version),
+		CreatedAt:   timestamppb.New(p.CreatedAt),
+		UpdatedAt:   timestamppb.New(p.UpdatedAt),
+	}
+}
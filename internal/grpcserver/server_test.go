@@ -0,0 +1,172 @@
+package grpcserver_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	personv1 "effective-mobile/api/person/v1"
+	"effective-mobile/internal/grpcserver"
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func init() {
+	logger.Init()
+}
+
+// fakeService implements the calls the tests make; any other call panics.
+type fakeService struct {
+	service.PersonServiceInterface
+	actor  string
+	filter model.PersonFilter
+}
+
+func (f *fakeService) CreatePerson(ctx context.Context, req model.CreatePersonRequest) (*model.Person, error) {
+	f.actor = service.ActorFrom(ctx)
+	if req.Name == "Duplicate" {
+		return nil, &service.DuplicateError{Candidates: []uint{4}}
+	}
+	return &model.Person{ID: 1, Name: req.Name, Surname: req.Surname, Gender: "female", Version: 1}, nil
+}
+
+func (f *fakeService) GetPersonByID(ctx context.Context, id uint) (*model.Person, error) {
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeService) UpdatePerson(ctx context.Context, id uint, req model.UpdatePersonRequest) (*model.Person, error) {
+	return nil, repository.ErrVersionConflict
+}
+
+func (f *fakeService) ExportPersons(ctx context.Context, filter model.PersonFilter, fn func(p *model.Person) error) error {
+	f.filter = filter
+	for i := uint(1); i <= 3; i++ {
+		if err := fn(&model.Person{ID: i, Name: "Alice"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dial(t *testing.T, svc service.PersonServiceInterface) personv1.PersonServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcserver.NewServer(svc)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return personv1.NewPersonServiceClient(conn)
+}
+
+func TestCreate(t *testing.T) {
+	svc := &fakeService{}
+	client := dial(t, svc)
+
+	_, err := client.Create(context.Background(), &personv1.CreateRequest{Name: "Alice"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without surname, got %v", err)
+	}
+
+	_, err = client.Create(context.Background(), &personv1.CreateRequest{Name: "Duplicate", Surname: "Smith"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected AlreadyExists for a duplicate, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcserver.ActorMetadata, "crm")
+	p, err := client.Create(ctx, &personv1.CreateRequest{Name: "Alice", Surname: "Smith"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetId() != 1 || p.GetGender() != "female" || svc.actor != "crm" {
+		t.Fatalf("unexpected person %v created by %q", p, svc.actor)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	client := dial(t, &fakeService{})
+
+	_, err := client.Get(context.Background(), &personv1.GetRequest{Id: 7})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+	_, err = client.Update(context.Background(), &personv1.UpdateRequest{Id: 7, Name: "Bob", Version: 1})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition on version conflict, got %v", err)
+	}
+	_, err = client.Delete(context.Background(), &personv1.DeleteRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without ID, got %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	svc := &fakeService{}
+	client := dial(t, svc)
+
+	stream, err := client.List(context.Background(), &personv1.ListRequest{Filter: "age >>= 1"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a bad filter, got %v", err)
+	}
+
+	stream, err = client.List(context.Background(), &personv1.ListRequest{Nationality: "RU", Filter: "age >= 18", Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint32
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p.GetId())
+	}
+	if len(ids) != 3 || ids[2] != 3 {
+		t.Fatalf("expected three streamed people, got %v", ids)
+	}
+	if svc.filter.Nationality != "RU" || svc.filter.Expr == nil || svc.filter.Limit != 3 {
+		t.Fatalf("unexpected filter %+v", svc.filter)
+	}
+
+	stream, err = client.List(context.Background(), &personv1.ListRequest{Limit: 1_000_000})
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	if err != io.EOF || svc.filter.Limit != 1000 {
+		t.Fatalf("expected the limit to be capped at 1000, got %d %v", svc.filter.Limit, err)
+	}
+}
+
+func TestNewServerWithoutReflection(t *testing.T) {
+	srv := grpcserver.NewServer(&fakeService{})
+	if _, ok := srv.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]; ok {
+		t.Fatal("expected reflection to be off by default")
+	}
+
+	grpcserver.RegisterReflection(srv)
+	if _, ok := srv.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]; !ok {
+		t.Fatal("expected reflection to be registered")
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// GetChanges godoc
+// @Summary Изменения для синхронизации
+// @Description Возвращает людей, созданных или изменённых после токена since, и надгробия (deleted) удалённых после него, в порядке изменений. Без since возвращается полная выгрузка живых людей. Токен из ответа передаётся как since в следующем запросе; пока has_more равно true, следующая страница уже готова. Применяя страницы по порядку, клиент поддерживает точную копию без повторной выгрузки
+// @Tags persons
+// @Produce json
+// @Param since query string false "Токен из предыдущего ответа"
+// @Param limit query int false "Размер страницы, по умолчанию 500"
+// @Success 200 {object} model.PersonChanges
+// @Failure 400 {string} string "invalid change token or limit"
+// @Failure 500 {string} string "failed to get changes"
+// @Router /v1/person/changes [get]
+func (h *PersonHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	since := r.URL.Query().Get("since")
+	logger.Log.Debug("GET /person/changes - received request", zap.String("since", since))
+
+	limit, _, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.service.GetChanges(requestContext(r), since, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidChangeToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Log.Error("failed to get changes", zap.Error(err))
+		http.Error(w, "failed to get changes: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("changes fetched", zap.String("since", since), zap.String("token", changes.Token),
+		zap.Int("changed", len(changes.Changed)), zap.Int("deleted", len(changes.Deleted)))
+	writeJSON(w, changes, http.StatusOK)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/validator"
+
+	"go.uber.org/zap"
+)
+
+// FindDuplicates godoc
+// @Summary Возможные дубликаты
+// @Description Группирует людей с одинаковым нормализованным ФИО, по тому же правилу, что и проверка дубликатов при создании. Фильтры сужают выборку людей, limit и offset листают группы
+// @Tags duplicates
+// @Produce json
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Param limit query int false "Число групп"
+// @Param offset query int false "Смещение по группам"
+// @Success 200 {array} model.DuplicateCluster
+// @Failure 400 {string} string "invalid filter"
+// @Failure 500 {string} string "failed to find duplicates"
+// @Router /v1/person/duplicates [get]
+func (h *PersonHandler) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("GET /person/duplicates - received request", zap.String("query", r.URL.RawQuery))
+
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	clusters, err := h.service.FindDuplicates(requestContext(r), filter)
+	if err != nil {
+		logger.Log.Error("failed to find duplicates", zap.Error(err))
+		http.Error(w, "failed to find duplicates: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("duplicate clusters found", zap.Int("count", len(clusters)))
+	writeJSON(w, clusters, http.StatusOK)
+}
+
+// MergePersons godoc
+// @Summary Слияние дубликатов
+// @Description Объединяет людей из source_ids с target_id и переносит их в корзину. Целевая запись сохраняет свои значения, пустые поля заполняются из источников; fields задаёт для поля ID человека, чьё значение побеждает. История всех записей сохраняется
+// @Tags duplicates
+// @Accept json
+// @Produce json
+// @Param X-Actor header string false "Автор изменения"
+// @Param merge body model.MergeRequest true "Параметры слияния"
+// @Success 200 {object} model.MergeResult
+// @Failure 400 {string} string "invalid JSON or merge request"
+// @Failure 404 {string} string "person not found"
+// @Failure 409 {string} string "person changed concurrently"
+// @Failure 500 {string} string "failed to merge"
+// @Router /v1/person/merge [post]
+func (h *PersonHandler) MergePersons(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("POST /person/merge - received request")
+
+	var req model.MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log.Warn("failed to decode merge JSON", zap.Error(err))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := validator.Validate.Struct(req); err != nil {
+		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.MergePersons(requestContext(r), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMerge):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repository.ErrNotFound):
+			http.Error(w, "person not found", http.StatusNotFound)
+		case errors.Is(err, repository.ErrVersionConflict):
+			http.Error(w, "person changed concurrently", http.StatusConflict)
+		default:
+			logger.Log.Error("failed to merge persons", zap.Error(err))
+			http.Error(w, "failed to merge: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Log.Info("persons merged", zap.Uint("target", result.Person.ID), zap.Uints("sources", result.MergedIDs), zap.Strings("conflicts", result.Conflicts))
+	w.Header().Set("ETag", etag(&result.Person))
+	writeJSON(w, result, http.StatusOK)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"effective-mobile/internal/model"
+)
+
+var errInvalidETag = errors.New("invalid entity tag")
+
+// etag returns the entity tag of the person's current version.
+func etag(p *model.Person) string {
+	return `"` + strconv.FormatUint(uint64(p.Version), 10) + `"`
+}
+
+// ifMatchVersion extracts the expected version from the If-Match header.
+// It returns zero when the header is absent or set to "*".
+func ifMatchVersion(r *http.Request) (uint, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errInvalidETag
+	}
+	return parseETag(header)
+}
+
+// etagMatches reports whether the If-None-Match header lists the given tag.
+func etagMatches(header, tag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func parseETag(tag string) (uint, error) {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, errInvalidETag
+	}
+	v, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32)
+	if err != nil || v == 0 {
+		return 0, errInvalidETag
+	}
+	return uint(v), nil
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"effective-mobile/internal/events"
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const sseHeartbeat = 15 * time.Second
+
+// WithEvents sets the broker that live feeds subscribe to. Without it the
+// feeds are disabled.
+func (h *PersonHandler) WithEvents(broker *events.Broker) *PersonHandler {
+	h.events = broker
+	return h
+}
+
+// StreamEvents godoc
+// @Summary Лента изменений (SSE)
+// @Description Поток Server-Sent Events о создании, изменении, удалении и обогащении людей. Фильтры списка отбирают события по состоянию человека после изменения. При переподключении заголовок Last-Event-ID (или параметр last_event_id) продолжает поток с пропущенных событий; если их уже нет в буфере, сначала приходит событие reset, и клиенту нужно перечитать список
+// @Tags events
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Param last_event_id query int false "ID последнего полученного события"
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Success 200 {object} model.PersonEvent "поток событий"
+// @Failure 400 {string} string "invalid filter or event ID"
+// @Failure 503 {string} string "event feed is not enabled or too many subscribers"
+// @Router /v1/person/events [get]
+func (h *PersonHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		http.Error(w, "event feed is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lastID, err := parseLastEventID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.acquireSubscriber() {
+		http.Error(w, "too many subscribers", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.releaseSubscriber()
+
+	sub, missed, complete := h.events.Subscribe(lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	logger.Log.Info("event feed opened", zap.Uint64("last_event_id", lastID), zap.Int("replayed", len(missed)), zap.Bool("complete", complete))
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for i := range missed {
+		if err := writeSSE(w, &missed[i], filter); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind. The client reconnects with
+				// Last-Event-ID and catches up from the backlog.
+				logger.Log.Warn("event feed subscriber fell behind")
+				return
+			}
+			if err := writeSSE(w, &ev, filter); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// WithSubscriberLimit caps the number of concurrent live feed connections,
+// SSE and WebSocket together. Zero means no limit.
+func (h *PersonHandler) WithSubscriberLimit(limit int) *PersonHandler {
+	h.subscribers = nil
+	if limit > 0 {
+		h.subscribers = make(chan struct{}, limit)
+	}
+	return h
+}
+
+func (h *PersonHandler) acquireSubscriber() bool {
+	if h.subscribers == nil {
+		return true
+	}
+	select {
+	case h.subscribers <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (h *PersonHandler) releaseSubscriber() {
+	if h.subscribers != nil {
+		<-h.subscribers
+	}
+}
+
+// parseLastEventID reads the ID to resume a feed from, sent by browsers in
+// the Last-Event-ID header or by other clients as the last_event_id
+// parameter.
+func parseLastEventID(r *http.Request) (uint64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event ID: %q", v)
+	}
+	return id, nil
+}
+
+// writeSSE writes ev as a server-sent event if its person matches filter.
+// Filtered out events still advance the client's last event ID, so a
+// reconnect does not replay them.
+func writeSSE(w http.ResponseWriter, ev *model.PersonEvent, filter model.PersonFilter) error {
+	if !filter.Matches(ev.Subject()) {
+		_, err := fmt.Fprintf(w, "id: %d\n\n", ev.ID)
+		return err
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
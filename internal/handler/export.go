@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/xlsx"
+
+	"go.uber.org/zap"
+)
+
+var exportColumns = []string{"id", "name", "surname", "patronymic", "gender", "age", "nationality", "version", "created_at", "updated_at"}
+
+// ExportPersons godoc
+// @Summary Выгрузка людей
+// @Description Потоково выгружает людей, подходящих под фильтры списка, в формате CSV, NDJSON или XLSX
+// @Tags persons
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string true "Формат выгрузки" Enums(csv, ndjson, xlsx)
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {file} file "persons"
+// @Failure 400 {string} string "invalid format or filter"
+// @Failure 500 {string} string "failed to export persons"
+// @Router /v1/person/export [get]
+func (h *PersonHandler) ExportPersons(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("GET /person/export - received request", zap.String("query", r.URL.RawQuery))
+
+	format := r.URL.Query().Get("format")
+	newWriter, ok := exportFormats[format]
+	if !ok {
+		http.Error(w, "invalid format: expected csv, ndjson or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := &exportResponse{ResponseWriter: w}
+	resp.Header().Set("Content-Disposition", `attachment; filename="persons.`+format+`"`)
+	out, err := newWriter(resp)
+	if err != nil {
+		logger.Log.Error("failed to start export", zap.Error(err))
+		resp.fail("failed to start export")
+		return
+	}
+
+	count := 0
+	err = h.service.ExportPersons(requestContext(r), filter, func(p *model.Person) error {
+		count++
+		return out.Write(p)
+	})
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		logger.Log.Error("export failed", zap.String("format", format), zap.Int("written", count), zap.Error(err))
+		resp.fail("failed to export persons")
+		return
+	}
+
+	logger.Log.Info("persons exported", zap.String("format", format), zap.Int("count", count))
+}
+
+// exportResponse notes whether any of the export has been sent, so that a
+// failure can still be reported with a status.
+type exportResponse struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *exportResponse) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// fail answers 500 while nothing has been sent. Once the status line is out
+// it aborts the connection instead, to let the client see a truncated
+// download rather than a silent success.
+func (w *exportResponse) fail(msg string) {
+	if w.started {
+		panic(http.ErrAbortHandler)
+	}
+	w.Header().Del("Content-Disposition")
+	http.Error(w, msg, http.StatusInternalServerError)
+}
+
+type exportWriter interface {
+	Write(p *model.Person) error
+	Close() error
+}
+
+var exportFormats = map[string]func(w http.ResponseWriter) (exportWriter, error){
+	"csv":    newCSVExport,
+	"ndjson": newNDJSONExport,
+	"xlsx":   newXLSXExport,
+}
+
+func exportRecord(p *model.Person) []string {
+	return []string{
+		strconv.FormatUint(uint64(p.ID), 10),
+		p.Name,
+		p.Surname,
+		p.Patronymic,
+		p.Gender,
+		strconv.Itoa(p.Age),
+		p.Nationality,
+		strconv.FormatUint(uint64(p.Version), 10),
+		p.CreatedAt.Format(time.RFC3339),
+		p.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w http.ResponseWriter) (exportWriter, error) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	return &csvExport{w: cw}, cw.Write(exportColumns)
+}
+
+func (e *csvExport) Write(p *model.Person) error {
+	return e.w.Write(exportRecord(p))
+}
+
+func (e *csvExport) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func newNDJSONExport(w http.ResponseWriter) (exportWriter, error) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	return &ndjsonExport{enc: json.NewEncoder(w)}, nil
+}
+
+func (e *ndjsonExport) Write(p *model.Person) error {
+	return e.enc.Encode(p)
+}
+
+func (e *ndjsonExport) Close() error {
+	return nil
+}
+
+type xlsxExport struct {
+	sw *xlsx.StreamWriter
+}
+
+func newXLSXExport(w http.ResponseWriter) (exportWriter, error) {
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	sw, err := xlsx.NewStreamWriter(w, "persons")
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(exportColumns))
+	for i, c := range exportColumns {
+		header[i] = c
+	}
+	return &xlsxExport{sw: sw}, sw.WriteRow(header...)
+}
+
+func (e *xlsxExport) Write(p *model.Person) error {
+	return e.sw.WriteRow(p.ID, p.Name, p.Surname, p.Patronymic, p.Gender, p.Age, p.Nationality, p.Version, p.CreatedAt, p.UpdatedAt)
+}
+
+func (e *xlsxExport) Close() error {
+	return e.sw.Close()
+}
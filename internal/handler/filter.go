@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/filterexpr"
+)
+
+const (
+	maxPageSize         = 1000
+	maxFilterExprLength = 1000
+)
+
+// parsePersonFilter reads the list filter from query parameters:
+// name, surname, patronymic, gender, nationality, age_min, age_max,
+// filter, limit and offset.
+func parsePersonFilter(q url.Values) (model.PersonFilter, error) {
+	f := model.PersonFilter{
+		Name:        q.Get("name"),
+		Surname:     q.Get("surname"),
+		Patronymic:  q.Get("patronymic"),
+		Gender:      q.Get("gender"),
+		Nationality: q.Get("nationality"),
+	}
+
+	ints := []struct {
+		param  string
+		target *int
+	}{
+		{"age_min", &f.AgeMin},
+		{"age_max", &f.AgeMax},
+	}
+	for _, p := range ints {
+		n, err := queryInt(q, p.param)
+		if err != nil {
+			return f, err
+		}
+		*p.target = n
+	}
+	var err error
+	if f.Limit, f.Offset, err = parsePageQuery(q); err != nil {
+		return f, err
+	}
+
+	if src := q.Get("filter"); src != "" {
+		if len(src) > maxFilterExprLength {
+			return f, fmt.Errorf("filter must not exceed %d bytes", maxFilterExprLength)
+		}
+		expr, err := filterexpr.Parse(src, model.PersonFilterFields)
+		if err != nil {
+			return f, fmt.Errorf("invalid filter: %w", err)
+		}
+		f.Expr = expr
+	}
+
+	if f.AgeMin > 0 && f.AgeMax > 0 && f.AgeMin > f.AgeMax {
+		return f, fmt.Errorf("age_min must not exceed age_max")
+	}
+	return f, nil
+}
+
+// parsePage reads the limit and offset query parameters of a paged list
+// that takes no person filter.
+func parsePage(r *http.Request) (limit, offset int, err error) {
+	return parsePageQuery(r.URL.Query())
+}
+
+func parsePageQuery(q url.Values) (limit, offset int, err error) {
+	if limit, err = queryInt(q, "limit"); err != nil {
+		return 0, 0, err
+	}
+	if offset, err = queryInt(q, "offset"); err != nil {
+		return 0, 0, err
+	}
+	if limit > maxPageSize {
+		return 0, 0, fmt.Errorf("limit must not exceed %d", maxPageSize)
+	}
+	return limit, offset, nil
+}
+
+// queryInt reads a non-negative integer query parameter, zero when absent.
+func queryInt(q url.Values, param string) (int, error) {
+	v := q.Get(param)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", param, v)
+	}
+	return n, nil
+}
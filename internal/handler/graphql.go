@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"effective-mobile/internal/gql"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const maxGraphQLRequestSize = 1 << 20
+
+type GraphQLHandler struct {
+	schema *gql.Schema
+}
+
+func NewGraphQLHandler(s *gql.Schema) *GraphQLHandler {
+	return &GraphQLHandler{schema: s}
+}
+
+// ServeGraphQL godoc
+// @Summary GraphQL
+// @Description Выполняет GraphQL-запрос: person(id), people(filter, sort, first, after) с курсорной пагинацией, stats и мутации createPerson, updatePerson, deletePerson. Клиент выбирает только нужные поля. Запросы глубже или сложнее лимитов отклоняются до выполнения; сложность считает каждое поле, stats — как сто полей, а поля внутри people — столько раз, сколько людей может вернуть страница. Ошибки отдельных полей возвращаются в errors с кодом в extensions.code при статусе 200
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body gql.Request true "Запрос"
+// @Success 200 {object} object "data и errors"
+// @Failure 400 {object} object "невалидный запрос или превышены лимиты"
+// @Router /v1/graphql [post]
+func (h *GraphQLHandler) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)
+	var req gql.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log.Warn("failed to decode GraphQL request", zap.Error(err))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	logger.Log.Debug("POST /graphql - received request", zap.String("operation", req.OperationName))
+
+	result, err := h.schema.Execute(requestContext(r), req)
+	if err != nil {
+		var reqErr *gql.RequestError
+		if errors.As(err, &reqErr) {
+			logger.Log.Warn("GraphQL request rejected", zap.Error(err))
+			writeJSON(w, map[string]any{"errors": reqErr.Errors}, http.StatusBadRequest)
+			return
+		}
+		logger.Log.Error("failed to execute GraphQL request", zap.Error(err))
+		http.Error(w, "failed to execute GraphQL request: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, result, http.StatusOK)
+}
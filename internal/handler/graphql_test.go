@@ -0,0 +1,48 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"effective-mobile/internal/gql"
+	"effective-mobile/internal/handler"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	schema, err := gql.NewSchema(&mockPersonService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewGraphQLHandler(schema.WithLimits(3, 100))
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ people { edges { node { id } } } }"}`))
+	rec := httptest.NewRecorder()
+	h.ServeGraphQL(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "depth") {
+		t.Fatalf("expected 400 for a query too deep, got %d %s", rec.Result().StatusCode, rec.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ people { nodes { id name } totalCount } }"}`))
+	rec = httptest.NewRecorder()
+	h.ServeGraphQL(rec, req)
+
+	var resp struct {
+		Data struct {
+			People struct {
+				Nodes []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"nodes"`
+				TotalCount int `json:"totalCount"`
+			} `json:"people"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	people := resp.Data.People
+	if rec.Result().StatusCode != http.StatusOK || len(people.Nodes) != 1 || people.Nodes[0].Name != "Alice" || people.TotalCount != 1 {
+		t.Fatalf("unexpected response %d %+v", rec.Result().StatusCode, resp)
+	}
+}
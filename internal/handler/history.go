@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/validator"
+
+	"go.uber.org/zap"
+)
+
+// GetPersonHistory godoc
+// @Summary История изменений человека
+// @Description Возвращает все изменения человека: создание, обогащение, обновления, удаление и восстановление, с изменёнными полями, автором и источником
+// @Tags history
+// @Produce json
+// @Param id path int true "ID человека"
+// @Success 200 {array} model.PersonHistory
+// @Failure 400 {string} string "invalid ID"
+// @Failure 500 {string} string "failed to get history"
+// @Router /v1/person/{id}/history [get]
+func (h *PersonHandler) GetPersonHistory(w http.ResponseWriter, r *http.Request) {
+	idString := r.PathValue("id")
+	id, err := strconv.ParseUint(idString, 10, 32)
+	if err != nil {
+		logger.Log.Warn("invalid ID", zap.String("id", idString), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Log.Debug("fetching person history", zap.Uint("id", uint(id)))
+	entries, err := h.service.GetPersonHistory(requestContext(r), uint(id))
+	if err != nil {
+		logger.Log.Error("failed to get person history", zap.Error(err))
+		http.Error(w, "failed to get history: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("person history fetched", zap.Uint("id", uint(id)), zap.Int("count", len(entries)))
+	writeJSON(w, entries, http.StatusOK)
+}
+
+// RollbackPerson godoc
+// @Summary Откат человека к ревизии
+// @Description Возвращает поля человека к состоянию указанной ревизии из истории. Откат создаёт новую ревизию
+// @Tags history
+// @Accept json
+// @Produce json
+// @Param id path int true "ID человека"
+// @Param X-Actor header string false "Автор изменения"
+// @Param rollback body model.RollbackRequest true "Ревизия"
+// @Success 200 {object} model.Person
+// @Failure 400 {string} string "invalid ID or JSON"
+// @Failure 404 {string} string "person or revision not found"
+// @Failure 409 {string} string "person changed concurrently"
+// @Failure 500 {string} string "failed to rollback"
+// @Router /v1/person/{id}/rollback [post]
+func (h *PersonHandler) RollbackPerson(w http.ResponseWriter, r *http.Request) {
+	idString := r.PathValue("id")
+	id, err := strconv.ParseUint(idString, 10, 32)
+	if err != nil {
+		logger.Log.Warn("invalid ID", zap.String("id", idString), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req model.RollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log.Warn("failed to decode rollback JSON", zap.Error(err))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := validator.Validate.Struct(req); err != nil {
+		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Log.Info("rolling back person", zap.Uint("id", uint(id)), zap.Uint("revision", req.Revision))
+	person, err := h.service.RollbackPerson(requestContext(r), uint(id), req.Revision)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			logger.Log.Warn("person or revision not found", zap.Uint("id", uint(id)), zap.Uint("revision", req.Revision))
+			http.Error(w, "person or revision not found", http.StatusNotFound)
+		case errors.Is(err, repository.ErrVersionConflict):
+			http.Error(w, "person changed concurrently", http.StatusConflict)
+		default:
+			logger.Log.Error("failed to rollback person", zap.Error(err))
+			http.Error(w, "failed to rollback: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Log.Info("person rolled back", zap.Uint("id", uint(id)), zap.Uint("version", person.Version))
+	w.Header().Set("ETag", etag(person))
+	writeJSON(w, person, http.StatusOK)
+}
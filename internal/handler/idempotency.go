@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotent wraps next so that requests carrying an Idempotency-Key header
+// are executed at most once per key. The first non-5xx response is stored
+// for ttl and replayed for retries with the same payload; reusing a key with
+// a different payload is rejected with 422.
+func Idempotent(repo repository.IdempotencyRepositoryInterface, ttl time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.Log.Warn("failed to read request body", zap.Error(err))
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		now := time.Now()
+		reserved, err := repo.Reserve(&model.IdempotencyRecord{
+			Key:         key,
+			RequestHash: hash,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		})
+		if err != nil {
+			logger.Log.Error("failed to reserve idempotency key", zap.Error(err))
+			http.Error(w, "failed to process idempotency key", http.StatusInternalServerError)
+			return
+		}
+
+		if !reserved {
+			replayIdempotent(w, repo, key, hash)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		if rec.status >= http.StatusInternalServerError {
+			if err := repo.Release(key); err != nil {
+				logger.Log.Error("failed to release idempotency key", zap.String("key", key), zap.Error(err))
+			}
+			return
+		}
+
+		err = repo.Complete(&model.IdempotencyRecord{
+			Key:        key,
+			StatusCode: rec.status,
+			Header:     storedHeader(w.Header()),
+			Body:       rec.body.Bytes(),
+		})
+		if err != nil {
+			logger.Log.Error("failed to store idempotent response", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func replayIdempotent(w http.ResponseWriter, repo repository.IdempotencyRepositoryInterface, key, hash string) {
+	stored, err := repo.FindByKey(key)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// The key expired or was released between Reserve and FindByKey.
+			http.Error(w, "request with this Idempotency-Key is in progress", http.StatusConflict)
+			return
+		}
+		logger.Log.Error("failed to load idempotency key", zap.Error(err))
+		http.Error(w, "failed to process idempotency key", http.StatusInternalServerError)
+		return
+	}
+
+	if stored.RequestHash != hash {
+		logger.Log.Warn("idempotency key reused with different payload", zap.String("key", key))
+		http.Error(w, "Idempotency-Key was used with a different payload", http.StatusUnprocessableEntity)
+		return
+	}
+	if stored.StatusCode == 0 {
+		http.Error(w, "request with this Idempotency-Key is in progress", http.StatusConflict)
+		return
+	}
+
+	logger.Log.Info("replaying idempotent response", zap.String("key", key), zap.Int("status", stored.StatusCode))
+	for name, values := range stored.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.Body)
+}
+
+// requestHash fingerprints the request so a reused key can be told apart
+// from a genuine retry. JSON bodies are compacted first so that whitespace
+// differences between retries don't count as a different payload. The query
+// is part of it (?force=true, ?mode=partial change what the request does),
+// with its parameters sorted so their order doesn't matter.
+func requestHash(r *http.Request, body []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		body = compact.Bytes()
+	}
+
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Encode()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func storedHeader(h http.Header) http.Header {
+	stored := http.Header{}
+	for _, name := range []string{"Content-Type", "ETag", "Location"} {
+		if v := h.Values(name); len(v) > 0 {
+			stored[name] = v
+		}
+	}
+	return stored
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
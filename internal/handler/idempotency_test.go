@@ -0,0 +1,112 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"effective-mobile/internal/handler"
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+)
+
+type memoryIdempotencyRepo struct {
+	records map[string]*model.IdempotencyRecord
+}
+
+func newMemoryIdempotencyRepo() *memoryIdempotencyRepo {
+	return &memoryIdempotencyRepo{records: map[string]*model.IdempotencyRecord{}}
+}
+
+func (m *memoryIdempotencyRepo) Reserve(rec *model.IdempotencyRecord) (bool, error) {
+	if _, ok := m.records[rec.Key]; ok {
+		return false, nil
+	}
+	m.records[rec.Key] = rec
+	return true, nil
+}
+
+func (m *memoryIdempotencyRepo) FindByKey(key string) (*model.IdempotencyRecord, error) {
+	rec, ok := m.records[key]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return rec, nil
+}
+
+func (m *memoryIdempotencyRepo) Complete(rec *model.IdempotencyRecord) error {
+	stored := m.records[rec.Key]
+	stored.StatusCode = rec.StatusCode
+	stored.Header = rec.Header
+	stored.Body = rec.Body
+	return nil
+}
+
+func (m *memoryIdempotencyRepo) Release(key string) error {
+	delete(m.records, key)
+	return nil
+}
+
+func TestIdempotentReplaysFirstResponse(t *testing.T) {
+	calls := 0
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}
+	h := handler.Idempotent(newMemoryIdempotencyRepo(), time.Hour, next)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/person", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "abc")
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	first := send(`{"name":"Alice","surname":"Smith"}`)
+	second := send(`{"name": "Alice", "surname": "Smith"}`)
+
+	if calls != 1 {
+		t.Fatalf("expected handler to run once, ran %d times", calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected replay %d %q, got %d %q", first.Code, first.Body, second.Code, second.Body)
+	}
+
+	third := send(`{"name":"Bob","surname":"Smith"}`)
+	if third.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a different payload, got %d", third.Code)
+	}
+}
+
+func TestIdempotentKeyReusedWithDifferentQuery(t *testing.T) {
+	calls := 0
+	next := func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusConflict)
+	}
+	h := handler.Idempotent(newMemoryIdempotencyRepo(), time.Hour, next)
+
+	send := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"name":"Alice","surname":"Smith"}`))
+		req.Header.Set("Idempotency-Key", "abc")
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	send("/person")
+	if replay := send("/person"); replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected the same request to replay, got %d", replay.Code)
+	}
+	if rec := send("/person?force=true"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for a retry with ?force=true, got %d", rec.Code)
+	}
+	if calls != 1 {
+		t.Fatalf("expected handler to run once, ran %d times", calls)
+	}
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/validator"
+
+	"go.uber.org/zap"
+)
+
+const (
+	importBatchSize = 100
+	maxImportSize   = 50 << 20
+)
+
+var importColumns = []string{"name", "surname", "patronymic", "gender", "age", "nationality"}
+
+// ImportPersons godoc
+// @Summary Импорт людей из CSV
+// @Description Принимает CSV (multipart поле file или тело text/csv) с заголовком name,surname[,patronymic,gender,age,nationality]. Строки проверяются и сохраняются пакетами, недостающие данные обогащаются через внешние API
+// @Tags persons
+// @Accept mpfd
+// @Accept text/csv
+// @Produce json
+// @Param file formData file false "CSV файл"
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {string} string "invalid CSV"
+// @Failure 415 {string} string "unsupported content type"
+// @Router /v1/person/import [post]
+func (h *PersonHandler) ImportPersons(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("POST /person/import - received request")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	src, err := importSource(r)
+	if err != nil {
+		logger.Log.Warn("invalid import upload", zap.Error(err))
+		status := http.StatusBadRequest
+		if errors.Is(err, errUnsupportedContentType) {
+			status = http.StatusUnsupportedMediaType
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	reader := csv.NewReader(src)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		http.Error(w, "invalid CSV: missing header", http.StatusBadRequest)
+		return
+	}
+	columns, err := importHeader(header)
+	if err != nil {
+		http.Error(w, "invalid CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := model.ImportReport{Rows: []model.ImportRowResult{}}
+	batch := make([]model.ImportRow, 0, importBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		report.Add(h.service.ImportPersons(requestContext(r), batch)...)
+		batch = batch[:0]
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				logger.Log.Warn("failed to read CSV", zap.Error(err))
+				http.Error(w, "failed to read CSV: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			report.Add(model.ImportRowResult{Line: parseErr.Line, Status: model.ImportRowRejected, Error: parseErr.Err.Error()})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row, err := importRow(columns, record)
+		if err == nil {
+			err = validator.Validate.Struct(row.Person)
+		}
+		if err != nil {
+			report.Add(model.ImportRowResult{Line: line, Status: model.ImportRowRejected, Error: err.Error()})
+			continue
+		}
+
+		row.Line = line
+		batch = append(batch, row)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	logger.Log.Info("persons imported", zap.Int("accepted", report.Accepted), zap.Int("rejected", report.Rejected))
+	writeJSON(w, report, http.StatusOK)
+}
+
+var errUnsupportedContentType = errors.New("unsupported content type, expected multipart/form-data or text/csv")
+
+// importSource returns the CSV stream of the request without buffering it:
+// the "file" part of a multipart form or the raw body for text/csv.
+func importSource(r *http.Request) (io.Reader, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, errUnsupportedContentType
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return r.Body, nil
+	case "multipart/form-data":
+		mr, err := r.MultipartReader()
+		if err != nil {
+			return nil, err
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil, errors.New("multipart form has no file field")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if part.FormName() == "file" {
+				return part, nil
+			}
+		}
+	default:
+		return nil, errUnsupportedContentType
+	}
+}
+
+// importHeader maps known column names to their positions.
+func importHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for _, known := range importColumns {
+			if name == known {
+				columns[name] = i
+			}
+		}
+	}
+	for _, required := range []string{"name", "surname"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+	return columns, nil
+}
+
+func importRow(columns map[string]int, record []string) (model.ImportRow, error) {
+	get := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	row := model.ImportRow{
+		Person: model.CreatePersonRequest{
+			Name:       get("name"),
+			Surname:    get("surname"),
+			Patronymic: get("patronymic"),
+		},
+		Gender:      get("gender"),
+		Nationality: get("nationality"),
+	}
+	if age := get("age"); age != "" {
+		n, err := strconv.Atoi(age)
+		if err != nil || n < 0 {
+			return row, fmt.Errorf("invalid age %q", age)
+		}
+		row.Age = n
+	}
+	return row, nil
+}
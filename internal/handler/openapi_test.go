@@ -0,0 +1,111 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"effective-mobile/internal/gql"
+	"effective-mobile/internal/handler"
+	"effective-mobile/internal/openapi"
+)
+
+// TestHandlersMatchOpenAPI sends requests through the OpenAPI validator
+// with response checks on, which answers 500 to any response the document
+// does not describe.
+func TestHandlersMatchOpenAPI(t *testing.T) {
+	v, err := openapi.NewValidator(openapi.Document())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := gql.NewSchema(&mockPersonService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewPersonHandler(&mockPersonService{}).WithAdminToken("secret").WithBatchLimit(2)
+	wh := handler.NewWebhookHandler(&mockWebhookService{}).WithAdminToken("secret")
+	gh := handler.NewGraphQLHandler(schema.WithLimits(10, 1000))
+
+	repo := newMemoryIdempotencyRepo()
+	routes := handler.V1(h, wh, gh, func(next http.HandlerFunc) http.HandlerFunc {
+		return handler.Idempotent(repo, time.Hour, next)
+	})
+	mux := http.NewServeMux()
+	handler.Mount(mux, "/v1", routes)
+	mux.HandleFunc("GET /openapi.json", openapi.ServeDocument)
+	srv := v.WithResponses(true).Middleware(mux)
+	admin := http.Header{"X-Admin-Token": {"secret"}}
+
+	tests := []struct {
+		method     string
+		target     string
+		body       string
+		header     http.Header
+		wantStatus int
+	}{
+		{"POST", "/v1/person", `{"name":"Dmitriy","surname":"Ushakov"}`, nil, http.StatusCreated},
+		{"POST", "/v1/person", `{"name":"Duplicate","surname":"Ushakov"}`, nil, http.StatusConflict},
+		{"POST", "/v1/person/batch", `[{"name":"A","surname":"B"},{"name":"C","surname":"D"}]`, nil, http.StatusCreated},
+		{"POST", "/v1/person/batch?mode=atomic", `[{"name":"A"}]`, nil, http.StatusUnprocessableEntity},
+		{"POST", "/v1/person/batch", `[{},{},{}]`, nil, http.StatusRequestEntityTooLarge},
+		{"POST", "/v1/person/import", "name,surname\nDmitriy,Ushakov\n", http.Header{"Content-Type": {"text/csv"}}, http.StatusOK},
+		{"GET", "/v1/person?limit=10", "", nil, http.StatusOK},
+		{"GET", "/v1/person?filter=age%20%3E", "", nil, http.StatusBadRequest},
+		{"GET", "/v1/person/export?format=ndjson", "", nil, http.StatusOK},
+		{"GET", "/v1/person/search?q=Ushakov", "", nil, http.StatusOK},
+		{"GET", "/v1/person/changes?limit=2", "", nil, http.StatusOK},
+		{"GET", "/v1/person/changes?since=bad", "", nil, http.StatusBadRequest},
+		{"GET", "/v1/person/stats?age_buckets=18,30", "", nil, http.StatusOK},
+		{"GET", "/v1/person/duplicates", "", nil, http.StatusOK},
+		{"POST", "/v1/person/merge", `{"target_id":1,"source_ids":[3]}`, nil, http.StatusOK},
+		{"POST", "/v1/person/merge", `{"target_id":404,"source_ids":[3]}`, nil, http.StatusNotFound},
+		{"GET", "/v1/person/trash", "", nil, http.StatusOK},
+		{"POST", "/v1/person/3/restore", "", nil, http.StatusOK},
+		{"POST", "/v1/person/4/restore", "", nil, http.StatusNotFound},
+		{"GET", "/v1/person/1/history", "", nil, http.StatusOK},
+		{"POST", "/v1/person/1/rollback", `{"revision":1}`, nil, http.StatusOK},
+		{"POST", "/v1/person/1/rollback", `{"revision":7}`, nil, http.StatusNotFound},
+		{"PATCH", "/v1/person?gender=male&dry_run=true", `{"age":30}`, nil, http.StatusOK},
+		{"DELETE", "/v1/person?gender=male", "", nil, http.StatusPreconditionRequired},
+		{"GET", "/v1/person/1", "", nil, http.StatusOK},
+		{"GET", "/v1/person/1", "", http.Header{"If-None-Match": {`"2"`}}, http.StatusNotModified},
+		{"PUT", "/v1/person/1", `{"name":"Alice"}`, http.Header{"If-Match": {`"1"`}}, http.StatusPreconditionFailed},
+		{"PUT", "/v1/person/1", `{"name":"Alice"}`, nil, http.StatusOK},
+		{"DELETE", "/v1/person/1", "", nil, http.StatusNoContent},
+		{"DELETE", "/v1/person/1?hard=true", "", nil, http.StatusForbidden},
+		{"POST", "/v1/views", `{"name":"adults","filter":"age >= 18"}`, nil, http.StatusCreated},
+		{"POST", "/v1/views", `{"name":"adults","sort":"salary"}`, nil, http.StatusBadRequest},
+		{"GET", "/v1/views", "", nil, http.StatusOK},
+		{"GET", "/v1/views/adults", "", nil, http.StatusOK},
+		{"GET", "/v1/views/kids", "", nil, http.StatusNotFound},
+		{"GET", "/v1/views/adults/versions", "", nil, http.StatusOK},
+		{"GET", "/v1/views/adults/persons", "", nil, http.StatusOK},
+		{"POST", "/v1/webhooks", `{"url":"https://example.com/hook","events":["created"]}`, admin, http.StatusCreated},
+		{"POST", "/v1/webhooks", `{"url":"https://example.com/hook"}`, http.Header{"X-Admin-Token": {"wrong"}}, http.StatusForbidden},
+		{"GET", "/v1/webhooks", "", admin, http.StatusOK},
+		{"DELETE", "/v1/webhooks/3", "", admin, http.StatusNoContent},
+		{"DELETE", "/v1/webhooks/4", "", admin, http.StatusNotFound},
+		{"GET", "/v1/webhooks/3/deliveries?status=failed", "", admin, http.StatusOK},
+		{"POST", "/v1/webhooks/3/deliveries/1/replay", "", admin, http.StatusAccepted},
+		{"POST", "/v1/graphql", `{"query":"{ people(first: 5) { nodes { id name } totalCount } }"}`, nil, http.StatusOK},
+		{"POST", "/v1/graphql", `{"query":"{ people {"}`, nil, http.StatusBadRequest},
+		{"GET", "/openapi.json", "", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tt.method, tt.target, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+	}
+}
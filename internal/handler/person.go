@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"effective-mobile/internal/events"
 	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
 	"effective-mobile/internal/service"
 	"effective-mobile/pkg/logger"
 	"effective-mobile/pkg/validator"
@@ -13,25 +18,54 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	defaultBatchLimit = 500
+	maxActorLength    = 255
+)
+
 type PersonHandler struct {
-	service service.PersonServiceInterface
+	service    service.PersonServiceInterface
+	batchLimit int
+	adminToken string
+	events     *events.Broker
+
+	// subscribers holds a token per open live feed connection.
+	subscribers chan struct{}
 }
 
 func NewPersonHandler(s service.PersonServiceInterface) *PersonHandler {
-	return &PersonHandler{service: s}
+	return &PersonHandler{service: s, batchLimit: defaultBatchLimit}
+}
+
+// WithAdminToken sets the token that admin-only operations expect in the
+// X-Admin-Token header. Without it those operations are disabled.
+func (h *PersonHandler) WithAdminToken(token string) *PersonHandler {
+	h.adminToken = token
+	return h
+}
+
+// WithBatchLimit sets the maximum number of items accepted by CreatePersonsBatch.
+func (h *PersonHandler) WithBatchLimit(limit int) *PersonHandler {
+	h.batchLimit = limit
+	return h
 }
 
 // CreatePerson godoc
 // @Summary Создание человека
-// @Description Создаёт нового человека и обогащает его данными через внешние API
+// @Description Создаёт нового человека и обогащает его данными через внешние API. Если человек с таким же нормализованным ФИО уже есть, возвращает 409 со списком кандидатов, пока не передан force=true; проверка выполняется до обращения к внешним API
 // @Tags persons
 // @Accept json
 // @Produce json
+// @Param force query bool false "Создать, даже если найдены возможные дубликаты"
+// @Param Idempotency-Key header string false "Ключ идемпотентности для безопасных повторов"
+// @Param X-Actor header string false "Автор изменения"
 // @Param person body model.CreatePersonRequest true "Данные человека"
 // @Success 201 {object} model.Person
-// @Failure 400 {string} string "invalid JSON"
+// @Failure 400 {string} string "invalid JSON or force"
+// @Failure 409 {object} model.DuplicateConflict "person may already exist, or request with this Idempotency-Key is in progress"
+// @Failure 422 {string} string "Idempotency-Key was used with a different payload"
 // @Failure 500 {string} string "failed to create person"
-// @Router /person [post]
+// @Router /v1/person [post]
 func (h *PersonHandler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Debug("POST /person - received request")
 
@@ -47,30 +81,158 @@ func (h *PersonHandler) CreatePerson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if force := r.URL.Query().Get("force"); force != "" {
+		var err error
+		if req.Force, err = strconv.ParseBool(force); err != nil {
+			http.Error(w, "invalid force: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	logger.Log.Info("creating person", zap.String("name", req.Name), zap.String("surname", req.Surname))
-	person, err := h.service.CreatePerson(req)
+	person, err := h.service.CreatePerson(requestContext(r), req)
 	if err != nil {
+		var dup *service.DuplicateError
+		if errors.As(err, &dup) {
+			logger.Log.Info("possible duplicate person", zap.Uints("candidates", dup.Candidates))
+			writeJSON(w, model.DuplicateConflict{Error: "person may already exist, retry with force=true to create anyway", Candidates: dup.Candidates}, http.StatusConflict)
+			return
+		}
 		logger.Log.Error("failed to create person", zap.Error(err))
 		http.Error(w, "failed to create person: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	logger.Log.Info("person created", zap.Uint("id", person.ID))
+	w.Header().Set("ETag", etag(person))
 	writeJSON(w, person, http.StatusCreated)
 }
 
+// CreatePersonsBatch godoc
+// @Summary Пакетное создание людей
+// @Description Создаёт и обогащает несколько людей за один запрос. В режиме atomic (по умолчанию) ошибка любого элемента отменяет весь пакет, в режиме partial успешные элементы сохраняются
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param mode query string false "Режим вставки" Enums(atomic, partial)
+// @Param Idempotency-Key header string false "Ключ идемпотентности для безопасных повторов"
+// @Param persons body []model.CreatePersonRequest true "Список людей"
+// @Success 201 {array} model.BatchItemResult
+// @Success 207 {array} model.BatchItemResult
+// @Failure 400 {string} string "invalid JSON"
+// @Failure 413 {string} string "batch too large"
+// @Failure 422 {array} model.BatchItemResult
+// @Router /v1/person/batch [post]
+func (h *PersonHandler) CreatePersonsBatch(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("POST /person/batch - received request")
+
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "atomic" && mode != "partial" {
+		http.Error(w, "invalid mode: "+mode, http.StatusBadRequest)
+		return
+	}
+	atomic := mode != "partial"
+
+	var reqs []model.CreatePersonRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		logger.Log.Warn("failed to decode batch JSON", zap.Error(err))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(reqs) == 0 {
+		http.Error(w, "empty batch", http.StatusBadRequest)
+		return
+	}
+	if len(reqs) > h.batchLimit {
+		http.Error(w, "batch too large: limit is "+strconv.Itoa(h.batchLimit), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]model.BatchItemResult, len(reqs))
+	valid := make([]model.CreatePersonRequest, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		results[i].Index = i
+		if err := validator.Validate.Struct(req); err != nil {
+			results[i].Status = model.BatchItemInvalid
+			results[i].Error = "validation failed: " + err.Error()
+			continue
+		}
+		valid = append(valid, req)
+		indexes = append(indexes, i)
+	}
+
+	if atomic && len(valid) < len(reqs) {
+		for _, i := range indexes {
+			results[i].Status = model.BatchItemSkipped
+		}
+		logger.Log.Warn("batch rejected by validation", zap.Int("invalid", len(reqs)-len(valid)))
+		writeJSON(w, results, http.StatusUnprocessableEntity)
+		return
+	}
+
+	logger.Log.Info("creating persons batch", zap.Int("count", len(valid)), zap.Bool("atomic", atomic))
+	created, err := h.service.CreatePersons(requestContext(r), valid, atomic)
+	if err != nil && !errors.Is(err, service.ErrBatchAborted) {
+		logger.Log.Error("failed to create persons batch", zap.Error(err))
+		http.Error(w, "failed to create persons: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for n, i := range indexes {
+		created[n].Index = i
+		results[i] = created[n]
+	}
+	if err != nil {
+		logger.Log.Warn("batch aborted", zap.Error(err))
+		writeJSON(w, results, http.StatusUnprocessableEntity)
+		return
+	}
+
+	status := http.StatusCreated
+	createdCount := 0
+	for _, res := range results {
+		if res.Status == model.BatchItemCreated {
+			createdCount++
+		}
+	}
+	if createdCount < len(results) {
+		status = http.StatusMultiStatus
+	}
+
+	logger.Log.Info("persons batch processed", zap.Int("created", createdCount), zap.Int("total", len(results)))
+	writeJSON(w, results, status)
+}
+
 // GetAllPersons godoc
 // @Summary Получение всех людей
-// @Description Возвращает список всех сохранённых людей
+// @Description Возвращает список сохранённых людей с фильтрами и пагинацией
 // @Tags persons
 // @Produce json
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
 // @Success 200 {array} model.Person
+// @Failure 400 {string} string "invalid filter"
 // @Failure 500 {string} string "failed to get persons"
-// @Router /person [get]
+// @Router /v1/person [get]
 func (h *PersonHandler) GetAllPersons(w http.ResponseWriter, r *http.Request) {
-	logger.Log.Debug("GET /person - listing all persons")
+	logger.Log.Debug("GET /person - listing persons", zap.String("query", r.URL.RawQuery))
+
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	people, err := h.service.GetAllPersons()
+	people, err := h.service.GetAllPersons(requestContext(r), filter)
 	if err != nil {
 		logger.Log.Error("failed to get persons", zap.Error(err))
 		http.Error(w, "failed to get persons: "+err.Error(), http.StatusInternalServerError)
@@ -87,10 +249,12 @@ func (h *PersonHandler) GetAllPersons(w http.ResponseWriter, r *http.Request) {
 // @Tags persons
 // @Produce json
 // @Param id path int true "ID человека"
+// @Param If-None-Match header string false "ETag известной версии"
 // @Success 200 {object} model.Person
+// @Success 304 {string} string "not modified"
 // @Failure 400 {string} string "invalid ID"
 // @Failure 404 {string} string "person not found"
-// @Router /person/{id} [get]
+// @Router /v1/person/{id} [get]
 func (h *PersonHandler) GetPersonByID(w http.ResponseWriter, r *http.Request) {
 	idString := r.PathValue("id")
 	id, err := strconv.ParseUint(idString, 10, 32)
@@ -101,13 +265,21 @@ func (h *PersonHandler) GetPersonByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger.Log.Debug("fetching person", zap.Uint("id", uint(id)))
-	person, err := h.service.GetPersonByID(uint(id))
+	person, err := h.service.GetPersonByID(requestContext(r), uint(id))
 	if err != nil {
 		logger.Log.Warn("person not found", zap.Uint("id", uint(id)))
 		http.Error(w, "person not found", http.StatusNotFound)
 		return
 	}
 
+	tag := etag(person)
+	w.Header().Set("ETag", tag)
+	if etagMatches(r.Header.Get("If-None-Match"), tag) {
+		logger.Log.Debug("person not modified", zap.Uint("id", uint(id)))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	logger.Log.Info("person fetched", zap.Uint("id", uint(id)))
 	writeJSON(w, person, http.StatusOK)
 }
@@ -119,11 +291,16 @@ func (h *PersonHandler) GetPersonByID(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path int true "ID человека"
+// @Param If-Match header string false "ETag ожидаемой версии"
+// @Param X-Actor header string false "Автор изменения"
 // @Param person body model.UpdatePersonRequest true "Обновлённые данные"
 // @Success 200 {object} model.Person
 // @Failure 400 {string} string "invalid ID or JSON"
+// @Failure 404 {string} string "person not found"
+// @Failure 412 {string} string "version mismatch"
 // @Failure 500 {string} string "failed to update"
-// @Router /person/{id} [put]
+// @Router /v1/person/{id} [put]
+// @Router /v1/person/{id} [patch]
 func (h *PersonHandler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 	idString := r.PathValue("id")
 	id, err := strconv.ParseUint(idString, 10, 32)
@@ -133,33 +310,58 @@ func (h *PersonHandler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		logger.Log.Warn("invalid If-Match", zap.String("if_match", r.Header.Get("If-Match")))
+		http.Error(w, "invalid If-Match", http.StatusBadRequest)
+		return
+	}
+
 	var req model.UpdatePersonRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Log.Warn("failed to decode update JSON", zap.Error(err))
 		http.Error(w, "invalid JSON", http.StatusBadRequest)
 		return
 	}
+	req.Version = version
 
 	logger.Log.Info("updating person", zap.Uint("id", uint(id)))
-	person, err := h.service.UpdatePerson(uint(id), req)
+	person, err := h.service.UpdatePerson(requestContext(r), uint(id), req)
 	if err != nil {
-		logger.Log.Error("failed to update person", zap.Error(err))
-		http.Error(w, "failed to update: "+err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			logger.Log.Warn("person not found", zap.Uint("id", uint(id)))
+			http.Error(w, "person not found", http.StatusNotFound)
+		case errors.Is(err, repository.ErrVersionConflict):
+			logger.Log.Warn("version mismatch", zap.Uint("id", uint(id)), zap.Uint("version", version))
+			http.Error(w, "version mismatch", http.StatusPreconditionFailed)
+		default:
+			logger.Log.Error("failed to update person", zap.Error(err))
+			http.Error(w, "failed to update: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
+	w.Header().Set("ETag", etag(person))
 	writeJSON(w, person, http.StatusOK)
 }
 
 // DeletePerson godoc
 // @Summary Удаление человека
-// @Description Удаляет человека по ID
+// @Description Помещает человека в корзину по ID. С hard=true удаляет его безвозвратно (только для администратора)
 // @Tags persons
 // @Param id path int true "ID человека"
+// @Param hard query bool false "Удалить безвозвратно"
+// @Param If-Match header string false "ETag ожидаемой версии"
+// @Param X-Actor header string false "Автор изменения"
+// @Param X-Admin-Token header string false "Токен администратора для hard=true"
 // @Success 204 {string} string "no content"
 // @Failure 400 {string} string "invalid ID"
+// @Failure 403 {string} string "admin token required"
+// @Failure 404 {string} string "person not found"
+// @Failure 412 {string} string "version mismatch"
 // @Failure 500 {string} string "failed to delete"
-// @Router /person/{id} [delete]
+// @Router /v1/person/{id} [delete]
 func (h *PersonHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
 	idString := r.PathValue("id")
 	id, err := strconv.ParseUint(idString, 10, 32)
@@ -169,11 +371,39 @@ func (h *PersonHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if hard := r.URL.Query().Get("hard"); hard != "" {
+		purge, err := strconv.ParseBool(hard)
+		if err != nil {
+			http.Error(w, "invalid hard: "+hard, http.StatusBadRequest)
+			return
+		}
+		if purge {
+			h.purgePerson(w, r, uint(id))
+			return
+		}
+	}
+
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		logger.Log.Warn("invalid If-Match", zap.String("if_match", r.Header.Get("If-Match")))
+		http.Error(w, "invalid If-Match", http.StatusBadRequest)
+		return
+	}
+
 	logger.Log.Info("deleting person", zap.Uint("id", uint(id)))
-	err = h.service.DeletePerson(uint(id))
+	err = h.service.DeletePerson(requestContext(r), uint(id), version)
 	if err != nil {
-		logger.Log.Error("failed to delete person", zap.Error(err))
-		http.Error(w, "failed to delete: "+err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			logger.Log.Warn("person not found", zap.Uint("id", uint(id)))
+			http.Error(w, "person not found", http.StatusNotFound)
+		case errors.Is(err, repository.ErrVersionConflict):
+			logger.Log.Warn("version mismatch", zap.Uint("id", uint(id)), zap.Uint("version", version))
+			http.Error(w, "version mismatch", http.StatusPreconditionFailed)
+		default:
+			logger.Log.Error("failed to delete person", zap.Error(err))
+			http.Error(w, "failed to delete: "+err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -181,6 +411,136 @@ func (h *PersonHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
 	logger.Log.Info("person deleted", zap.Uint("id", uint(id)))
 }
 
+// UpdatePersonsByFilter godoc
+// @Summary Массовое обновление людей
+// @Description Обновляет всех людей, подходящих под фильтр. Сначала нужно выполнить запрос с dry_run=true, чтобы узнать количество и получить confirm_token
+// @Tags persons
+// @Accept json
+// @Produce json
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Param dry_run query bool false "Только подсчитать совпадения"
+// @Param confirm query string false "Токен подтверждения из dry run"
+// @Param person body model.UpdatePersonRequest true "Новые значения полей"
+// @Success 200 {object} model.BulkResult
+// @Failure 400 {string} string "invalid filter or JSON"
+// @Failure 409 {string} string "confirmation token does not match the current selection"
+// @Failure 428 {string} string "confirmation token required"
+// @Failure 500 {string} string "failed to update"
+// @Router /v1/person [patch]
+func (h *PersonHandler) UpdatePersonsByFilter(w http.ResponseWriter, r *http.Request) {
+	filter, dryRun, ok := parseBulkQuery(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.UpdatePersonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log.Warn("failed to decode bulk update JSON", zap.Error(err))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	logger.Log.Info("bulk updating persons", zap.String("query", r.URL.RawQuery), zap.Bool("dry_run", dryRun))
+	result, err := h.service.UpdatePersons(requestContext(r), filter, req, dryRun, r.URL.Query().Get("confirm"))
+	if err != nil {
+		writeBulkError(w, "update", err)
+		return
+	}
+
+	logger.Log.Info("bulk update done", zap.Int64("matched", result.Matched), zap.Int64("affected", result.Affected))
+	writeJSON(w, result, http.StatusOK)
+}
+
+// DeletePersonsByFilter godoc
+// @Summary Массовое удаление людей
+// @Description Удаляет всех людей, подходящих под фильтр. Сначала нужно выполнить запрос с dry_run=true, чтобы узнать количество и получить confirm_token
+// @Tags persons
+// @Produce json
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Param dry_run query bool false "Только подсчитать совпадения"
+// @Param confirm query string false "Токен подтверждения из dry run"
+// @Success 200 {object} model.BulkResult
+// @Failure 400 {string} string "invalid filter"
+// @Failure 409 {string} string "confirmation token does not match the current selection"
+// @Failure 428 {string} string "confirmation token required"
+// @Failure 500 {string} string "failed to delete"
+// @Router /v1/person [delete]
+func (h *PersonHandler) DeletePersonsByFilter(w http.ResponseWriter, r *http.Request) {
+	filter, dryRun, ok := parseBulkQuery(w, r)
+	if !ok {
+		return
+	}
+
+	logger.Log.Info("bulk deleting persons", zap.String("query", r.URL.RawQuery), zap.Bool("dry_run", dryRun))
+	result, err := h.service.DeletePersons(requestContext(r), filter, dryRun, r.URL.Query().Get("confirm"))
+	if err != nil {
+		writeBulkError(w, "delete", err)
+		return
+	}
+
+	logger.Log.Info("bulk delete done", zap.Int64("matched", result.Matched), zap.Int64("affected", result.Affected))
+	writeJSON(w, result, http.StatusOK)
+}
+
+func parseBulkQuery(w http.ResponseWriter, r *http.Request) (model.PersonFilter, bool, bool) {
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return filter, false, false
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid dry_run: "+v, http.StatusBadRequest)
+			return filter, false, false
+		}
+	}
+	return filter, dryRun, true
+}
+
+func writeBulkError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmptyFilter), errors.Is(err, service.ErrEmptyUpdate):
+		logger.Log.Warn("bulk "+op+" rejected", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrConfirmationRequired):
+		http.Error(w, err.Error(), http.StatusPreconditionRequired)
+	case errors.Is(err, service.ErrConfirmationMismatch):
+		logger.Log.Warn("bulk "+op+" confirmation mismatch", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		logger.Log.Error("failed to bulk "+op+" persons", zap.Error(err))
+		http.Error(w, "failed to "+op+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// requestContext returns the request context carrying the actor from the
+// X-Actor header, so that changes are attributed in the person history.
+func requestContext(r *http.Request) context.Context {
+	actor := strings.TrimSpace(r.Header.Get("X-Actor"))
+	if len(actor) > maxActorLength {
+		actor = actor[:maxActorLength]
+	}
+	return service.WithActor(r.Context(), actor)
+}
+
 func writeJSON(w http.ResponseWriter, data any, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -2,14 +2,25 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"effective-mobile/internal/events"
 	"effective-mobile/internal/handler"
 	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
 	"effective-mobile/pkg/logger"
+
+	"github.com/gorilla/websocket"
 )
 
 func init() {
@@ -18,7 +29,10 @@ func init() {
 
 type mockPersonService struct{}
 
-func (m *mockPersonService) CreatePerson(req model.CreatePersonRequest) (*model.Person, error) {
+func (m *mockPersonService) CreatePerson(ctx context.Context, req model.CreatePersonRequest) (*model.Person, error) {
+	if req.Name == "Duplicate" && !req.Force {
+		return nil, &service.DuplicateError{Candidates: []uint{4, 5}}
+	}
 	return &model.Person{
 		ID:          1,
 		Name:        req.Name,
@@ -29,24 +43,189 @@ func (m *mockPersonService) CreatePerson(req model.CreatePersonRequest) (*model.
 	}, nil
 }
 
-func (m *mockPersonService) GetAllPersons() ([]model.Person, error) {
+func (m *mockPersonService) CreatePersons(ctx context.Context, reqs []model.CreatePersonRequest, atomic bool) ([]model.BatchItemResult, error) {
+	results := make([]model.BatchItemResult, len(reqs))
+	for i, req := range reqs {
+		results[i] = model.BatchItemResult{
+			Index:  i,
+			Status: model.BatchItemCreated,
+			Person: &model.Person{ID: uint(i + 1), Name: req.Name, Surname: req.Surname},
+		}
+	}
+	return results, nil
+}
+
+func (m *mockPersonService) ImportPersons(ctx context.Context, rows []model.ImportRow) []model.ImportRowResult {
+	results := make([]model.ImportRowResult, len(rows))
+	for i, row := range rows {
+		results[i] = model.ImportRowResult{Line: row.Line, Status: model.ImportRowAccepted, ID: uint(i + 1)}
+	}
+	return results
+}
+
+func (m *mockPersonService) GetAllPersons(ctx context.Context, filter model.PersonFilter) ([]model.Person, error) {
 	return []model.Person{
 		{ID: 1, Name: "Alice"},
 	}, nil
 }
 
-func (m *mockPersonService) GetPersonByID(id uint) (*model.Person, error) {
-	return &model.Person{ID: id, Name: "Alice"}, nil
+func (m *mockPersonService) CountPersons(ctx context.Context, filter model.PersonFilter) (int64, error) {
+	return 1, nil
+}
+
+func (m *mockPersonService) ExportPersons(ctx context.Context, filter model.PersonFilter, fn func(p *model.Person) error) error {
+	if filter.Name == "Broken" {
+		return errors.New("connection refused")
+	}
+	for _, p := range []model.Person{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Bob"}} {
+		if err := fn(&p); err != nil {
+			return err
+		}
+		if filter.Name == "Interrupted" {
+			return errors.New("connection reset")
+		}
+	}
+	return nil
+}
+
+func (m *mockPersonService) SearchPersons(ctx context.Context, query string, match string, filter model.PersonFilter) ([]model.PersonSearchResult, error) {
+	return []model.PersonSearchResult{
+		{Person: model.Person{ID: 1, Name: "Dmitriy", Surname: "Ushakov"}, Score: 0.8},
+	}, nil
+}
+
+func (m *mockPersonService) GetPersonStats(ctx context.Context, filter model.PersonFilter, opts model.StatsOptions) (*model.PersonStats, error) {
+	return &model.PersonStats{Total: 2, AgeHistogram: make([]model.AgeBucket, len(opts.AgeBuckets)+1)}, nil
+}
+
+func (m *mockPersonService) FindDuplicates(ctx context.Context, filter model.PersonFilter) ([]model.DuplicateCluster, error) {
+	return []model.DuplicateCluster{
+		{ID: 1, Persons: []model.Person{{ID: 1, Name: "Alice"}, {ID: 3, Name: "Alice"}}},
+	}, nil
+}
+
+func (m *mockPersonService) MergePersons(ctx context.Context, req model.MergeRequest) (*model.MergeResult, error) {
+	if req.TargetID == 404 {
+		return nil, repository.ErrNotFound
+	}
+	return &model.MergeResult{Person: model.Person{ID: req.TargetID, Version: 3}, MergedIDs: req.SourceIDs}, nil
+}
+
+func (m *mockPersonService) SaveView(ctx context.Context, req model.SaveViewRequest) (*model.View, error) {
+	if req.Sort == "salary" {
+		return nil, fmt.Errorf("%w: can't sort by %q", service.ErrInvalidView, req.Sort)
+	}
+	return &model.View{Name: req.Name, Version: 2, Filter: req.Filter, Sort: req.Sort, Columns: req.Columns}, nil
+}
+
+func (m *mockPersonService) GetViews(ctx context.Context) ([]model.View, error) {
+	return []model.View{{Name: "adults", Version: 2}}, nil
+}
+
+func (m *mockPersonService) GetView(ctx context.Context, name string, version uint) (*model.View, error) {
+	if name != "adults" {
+		return nil, repository.ErrNotFound
+	}
+	return &model.View{Name: name, Version: 2, Columns: []string{"id", "surname"}}, nil
+}
+
+func (m *mockPersonService) GetViewVersions(ctx context.Context, name string) ([]model.View, error) {
+	return []model.View{{Name: name, Version: 1}, {Name: name, Version: 2}}, nil
+}
+
+func (m *mockPersonService) GetViewPersons(ctx context.Context, name string, version uint, limit, offset int) (*model.View, []model.Person, error) {
+	view, err := m.GetView(ctx, name, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return view, []model.Person{{ID: 1, Name: "Dmitriy", Surname: "Ushakov", Age: 40}}, nil
+}
+
+func (m *mockPersonService) GetPersonByID(ctx context.Context, id uint) (*model.Person, error) {
+	return &model.Person{ID: id, Name: "Alice", Version: 2}, nil
+}
+
+func (m *mockPersonService) UpdatePerson(ctx context.Context, id uint, req model.UpdatePersonRequest) (*model.Person, error) {
+	if req.Version != 0 && req.Version != 2 {
+		return nil, repository.ErrVersionConflict
+	}
+	return &model.Person{ID: id, Name: req.Name, Version: 3}, nil
+}
+
+func (m *mockPersonService) DeletePerson(ctx context.Context, id uint, version uint) error {
+	if version != 0 && version != 2 {
+		return repository.ErrVersionConflict
+	}
+	return nil
+}
+
+func (m *mockPersonService) UpdatePersons(ctx context.Context, filter model.PersonFilter, update model.UpdatePersonRequest, dryRun bool, confirm string) (*model.BulkResult, error) {
+	if dryRun {
+		return &model.BulkResult{Matched: 2, DryRun: true, ConfirmToken: "token"}, nil
+	}
+	if confirm == "" {
+		return nil, service.ErrConfirmationRequired
+	}
+	return &model.BulkResult{Matched: 2, Affected: 2}, nil
+}
+
+func (m *mockPersonService) DeletePersons(ctx context.Context, filter model.PersonFilter, dryRun bool, confirm string) (*model.BulkResult, error) {
+	if dryRun {
+		return &model.BulkResult{Matched: 2, DryRun: true, ConfirmToken: "token"}, nil
+	}
+	if confirm == "" {
+		return nil, service.ErrConfirmationRequired
+	}
+	return &model.BulkResult{Matched: 2, Affected: 2}, nil
 }
 
-func (m *mockPersonService) UpdatePerson(id uint, req model.UpdatePersonRequest) (*model.Person, error) {
-	return &model.Person{ID: id, Name: req.Name}, nil
+func (m *mockPersonService) GetDeletedPersons(ctx context.Context, filter model.PersonFilter) ([]model.DeletedPerson, error) {
+	return []model.DeletedPerson{{
+		Person:    model.Person{ID: 3, Name: "Deleted"},
+		DeletedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}}, nil
 }
 
-func (m *mockPersonService) DeletePerson(id uint) error {
+func (m *mockPersonService) RestorePerson(ctx context.Context, id uint) (*model.Person, error) {
+	if id != 3 {
+		return nil, repository.ErrNotFound
+	}
+	return &model.Person{ID: id, Name: "Deleted", Version: 2}, nil
+}
+
+func (m *mockPersonService) PurgePerson(ctx context.Context, id uint) error {
 	return nil
 }
 
+func (m *mockPersonService) ReenrichPerson(ctx context.Context, id uint) (*model.Person, error) {
+	return &model.Person{ID: id, Name: "Alice", Gender: "female", Version: 2}, nil
+}
+
+func (m *mockPersonService) GetChanges(ctx context.Context, token string, limit int) (*model.PersonChanges, error) {
+	if token == "bad" {
+		return nil, service.ErrInvalidChangeToken
+	}
+	return &model.PersonChanges{
+		Changed: []model.Person{{ID: 1, Name: "Alice", Version: 2}},
+		Deleted: []model.PersonTombstone{{PersonID: 3}},
+		Token:   "12",
+		HasMore: limit == 2,
+	}, nil
+}
+
+func (m *mockPersonService) GetPersonHistory(ctx context.Context, id uint) ([]model.PersonHistory, error) {
+	return []model.PersonHistory{
+		{ID: 1, PersonID: id, Revision: 1, Action: model.HistoryActionCreate, Source: model.HistorySourceAPI, Actor: service.ActorFrom(ctx)},
+	}, nil
+}
+
+func (m *mockPersonService) RollbackPerson(ctx context.Context, id uint, revision uint) (*model.Person, error) {
+	if revision > 2 {
+		return nil, repository.ErrNotFound
+	}
+	return &model.Person{ID: id, Name: "Alice", Version: 3}, nil
+}
+
 func TestCreatePersonHandler(t *testing.T) {
 	svc := &mockPersonService{}
 	h := handler.NewPersonHandler(svc)
@@ -141,3 +320,646 @@ func TestDeletePersonHandler(t *testing.T) {
 		t.Fatalf("expected 204 No Content, got %d", rec.Result().StatusCode)
 	}
 }
+
+func TestGetPersonByIDHandler_NotModified(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /person/{id}", h.GetPersonByID)
+
+	req := httptest.NewRequest(http.MethodGet, "/person/1", nil)
+	req.Header.Set("If-None-Match", `"2"`)
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304 Not Modified, got %d", rec.Result().StatusCode)
+	}
+	if rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected ETag \"2\", got %q", rec.Header().Get("ETag"))
+	}
+}
+
+func TestUpdatePersonHandler_IfMatchMismatch(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /person/{id}", h.UpdatePerson)
+
+	body, _ := json.Marshal(model.UpdatePersonRequest{Name: "UpdatedName"})
+
+	req := httptest.NewRequest(http.MethodPut, "/person/1", bytes.NewReader(body))
+	req.Header.Set("If-Match", `"1"`)
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 Precondition Failed, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestDeletePersonHandler_IfMatch(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /person/{id}", h.DeletePerson)
+
+	req := httptest.NewRequest(http.MethodDelete, "/person/1", nil)
+	req.Header.Set("If-Match", `"2"`)
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 No Content, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestCreatePersonsBatchHandler_Partial(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	body, _ := json.Marshal([]model.CreatePersonRequest{
+		{Name: "Alice", Surname: "Smith"},
+		{Name: "Bob"},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/person/batch?mode=partial", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	h.CreatePersonsBatch(rec, req)
+
+	if rec.Result().StatusCode != http.StatusMultiStatus {
+		t.Fatalf("expected 207 Multi-Status, got %d", rec.Result().StatusCode)
+	}
+
+	var results []model.BatchItemResult
+	json.NewDecoder(rec.Body).Decode(&results)
+	if len(results) != 2 || results[0].Status != model.BatchItemCreated || results[1].Status != model.BatchItemInvalid {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestCreatePersonsBatchHandler_TooLarge(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc).WithBatchLimit(1)
+
+	body, _ := json.Marshal([]model.CreatePersonRequest{
+		{Name: "Alice", Surname: "Smith"},
+		{Name: "Bob", Surname: "Smith"},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/person/batch", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	h.CreatePersonsBatch(rec, req)
+
+	if rec.Result().StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 Request Entity Too Large, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestGetAllPersonsHandler_InvalidFilter(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/person?age_min=40&age_max=30", nil)
+	rec := httptest.NewRecorder()
+
+	h.GetAllPersons(rec, req)
+
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 Bad Request, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestDeletePersonsByFilterHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodDelete, "/person?surname=Test", nil)
+	rec := httptest.NewRecorder()
+	h.DeletePersonsByFilter(rec, req)
+	if rec.Result().StatusCode != http.StatusPreconditionRequired {
+		t.Fatalf("expected 428 Precondition Required, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/person?surname=Test&dry_run=true", nil)
+	rec = httptest.NewRecorder()
+	h.DeletePersonsByFilter(rec, req)
+
+	var preview model.BulkResult
+	json.NewDecoder(rec.Body).Decode(&preview)
+	if rec.Result().StatusCode != http.StatusOK || preview.Matched != 2 || preview.ConfirmToken == "" {
+		t.Fatalf("unexpected dry run response %d %+v", rec.Result().StatusCode, preview)
+	}
+}
+
+func TestImportPersonsHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	csv := "name,surname,patronymic,age\n" +
+		"Dmitriy,Ushakov,Vasilevich,\n" +
+		"Anna,,,\n" +
+		"Ivan,Petrov,,abc\n" +
+		"Olga,Ivanova,,31\n"
+
+	req := httptest.NewRequest(http.MethodPost, "/person/import", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	rec := httptest.NewRecorder()
+
+	h.ImportPersons(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d: %s", rec.Result().StatusCode, rec.Body)
+	}
+
+	var report model.ImportReport
+	json.NewDecoder(rec.Body).Decode(&report)
+	if report.Accepted != 2 || report.Rejected != 2 {
+		t.Fatalf("expected 2 accepted and 2 rejected rows, got %+v", report)
+	}
+
+	rejected := map[int]bool{}
+	for _, row := range report.Rows {
+		if row.Status == model.ImportRowRejected {
+			rejected[row.Line] = true
+		}
+	}
+	if !rejected[3] || !rejected[4] {
+		t.Fatalf("expected lines 3 and 4 to be rejected, got %+v", report.Rows)
+	}
+}
+
+func TestExportPersonsHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	for format, contentType := range map[string]string{
+		"csv":    "text/csv; charset=utf-8",
+		"ndjson": "application/x-ndjson",
+		"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/person/export?format="+format, nil)
+		rec := httptest.NewRecorder()
+
+		h.ExportPersons(rec, req)
+
+		if rec.Result().StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200 OK, got %d", format, rec.Result().StatusCode)
+		}
+		if got := rec.Header().Get("Content-Type"); got != contentType {
+			t.Fatalf("%s: expected Content-Type %q, got %q", format, contentType, got)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/person/export?format=csv", nil)
+	rec := httptest.NewRecorder()
+	h.ExportPersons(rec, req)
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[2], "2,Bob,") {
+		t.Fatalf("unexpected CSV export: %q", rec.Body.String())
+	}
+}
+
+func TestExportPersonsHandler_Failure(t *testing.T) {
+	h := handler.NewPersonHandler(&mockPersonService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/person/export?format=ndjson&name=Broken", nil)
+	rec := httptest.NewRecorder()
+	h.ExportPersons(rec, req)
+
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Disposition") != "" {
+		t.Fatalf("expected 500 before anything was sent, got %d %v", rec.Code, rec.Header())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/person/export?format=ndjson&name=Interrupted", nil)
+	rec = httptest.NewRecorder()
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("expected the connection to be aborted after the first row, got %v", p)
+		}
+	}()
+	h.ExportPersons(rec, req)
+}
+
+func TestDeletePersonHandler_HardRequiresAdmin(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc).WithAdminToken("secret")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /person/{id}", h.DeletePerson)
+
+	req := httptest.NewRequest(http.MethodDelete, "/person/1?hard=true", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Result().StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 Forbidden, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/person/1?hard=true", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Result().StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 No Content, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestGetTrashHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/person/trash", nil)
+	rec := httptest.NewRecorder()
+	h.GetTrash(rec, req)
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", rec.Result().StatusCode)
+	}
+
+	var people []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&people); err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 1 || people[0]["deleted_at"] != "2024-01-02T03:04:05Z" {
+		t.Errorf("expected deleted_at in the trash listing, got %v", people)
+	}
+}
+
+func TestRestorePersonHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /person/{id}/restore", h.RestorePerson)
+
+	req := httptest.NewRequest(http.MethodPost, "/person/3/restore", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/person/1/restore", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 Not Found, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestGetPersonHistoryHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /person/{id}/history", h.GetPersonHistory)
+
+	req := httptest.NewRequest(http.MethodGet, "/person/1/history", nil)
+	req.Header.Set("X-Actor", "hr-bot")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var entries []model.PersonHistory
+	json.NewDecoder(rec.Body).Decode(&entries)
+	if rec.Result().StatusCode != http.StatusOK || len(entries) != 1 || entries[0].Actor != "hr-bot" {
+		t.Fatalf("unexpected history response %d %+v", rec.Result().StatusCode, entries)
+	}
+}
+
+func TestRollbackPersonHandler_UnknownRevision(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /person/{id}/rollback", h.RollbackPerson)
+
+	req := httptest.NewRequest(http.MethodPost, "/person/1/rollback", strings.NewReader(`{"revision": 7}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 Not Found, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestSearchPersonsHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/person/search", nil)
+	rec := httptest.NewRecorder()
+	h.SearchPersons(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 Bad Request without q, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/person/search?q=Ushakof&limit=5", nil)
+	rec = httptest.NewRecorder()
+	h.SearchPersons(rec, req)
+
+	var results []map[string]any
+	json.NewDecoder(rec.Body).Decode(&results)
+	if rec.Result().StatusCode != http.StatusOK || len(results) != 1 || results[0]["surname"] != "Ushakov" || results[0]["score"] != 0.8 {
+		t.Fatalf("unexpected search response %d %v", rec.Result().StatusCode, results)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/person/search?q=Ushakof&match=soundex", nil)
+	rec = httptest.NewRecorder()
+	h.SearchPersons(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 Bad Request for unknown match mode, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestCreatePersonHandler_Duplicate(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	body, _ := json.Marshal(model.CreatePersonRequest{Name: "Duplicate", Surname: "Smith"})
+	req := httptest.NewRequest(http.MethodPost, "/person", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.CreatePerson(rec, req)
+
+	var conflict model.DuplicateConflict
+	json.NewDecoder(rec.Body).Decode(&conflict)
+	if rec.Result().StatusCode != http.StatusConflict || len(conflict.Candidates) != 2 {
+		t.Fatalf("expected 409 with candidates, got %d %+v", rec.Result().StatusCode, conflict)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/person?force=true", bytes.NewReader(body))
+	rec = httptest.NewRecorder()
+	h.CreatePerson(rec, req)
+	if rec.Result().StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 Created with force, got %d", rec.Result().StatusCode)
+	}
+}
+
+func TestFindDuplicatesHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/person/duplicates?limit=10", nil)
+	rec := httptest.NewRecorder()
+	h.FindDuplicates(rec, req)
+
+	var clusters []model.DuplicateCluster
+	json.NewDecoder(rec.Body).Decode(&clusters)
+	if rec.Result().StatusCode != http.StatusOK || len(clusters) != 1 || len(clusters[0].Persons) != 2 {
+		t.Fatalf("unexpected duplicates response %d %+v", rec.Result().StatusCode, clusters)
+	}
+}
+
+func TestMergePersonsHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/person/merge", strings.NewReader(`{"target_id": 1}`))
+	rec := httptest.NewRecorder()
+	h.MergePersons(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 Bad Request without sources, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/person/merge", strings.NewReader(`{"target_id": 404, "source_ids": [2]}`))
+	rec = httptest.NewRecorder()
+	h.MergePersons(rec, req)
+	if rec.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 Not Found, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/person/merge", strings.NewReader(`{"target_id": 1, "source_ids": [2], "fields": {"age": 2}}`))
+	rec = httptest.NewRecorder()
+	h.MergePersons(rec, req)
+	if rec.Result().StatusCode != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Fatalf("expected 200 OK with ETag, got %d %q", rec.Result().StatusCode, rec.Header().Get("ETag"))
+	}
+}
+
+func TestGetPersonStatsHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	for _, query := range []string{"age_buckets=30,18", "age_buckets=18,x", "top=0"} {
+		req := httptest.NewRequest(http.MethodGet, "/person/stats?"+query, nil)
+		rec := httptest.NewRecorder()
+		h.GetPersonStats(rec, req)
+		if rec.Result().StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected 400 Bad Request for %s, got %d", query, rec.Result().StatusCode)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/person/stats?age_buckets=18,30,60&gender=female", nil)
+	rec := httptest.NewRecorder()
+	h.GetPersonStats(rec, req)
+
+	var stats model.PersonStats
+	json.NewDecoder(rec.Body).Decode(&stats)
+	if rec.Result().StatusCode != http.StatusOK || stats.Total != 2 || len(stats.AgeHistogram) != 4 {
+		t.Fatalf("unexpected stats response %d %+v", rec.Result().StatusCode, stats)
+	}
+}
+
+func TestGetAllPersonsHandler_FilterExpression(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/person?filter="+url.QueryEscape(`age >= 30 and (nationality in ("RU","KZ") or gender = "female")`), nil)
+	rec := httptest.NewRecorder()
+	h.GetAllPersons(rec, req)
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/person?filter="+url.QueryEscape(`age >= "old"`), nil)
+	rec = httptest.NewRecorder()
+	h.GetAllPersons(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "position 8") {
+		t.Fatalf("expected 400 Bad Request with position, got %d %q", rec.Result().StatusCode, rec.Body.String())
+	}
+}
+
+func TestSaveViewHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`{"name": "adults", "sort": "salary"}`))
+	rec := httptest.NewRecorder()
+	h.SaveView(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 Bad Request for invalid sort, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`{"name": "adults", "filter": "age >= 18", "sort": "-age"}`))
+	rec = httptest.NewRecorder()
+	h.SaveView(rec, req)
+	if rec.Result().StatusCode != http.StatusCreated || rec.Header().Get("Location") != "/views/adults?version=2" {
+		t.Fatalf("expected 201 Created with Location, got %d %q", rec.Result().StatusCode, rec.Header().Get("Location"))
+	}
+}
+
+func TestGetViewPersonsHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /views/{name}/persons", h.GetViewPersons)
+
+	req := httptest.NewRequest(http.MethodGet, "/views/missing/persons", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 Not Found, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/views/adults/persons?limit=10", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	var rows []map[string]any
+	json.NewDecoder(rec.Body).Decode(&rows)
+	if rec.Result().StatusCode != http.StatusOK || rec.Header().Get("X-View-Version") != "2" {
+		t.Fatalf("expected 200 OK with X-View-Version, got %d %q", rec.Result().StatusCode, rec.Header().Get("X-View-Version"))
+	}
+	if len(rows) != 1 || len(rows[0]) != 2 || rows[0]["surname"] != "Ushakov" {
+		t.Fatalf("expected only view columns, got %v", rows)
+	}
+}
+
+func TestStreamEventsHandler_ResumesFiltered(t *testing.T) {
+	broker := events.NewBroker(10)
+	h := handler.NewPersonHandler(&mockPersonService{}).WithEvents(broker)
+
+	probe, _, _ := broker.Subscribe(0)
+	broker.Publish(
+		model.PersonEvent{Type: model.EventCreated, PersonID: 1, Person: model.PersonSnapshot{Name: "Dmitriy", Nationality: "RU"}},
+		model.PersonEvent{Type: model.EventCreated, PersonID: 2, Person: model.PersonSnapshot{Name: "John", Nationality: "US"}},
+		model.PersonEvent{Type: model.EventUpdated, PersonID: 1, Person: model.PersonSnapshot{Name: "Dmitriy", Nationality: "RU", Age: 30}},
+	)
+	first := <-probe.C
+	probe.Close()
+
+	// A cancelled request returns right after the replay.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/person/events?nationality=RU", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", fmt.Sprint(first.ID))
+	rec := httptest.NewRecorder()
+	h.StreamEvents(rec, req)
+
+	if rec.Result().StatusCode != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected 200 OK event stream, got %d %q", rec.Result().StatusCode, rec.Header().Get("Content-Type"))
+	}
+	want := fmt.Sprintf("id: %d\n\nid: %d\nevent: updated\ndata: ", first.ID+1, first.ID+2)
+	if body := rec.Body.String(); !strings.HasPrefix(body, want) || strings.Contains(body, "reset") {
+		t.Fatalf("expected filtered replay after Last-Event-ID, got %q", body)
+	}
+}
+
+func TestStreamEventsHandler_ResetWhenBacklogExpired(t *testing.T) {
+	broker := events.NewBroker(1)
+	h := handler.NewPersonHandler(&mockPersonService{}).WithEvents(broker)
+
+	probe, _, _ := broker.Subscribe(0)
+	broker.Publish(model.PersonEvent{Type: model.EventCreated, PersonID: 1}, model.PersonEvent{Type: model.EventCreated, PersonID: 2}, model.PersonEvent{Type: model.EventDeleted, PersonID: 1})
+	first := <-probe.C
+	probe.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/person/events?last_event_id=%d", first.ID), nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	h.StreamEvents(rec, req)
+
+	if body := rec.Body.String(); !strings.HasPrefix(body, "event: reset\n") || !strings.Contains(body, "event: deleted") {
+		t.Fatalf("expected reset before the buffered events, got %q", body)
+	}
+}
+
+func TestStreamEventsWSHandler(t *testing.T) {
+	broker := events.NewBroker(10)
+	h := handler.NewPersonHandler(&mockPersonService{}).WithEvents(broker).WithSubscriberLimit(1)
+	srv := httptest.NewServer(http.HandlerFunc(h.StreamEventsWS))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// The limit of one subscriber is taken by the open connection.
+	if _, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil); err == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 over the subscriber limit, got %v", err)
+	}
+
+	read := func() model.StreamMessage {
+		t.Helper()
+		var msg model.StreamMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	conn.WriteJSON(model.StreamCommand{Type: model.StreamSubscribe, ID: "bad", Filter: "salary > 1"})
+	if msg := read(); msg.Type != model.StreamError || msg.ID != "bad" || !strings.Contains(msg.Error, "invalid filter") {
+		t.Fatalf("expected filter error, got %+v", msg)
+	}
+	conn.WriteJSON(model.StreamCommand{Type: model.StreamSubscribe, ID: "one", IDs: []uint{1}})
+	conn.WriteJSON(model.StreamCommand{Type: model.StreamSubscribe, ID: "adults", Filter: "age >= 18"})
+	for _, id := range []string{"one", "adults"} {
+		if msg := read(); msg.Type != model.StreamSubscribed || msg.ID != id {
+			t.Fatalf("expected subscribed %s, got %+v", id, msg)
+		}
+	}
+
+	broker.Publish(
+		model.PersonEvent{Type: model.EventCreated, PersonID: 2, Person: model.PersonSnapshot{Age: 10}},
+		model.PersonEvent{Type: model.EventEnriched, PersonID: 1, Person: model.PersonSnapshot{Age: 30}},
+	)
+	msg := read()
+	if msg.Type != model.StreamEvent || msg.Event == nil || msg.Event.PersonID != 1 || msg.Event.Type != model.EventEnriched {
+		t.Fatalf("expected enriched event of person 1, got %+v", msg)
+	}
+	if fmt.Sprint(msg.Subscriptions) != "[adults one]" {
+		t.Fatalf("expected both subscriptions to match, got %v", msg.Subscriptions)
+	}
+
+	conn.WriteJSON(model.StreamCommand{Type: model.StreamPing, ID: "p"})
+	if msg := read(); msg.Type != model.StreamPong || msg.ID != "p" {
+		t.Fatalf("expected pong, got %+v", msg)
+	}
+}
+
+func TestGetChangesHandler(t *testing.T) {
+	svc := &mockPersonService{}
+	h := handler.NewPersonHandler(svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/person/changes?since=bad", nil)
+	rec := httptest.NewRecorder()
+	h.GetChanges(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 Bad Request for invalid token, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/person/changes?since=5&limit=2", nil)
+	rec = httptest.NewRecorder()
+	h.GetChanges(rec, req)
+
+	var changes model.PersonChanges
+	json.NewDecoder(rec.Body).Decode(&changes)
+	if rec.Result().StatusCode != http.StatusOK || changes.Token != "12" || !changes.HasMore {
+		t.Fatalf("expected 200 OK with next token, got %d %+v", rec.Result().StatusCode, changes)
+	}
+	if len(changes.Changed) != 1 || len(changes.Deleted) != 1 || changes.Deleted[0].PersonID != 3 {
+		t.Fatalf("expected changed and deleted people, got %+v", changes)
+	}
+}
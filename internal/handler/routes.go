@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Route is an operation of a version of the API. Its pattern is a method
+// and a path relative to the version prefix, as in "GET /person/{id}".
+type Route struct {
+	Pattern string
+	Handler http.HandlerFunc
+}
+
+// V1 returns the routes of version 1 of the API. idempotent wraps the
+// routes that create people, so that they can be retried safely.
+//
+// A new version gets a constructor of its own that is mounted under its
+// own prefix next to this one. Handlers that do not change between the
+// versions are shared; the ones that do get a new method.
+func V1(persons *PersonHandler, webhooks *WebhookHandler, graphql *GraphQLHandler, idempotent func(http.HandlerFunc) http.HandlerFunc) []Route {
+	return []Route{
+		{"POST /person", idempotent(persons.CreatePerson)},
+		{"POST /person/batch", idempotent(persons.CreatePersonsBatch)},
+		{"POST /person/import", persons.ImportPersons},
+		{"GET /person", persons.GetAllPersons},
+		{"GET /person/export", persons.ExportPersons},
+		{"GET /person/search", persons.SearchPersons},
+		{"GET /person/changes", persons.GetChanges},
+		{"GET /person/events", persons.StreamEvents},
+		{"GET /person/ws", persons.StreamEventsWS},
+		{"GET /person/stats", persons.GetPersonStats},
+		{"GET /person/duplicates", persons.FindDuplicates},
+		{"POST /person/merge", persons.MergePersons},
+		{"GET /person/trash", persons.GetTrash},
+		{"POST /person/{id}/restore", persons.RestorePerson},
+		{"GET /person/{id}/history", persons.GetPersonHistory},
+		{"POST /person/{id}/rollback", persons.RollbackPerson},
+		{"PATCH /person", persons.UpdatePersonsByFilter},
+		{"DELETE /person", persons.DeletePersonsByFilter},
+		{"GET /person/{id}", persons.GetPersonByID},
+		{"PUT /person/{id}", persons.UpdatePerson},
+		{"PATCH /person/{id}", persons.UpdatePerson},
+		{"DELETE /person/{id}", persons.DeletePerson},
+
+		{"POST /views", persons.SaveView},
+		{"GET /views", persons.GetViews},
+		{"GET /views/{name}", persons.GetView},
+		{"GET /views/{name}/versions", persons.GetViewVersions},
+		{"GET /views/{name}/persons", persons.GetViewPersons},
+
+		{"POST /webhooks", webhooks.CreateWebhook},
+		{"GET /webhooks", webhooks.GetWebhooks},
+		{"DELETE /webhooks/{id}", webhooks.DeleteWebhook},
+		{"GET /webhooks/{id}/deliveries", webhooks.GetDeliveries},
+		{"POST /webhooks/{id}/deliveries/{delivery}/replay", webhooks.ReplayDelivery},
+
+		{"POST /graphql", graphql.ServeGraphQL},
+	}
+}
+
+// Mount registers the routes on mux under prefix, such as "/v1".
+func Mount(mux *http.ServeMux, prefix string, routes []Route) {
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route.Pattern, " ")
+		mux.HandleFunc(method+" "+prefix+path, route.Handler)
+	}
+}
+
+// Deprecation describes routes that are going away.
+type Deprecation struct {
+	// Since is when the routes were deprecated.
+	Since time.Time
+	// Sunset is when they stop being served; zero if not decided yet.
+	Sunset time.Time
+}
+
+// MountAliases registers the routes on mux at their paths without prefix,
+// as deprecated aliases of the ones Mount registers under it. An alias
+// moves the request under prefix and passes it to h, normally the handler
+// serving mux, so that it goes through the same middleware as a request to
+// the versioned path. Responses carry the Deprecation and Sunset headers
+// and a Link to the versioned path.
+func MountAliases(mux *http.ServeMux, prefix string, routes []Route, d Deprecation, h http.Handler) {
+	alias := d.alias(prefix, h)
+	for _, route := range routes {
+		mux.Handle(route.Pattern, alias)
+	}
+}
+
+func (d Deprecation) alias(prefix string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Log.Debug("deprecated route called", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+		u := *r.URL
+		u.Path = prefix + r.URL.Path
+		if r.URL.RawPath != "" {
+			u.RawPath = prefix + r.URL.RawPath
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = &u
+
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+		if !d.Sunset.IsZero() {
+			w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Add("Link", "<"+u.RequestURI()+`>; rel="successor-version"`)
+		h.ServeHTTP(w, r2)
+	})
+}
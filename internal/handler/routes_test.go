@@ -0,0 +1,120 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"effective-mobile/internal/gql"
+	"effective-mobile/internal/handler"
+	"effective-mobile/internal/openapi"
+)
+
+func TestMountAliases(t *testing.T) {
+	schema, err := gql.NewSchema(&mockPersonService{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := openapi.NewValidator(openapi.Document())
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := handler.V1(
+		handler.NewPersonHandler(&mockPersonService{}),
+		handler.NewWebhookHandler(&mockWebhookService{}),
+		handler.NewGraphQLHandler(schema),
+		func(next http.HandlerFunc) http.HandlerFunc { return next },
+	)
+	deprecation := handler.Deprecation{
+		Since:  time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC),
+	}
+
+	mux := http.NewServeMux()
+	root := v.Middleware(mux)
+	handler.Mount(mux, "/v1", routes)
+	handler.MountAliases(mux, "/v1", routes, deprecation, root)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		root.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/v1/person/1", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "" {
+		t.Fatalf("expected 200 without Deprecation, got %d %q", rec.Code, rec.Header().Get("Deprecation"))
+	}
+
+	rec = serve(http.MethodGet, "/person/1?fields=name", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the alias, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Deprecation"); got != "@1792368000" {
+		t.Errorf("unexpected Deprecation %q", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != "Mon, 19 Apr 2027 00:00:00 GMT" {
+		t.Errorf("unexpected Sunset %q", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</v1/person/1?fields=name>; rel="successor-version"` {
+		t.Errorf("unexpected Link %q", got)
+	}
+
+	rec = serve(http.MethodPost, "/views", `{"name":"adults"}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/v1/views/adults?version=2" {
+		t.Errorf("expected the alias to create the view under /v1, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// Aliases go through the middleware of the versioned routes.
+	rec = serve(http.MethodGet, "/person?limit=5000", "")
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Deprecation") == "" {
+		t.Errorf("expected the validator to reject the aliased request, got %d %q", rec.Code, rec.Body)
+	}
+
+	rec = serve(http.MethodGet, "/v2/person", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown version, got %d", rec.Code)
+	}
+}
+
+// TestV1MatchesOpenAPI checks that every route of V1 is described in the
+// OpenAPI document with the same path and method, and that the document
+// describes no other /v1 operations.
+func TestV1MatchesOpenAPI(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Document(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	routes := handler.V1(
+		handler.NewPersonHandler(&mockPersonService{}),
+		handler.NewWebhookHandler(&mockWebhookService{}),
+		&handler.GraphQLHandler{},
+		func(next http.HandlerFunc) http.HandlerFunc { return next },
+	)
+	served := map[string]bool{}
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route.Pattern, " ")
+		served[strings.ToLower(method)+" /v1"+path] = true
+		if _, ok := doc.Paths["/v1"+path][strings.ToLower(method)]; !ok {
+			t.Errorf("%s /v1%s is not in openapi.json", method, path)
+		}
+	}
+
+	for path, ops := range doc.Paths {
+		if !strings.HasPrefix(path, "/v1/") {
+			continue
+		}
+		for method := range ops {
+			if method != "parameters" && !served[method+" "+path] {
+				t.Errorf("openapi.json describes %s %s, which V1 does not serve", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
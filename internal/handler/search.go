@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const maxSearchQueryLength = 200
+
+// SearchPersons godoc
+// @Summary Нечёткий поиск людей
+// @Description Ищет людей по похожести запроса на имя, фамилию, отчество или полное имя (pg_trgm) и сортирует по релевантности. В режиме match=phonetic ищет фамилии, которые звучат так же, как слова запроса (Shevchenko, Schewtschenko, Шевченко). Поддерживает фильтры и пагинацию списка
+// @Tags persons
+// @Produce json
+// @Param q query string true "Поисковый запрос"
+// @Param match query string false "Режим поиска" Enums(fuzzy, phonetic) default(fuzzy)
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {array} model.PersonSearchResult
+// @Failure 400 {string} string "invalid query, match mode or filter"
+// @Failure 500 {string} string "failed to search"
+// @Router /v1/person/search [get]
+func (h *PersonHandler) SearchPersons(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	logger.Log.Debug("GET /person/search - received request", zap.String("q", query))
+
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		http.Error(w, "q is too long", http.StatusBadRequest)
+		return
+	}
+
+	match := r.URL.Query().Get("match")
+	switch match {
+	case "":
+		match = model.SearchMatchFuzzy
+	case model.SearchMatchFuzzy, model.SearchMatchPhonetic:
+	default:
+		http.Error(w, "invalid match: expected fuzzy or phonetic", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.service.SearchPersons(requestContext(r), query, match, filter)
+	if err != nil {
+		logger.Log.Error("failed to search persons", zap.Error(err))
+		http.Error(w, "failed to search: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("persons found", zap.String("q", query), zap.String("match", match), zap.Int("count", len(results)))
+	writeJSON(w, results, http.StatusOK)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	maxAgeBuckets       = 50
+	maxTopNationalities = 100
+)
+
+// GetPersonStats godoc
+// @Summary Статистика по людям
+// @Description Считает в базе распределение по полу, гистограмму возрастов, самые частые национальности и средний возраст по национальности и полу для людей, подходящих под фильтры списка. Люди без известного возраста не учитываются в гистограмме и средних
+// @Tags persons
+// @Produce json
+// @Param age_buckets query string false "Границы корзин гистограммы через запятую по возрастанию" default(18,25,35,45,55,65)
+// @Param top query int false "Число национальностей в рейтинге" default(10)
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Success 200 {object} model.PersonStats
+// @Failure 400 {string} string "invalid filter or stats options"
+// @Failure 500 {string} string "failed to get stats"
+// @Router /v1/person/stats [get]
+func (h *PersonHandler) GetPersonStats(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("GET /person/stats - received request", zap.String("query", r.URL.RawQuery))
+
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, err := parseStatsOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.service.GetPersonStats(requestContext(r), filter, opts)
+	if err != nil {
+		logger.Log.Error("failed to get person stats", zap.Error(err))
+		http.Error(w, "failed to get stats: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("person stats computed", zap.Int64("total", stats.Total))
+	writeJSON(w, stats, http.StatusOK)
+}
+
+// parseStatsOptions reads the age_buckets and top query parameters. Absent
+// parameters are left zero for the service defaults.
+func parseStatsOptions(q url.Values) (model.StatsOptions, error) {
+	var opts model.StatsOptions
+
+	if v := q.Get("age_buckets"); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) > maxAgeBuckets {
+			return opts, fmt.Errorf("age_buckets must not have more than %d bounds", maxAgeBuckets)
+		}
+		for _, part := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n <= 0 {
+				return opts, fmt.Errorf("invalid age_buckets: %q", part)
+			}
+			if len(opts.AgeBuckets) > 0 && n <= opts.AgeBuckets[len(opts.AgeBuckets)-1] {
+				return opts, fmt.Errorf("age_buckets must be in ascending order")
+			}
+			opts.AgeBuckets = append(opts.AgeBuckets, n)
+		}
+	}
+
+	if v := q.Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxTopNationalities {
+			return opts, fmt.Errorf("top must be between 1 and %d", maxTopNationalities)
+		}
+		opts.TopNationalities = n
+	}
+	return opts, nil
+}
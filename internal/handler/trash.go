@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// GetTrash godoc
+// @Summary Корзина
+// @Description Возвращает удалённых людей, которых ещё можно восстановить, с временем удаления. Поддерживает фильтры и пагинацию списка
+// @Tags trash
+// @Produce json
+// @Param name query string false "Имя"
+// @Param surname query string false "Фамилия"
+// @Param patronymic query string false "Отчество"
+// @Param gender query string false "Пол"
+// @Param nationality query string false "Национальность"
+// @Param age_min query int false "Минимальный возраст"
+// @Param age_max query int false "Максимальный возраст"
+// @Param filter query string false "Выражение фильтра, например: age >= 30 and (nationality in ('RU', 'KZ') or gender = 'female')"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {array} model.DeletedPerson
+// @Failure 400 {string} string "invalid filter"
+// @Failure 500 {string} string "failed to get trash"
+// @Router /v1/person/trash [get]
+func (h *PersonHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("GET /person/trash - listing deleted persons")
+
+	filter, err := parsePersonFilter(r.URL.Query())
+	if err != nil {
+		logger.Log.Warn("invalid filter", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	people, err := h.service.GetDeletedPersons(requestContext(r), filter)
+	if err != nil {
+		logger.Log.Error("failed to get trash", zap.Error(err))
+		http.Error(w, "failed to get trash: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("deleted persons fetched", zap.Int("count", len(people)))
+	writeJSON(w, people, http.StatusOK)
+}
+
+// RestorePerson godoc
+// @Summary Восстановление человека
+// @Description Восстанавливает человека из корзины
+// @Tags trash
+// @Produce json
+// @Param id path int true "ID человека"
+// @Success 200 {object} model.Person
+// @Failure 400 {string} string "invalid ID"
+// @Failure 404 {string} string "person not found in trash"
+// @Failure 500 {string} string "failed to restore"
+// @Router /v1/person/{id}/restore [post]
+func (h *PersonHandler) RestorePerson(w http.ResponseWriter, r *http.Request) {
+	idString := r.PathValue("id")
+	id, err := strconv.ParseUint(idString, 10, 32)
+	if err != nil {
+		logger.Log.Warn("invalid ID", zap.String("id", idString), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Log.Info("restoring person", zap.Uint("id", uint(id)))
+	person, err := h.service.RestorePerson(requestContext(r), uint(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			logger.Log.Warn("person not found in trash", zap.Uint("id", uint(id)))
+			http.Error(w, "person not found in trash", http.StatusNotFound)
+			return
+		}
+		logger.Log.Error("failed to restore person", zap.Error(err))
+		http.Error(w, "failed to restore: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("person restored", zap.Uint("id", uint(id)))
+	w.Header().Set("ETag", etag(person))
+	writeJSON(w, person, http.StatusOK)
+}
+
+func (h *PersonHandler) purgePerson(w http.ResponseWriter, r *http.Request, id uint) {
+	if !h.isAdmin(r) {
+		logger.Log.Warn("hard delete without admin token", zap.Uint("id", id))
+		http.Error(w, "admin token required", http.StatusForbidden)
+		return
+	}
+
+	logger.Log.Info("purging person", zap.Uint("id", id))
+	if err := h.service.PurgePerson(requestContext(r), id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			logger.Log.Warn("person not found", zap.Uint("id", id))
+			http.Error(w, "person not found", http.StatusNotFound)
+			return
+		}
+		logger.Log.Error("failed to purge person", zap.Error(err))
+		http.Error(w, "failed to delete: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	logger.Log.Info("person purged", zap.Uint("id", id))
+}
+
+func (h *PersonHandler) isAdmin(r *http.Request) bool {
+	return hasAdminToken(r, h.adminToken)
+}
+
+// hasAdminToken reports whether r carries token in the X-Admin-Token
+// header. An empty token matches nothing, which disables admin-only
+// operations.
+func hasAdminToken(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(token)) == 1
+}
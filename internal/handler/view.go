@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/validator"
+
+	"go.uber.org/zap"
+)
+
+// SaveView godoc
+// @Summary Сохранение представления
+// @Description Сохраняет под именем выражение фильтра, сортировку и набор колонок. Повторное сохранение с тем же именем создаёт новую версию, старые версии остаются доступны
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param X-Actor header string false "Автор изменения"
+// @Param view body model.SaveViewRequest true "Представление"
+// @Success 201 {object} model.View
+// @Failure 400 {string} string "invalid JSON or view"
+// @Failure 500 {string} string "failed to save view"
+// @Router /v1/views [post]
+func (h *PersonHandler) SaveView(w http.ResponseWriter, r *http.Request) {
+	logger.Log.Debug("POST /views - received request")
+
+	var req model.SaveViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log.Warn("failed to decode view JSON", zap.Error(err))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := validator.Validate.Struct(req); err != nil {
+		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view, err := h.service.SaveView(requestContext(r), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidView) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Log.Error("failed to save view", zap.Error(err))
+		http.Error(w, "failed to save view: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("view saved", zap.String("name", view.Name), zap.Uint("version", view.Version))
+	w.Header().Set("Location", r.URL.Path+"/"+url.PathEscape(view.Name)+"?version="+strconv.FormatUint(uint64(view.Version), 10))
+	writeJSON(w, view, http.StatusCreated)
+}
+
+// GetViews godoc
+// @Summary Список представлений
+// @Description Возвращает последнюю версию каждого сохранённого представления
+// @Tags views
+// @Produce json
+// @Success 200 {array} model.View
+// @Failure 500 {string} string "failed to get views"
+// @Router /v1/views [get]
+func (h *PersonHandler) GetViews(w http.ResponseWriter, r *http.Request) {
+	views, err := h.service.GetViews(requestContext(r))
+	if err != nil {
+		logger.Log.Error("failed to get views", zap.Error(err))
+		http.Error(w, "failed to get views: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, views, http.StatusOK)
+}
+
+// GetView godoc
+// @Summary Представление
+// @Description Возвращает указанную версию представления или последнюю, если версия не задана
+// @Tags views
+// @Produce json
+// @Param name path string true "Имя представления"
+// @Param version query int false "Версия"
+// @Success 200 {object} model.View
+// @Failure 400 {string} string "invalid version"
+// @Failure 404 {string} string "view not found"
+// @Failure 500 {string} string "failed to get view"
+// @Router /v1/views/{name} [get]
+func (h *PersonHandler) GetView(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	version, err := parseViewVersion(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view, err := h.service.GetView(requestContext(r), name, version)
+	if err != nil {
+		writeViewError(w, name, err)
+		return
+	}
+	writeJSON(w, view, http.StatusOK)
+}
+
+// GetViewVersions godoc
+// @Summary Версии представления
+// @Description Возвращает все версии представления, от старых к новым
+// @Tags views
+// @Produce json
+// @Param name path string true "Имя представления"
+// @Success 200 {array} model.View
+// @Failure 404 {string} string "view not found"
+// @Failure 500 {string} string "failed to get view"
+// @Router /v1/views/{name}/versions [get]
+func (h *PersonHandler) GetViewVersions(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	views, err := h.service.GetViewVersions(requestContext(r), name)
+	if err != nil {
+		writeViewError(w, name, err)
+		return
+	}
+	writeJSON(w, views, http.StatusOK)
+}
+
+// GetViewPersons godoc
+// @Summary Люди из представления
+// @Description Выполняет фильтр и сортировку представления и возвращает страницу людей. Если в представлении заданы колонки, у каждого человека возвращаются только они. Использованная версия передаётся в заголовке X-View-Version
+// @Tags views
+// @Produce json
+// @Param name path string true "Имя представления"
+// @Param version query int false "Версия, по умолчанию последняя"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Success 200 {array} model.Person
+// @Failure 400 {string} string "invalid version or page"
+// @Failure 404 {string} string "view not found"
+// @Failure 500 {string} string "failed to get view persons"
+// @Router /v1/views/{name}/persons [get]
+func (h *PersonHandler) GetViewPersons(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	logger.Log.Debug("GET /views/{name}/persons - received request", zap.String("name", name), zap.String("query", r.URL.RawQuery))
+
+	version, err := parseViewVersion(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	view, people, err := h.service.GetViewPersons(requestContext(r), name, version, limit, offset)
+	if err != nil {
+		writeViewError(w, name, err)
+		return
+	}
+
+	logger.Log.Info("view persons fetched", zap.String("name", name), zap.Uint("version", view.Version), zap.Int("count", len(people)))
+	w.Header().Set("X-View-Version", strconv.FormatUint(uint64(view.Version), 10))
+	if len(view.Columns) == 0 {
+		writeJSON(w, people, http.StatusOK)
+		return
+	}
+
+	rows := make([]map[string]any, len(people))
+	for i := range people {
+		rows[i] = projectPerson(&people[i], view.Columns)
+	}
+	writeJSON(w, rows, http.StatusOK)
+}
+
+func parseViewVersion(q url.Values) (uint, error) {
+	v := q.Get("version")
+	if v == "" {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("invalid version: %q", v)
+	}
+	return uint(version), nil
+}
+
+func writeViewError(w http.ResponseWriter, name string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "view not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidView):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.Log.Error("failed to get view", zap.String("name", name), zap.Error(err))
+		http.Error(w, "failed to get view: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// projectPerson returns the given columns of p keyed by their JSON names.
+func projectPerson(p *model.Person, columns []string) map[string]any {
+	all := map[string]any{
+		"id":          p.ID,
+		"name":        p.Name,
+		"surname":     p.Surname,
+		"patronymic":  p.Patronymic,
+		"gender":      p.Gender,
+		"age":         p.Age,
+		"nationality": p.Nationality,
+		"version":     p.Version,
+		"created_at":  p.CreatedAt,
+		"updated_at":  p.UpdatedAt,
+	}
+
+	row := make(map[string]any, len(columns))
+	for _, c := range columns {
+		row[c] = all[c]
+	}
+	return row
+}
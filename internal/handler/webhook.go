@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/logger"
+	"effective-mobile/pkg/validator"
+
+	"go.uber.org/zap"
+)
+
+type WebhookHandler struct {
+	service    service.WebhookServiceInterface
+	adminToken string
+}
+
+func NewWebhookHandler(s service.WebhookServiceInterface) *WebhookHandler {
+	return &WebhookHandler{service: s}
+}
+
+// WithAdminToken sets the token expected in the X-Admin-Token header.
+// Webhooks receive the data of every person, so only the administrator
+// manages them; without a token webhook management is disabled.
+func (h *WebhookHandler) WithAdminToken(token string) *WebhookHandler {
+	h.adminToken = token
+	return h
+}
+
+// CreateWebhook godoc
+// @Summary Регистрация вебхука
+// @Description Регистрирует URL, на который POST-запросами отправляются события created, updated, deleted и enriched (без events — все). Каждая доставка подписана: заголовок X-Webhook-Signature содержит sha256=HMAC-SHA256 строки '<X-Webhook-Timestamp>.<тело>' с секретом вебхука. Секрет возвращается только в ответе на этот запрос. Неудачные доставки повторяются с нарастающей задержкой. Адреса в локальных и частных сетях не принимаются. Управление вебхуками доступно только администратору
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body model.CreateWebhookRequest true "Вебхук"
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Success 201 {object} model.Webhook
+// @Failure 400 {string} string "invalid JSON or webhook"
+// @Failure 403 {string} string "admin token required"
+// @Failure 500 {string} string "failed to create webhook"
+// @Router /v1/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	var req model.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Log.Warn("failed to decode webhook JSON", zap.Error(err))
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := validator.Validate.Struct(req); err != nil {
+		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hook, err := h.service.CreateWebhook(requestContext(r), req)
+	if errors.Is(err, service.ErrWebhookTarget) {
+		logger.Log.Warn("webhook target rejected", zap.String("url", req.URL), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.Log.Error("failed to create webhook", zap.Error(err))
+		http.Error(w, "failed to create webhook: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Log.Info("webhook created", zap.Uint("id", hook.ID), zap.String("url", hook.URL), zap.Strings("events", hook.Events))
+	w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, hook.ID))
+	writeJSON(w, hook, http.StatusCreated)
+}
+
+// GetWebhooks godoc
+// @Summary Список вебхуков
+// @Tags webhooks
+// @Produce json
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Success 200 {array} model.Webhook
+// @Failure 403 {string} string "admin token required"
+// @Failure 500 {string} string "failed to get webhooks"
+// @Router /v1/webhooks [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	hooks, err := h.service.GetWebhooks(requestContext(r))
+	if err != nil {
+		logger.Log.Error("failed to get webhooks", zap.Error(err))
+		http.Error(w, "failed to get webhooks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, hooks, http.StatusOK)
+}
+
+// DeleteWebhook godoc
+// @Summary Удаление вебхука
+// @Description Удаляет вебхук вместе с журналом доставок
+// @Tags webhooks
+// @Param id path int true "ID вебхука"
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Success 204 {string} string "no content"
+// @Failure 400 {string} string "invalid ID"
+// @Failure 403 {string} string "admin token required"
+// @Failure 404 {string} string "webhook not found"
+// @Failure 500 {string} string "failed to delete webhook"
+// @Router /v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteWebhook(requestContext(r), id); err != nil {
+		writeWebhookError(w, err, "webhook not found", "failed to delete webhook")
+		return
+	}
+	logger.Log.Info("webhook deleted", zap.Uint("id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries godoc
+// @Summary Журнал доставок
+// @Description Возвращает доставки вебхука, от новых к старым
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param status query string false "Статус: pending, delivered или failed"
+// @Param limit query int false "Размер страницы"
+// @Param offset query int false "Смещение"
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Success 200 {array} model.WebhookDelivery
+// @Failure 400 {string} string "invalid ID, status or page"
+// @Failure 403 {string} string "admin token required"
+// @Failure 404 {string} string "webhook not found"
+// @Failure 500 {string} string "failed to get deliveries"
+// @Router /v1/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", model.DeliveryPending, model.DeliveryDelivered, model.DeliveryFailed:
+	default:
+		http.Error(w, "invalid status: "+status, http.StatusBadRequest)
+		return
+	}
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.service.GetDeliveries(requestContext(r), id, status, limit, offset)
+	if err != nil {
+		writeWebhookError(w, err, "webhook not found", "failed to get deliveries")
+		return
+	}
+	writeJSON(w, deliveries, http.StatusOK)
+}
+
+// ReplayDelivery godoc
+// @Summary Повтор доставки
+// @Description Ставит событие из журнала в очередь на повторную отправку новой доставкой; исходная запись журнала не меняется
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param delivery path int true "ID доставки"
+// @Param X-Admin-Token header string true "Токен администратора"
+// @Success 202 {object} model.WebhookDelivery
+// @Failure 400 {string} string "invalid ID"
+// @Failure 403 {string} string "admin token required"
+// @Failure 404 {string} string "delivery not found"
+// @Failure 500 {string} string "failed to replay delivery"
+// @Router /v1/webhooks/{id}/deliveries/{delivery}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	deliveryID, err := pathID(r, "delivery")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.service.ReplayDelivery(requestContext(r), id, deliveryID)
+	if err != nil {
+		writeWebhookError(w, err, "delivery not found", "failed to replay delivery")
+		return
+	}
+	logger.Log.Info("webhook delivery replayed", zap.Uint("webhook_id", id), zap.Uint("delivery_id", deliveryID), zap.Uint("replay_id", delivery.ID))
+	writeJSON(w, delivery, http.StatusAccepted)
+}
+
+// authorize writes 403 and returns false unless r carries the admin token.
+func (h *WebhookHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if hasAdminToken(r, h.adminToken) {
+		return true
+	}
+	logger.Log.Warn("webhook management without admin token", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+	http.Error(w, "admin token required", http.StatusForbidden)
+	return false
+}
+
+func pathID(r *http.Request, name string) (uint, error) {
+	v := r.PathValue(name)
+	id, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %q", name, v)
+	}
+	return uint(id), nil
+}
+
+func writeWebhookError(w http.ResponseWriter, err error, notFound, failed string) {
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+	logger.Log.Error(failed, zap.Error(err))
+	http.Error(w, failed+": "+err.Error(), http.StatusInternalServerError)
+}
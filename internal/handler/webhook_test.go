@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"effective-mobile/internal/handler"
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+)
+
+type mockWebhookService struct{}
+
+func (m *mockWebhookService) CreateWebhook(ctx context.Context, req model.CreateWebhookRequest) (*model.Webhook, error) {
+	return &model.Webhook{ID: 3, URL: req.URL, Events: req.Events, Secret: "generated"}, nil
+}
+
+func (m *mockWebhookService) GetWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	return []model.Webhook{{ID: 3, URL: "https://example.com/hook"}}, nil
+}
+
+func (m *mockWebhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	if id != 3 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (m *mockWebhookService) GetDeliveries(ctx context.Context, webhookID uint, status string, limit, offset int) ([]model.WebhookDelivery, error) {
+	return []model.WebhookDelivery{{ID: 1, WebhookID: webhookID, EventType: model.EventCreated, Status: model.DeliveryFailed}}, nil
+}
+
+func (m *mockWebhookService) ReplayDelivery(ctx context.Context, webhookID, deliveryID uint) (*model.WebhookDelivery, error) {
+	if deliveryID != 1 {
+		return nil, repository.ErrNotFound
+	}
+	return &model.WebhookDelivery{ID: 2, WebhookID: webhookID, EventType: model.EventCreated, Status: model.DeliveryPending, ReplayOf: &deliveryID}, nil
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	h := handler.NewWebhookHandler(&mockWebhookService{}).WithAdminToken("secret")
+
+	body := `{"url": "https://example.com/hook", "events": ["created"]}`
+	rec := httptest.NewRecorder()
+	h.CreateWebhook(rec, httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body)))
+	if rec.Result().StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 without the admin token, got %d", rec.Result().StatusCode)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "https://example.com/hook", "events": ["moved"]}`))
+	req.Header.Set("X-Admin-Token", "secret")
+	rec = httptest.NewRecorder()
+	h.CreateWebhook(rec, req)
+	if rec.Result().StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown event, got %d", rec.Result().StatusCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "https://example.com/hook", "events": ["created", "enriched"]}`))
+	req.Header.Set("X-Admin-Token", "secret")
+	rec = httptest.NewRecorder()
+	h.CreateWebhook(rec, req)
+
+	var hook model.Webhook
+	json.NewDecoder(rec.Body).Decode(&hook)
+	if rec.Result().StatusCode != http.StatusCreated || rec.Header().Get("Location") != "/webhooks/3" {
+		t.Fatalf("expected 201 Created with Location, got %d %q", rec.Result().StatusCode, rec.Header().Get("Location"))
+	}
+	if hook.Secret == "" {
+		t.Fatal("expected the secret in the creation response")
+	}
+}
+
+func TestReplayDeliveryHandler(t *testing.T) {
+	h := handler.NewWebhookHandler(&mockWebhookService{}).WithAdminToken("secret")
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/{id}/deliveries/{delivery}/replay", h.ReplayDelivery)
+	admin := func(req *http.Request) *http.Request {
+		req.Header.Set("X-Admin-Token", "secret")
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/3/deliveries/1/replay", nil))
+	if rec.Result().StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 without the admin token, got %d", rec.Result().StatusCode)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, admin(httptest.NewRequest(http.MethodPost, "/webhooks/3/deliveries/9/replay", nil)))
+	if rec.Result().StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown delivery, got %d", rec.Result().StatusCode)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, admin(httptest.NewRequest(http.MethodPost, "/webhooks/3/deliveries/1/replay", nil)))
+	var d model.WebhookDelivery
+	json.NewDecoder(rec.Body).Decode(&d)
+	if rec.Result().StatusCode != http.StatusAccepted || d.ReplayOf == nil || *d.ReplayOf != 1 {
+		t.Fatalf("expected 202 Accepted with the replay, got %d %+v", rec.Result().StatusCode, d)
+	}
+}
@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"effective-mobile/internal/events"
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/logger"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+
+	maxWSMessageSize   = 4096
+	maxWSSubscriptions = 100
+	maxWSSubscribedIDs = 1000
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsSubscription selects events by person ID, by filter, or by both.
+type wsSubscription struct {
+	ids    map[uint]struct{}
+	filter *model.PersonFilter
+}
+
+func (s *wsSubscription) matches(ev *model.PersonEvent) bool {
+	if s.ids != nil {
+		if _, ok := s.ids[ev.PersonID]; !ok {
+			return false
+		}
+	}
+	return s.filter == nil || s.filter.Matches(ev.Subject())
+}
+
+// StreamEventsWS godoc
+// @Summary Подписка на изменения (WebSocket)
+// @Description Открывает WebSocket, по которому клиент управляет подписками на изменения людей. Команды: {"type": "subscribe", "id": "s1", "ids": [1, 2]} или {"type": "subscribe", "id": "s2", "filter": "age >= 18"}, {"type": "unsubscribe", "id": "s1"}, {"type": "ping"}. Сервер отвечает сообщениями subscribed, unsubscribed, pong и error, а события присылает как {"type": "event", "subscriptions": [...], "event": {...}}. Сервер шлёт ping-кадры и закрывает соединение, если клиент не отвечает или не успевает читать события; параметр last_event_id продолжает поток после переподключения
+// @Tags events
+// @Param last_event_id query int false "ID последнего полученного события"
+// @Success 101 {object} model.StreamMessage "переключение на WebSocket"
+// @Failure 400 {string} string "invalid event ID or not a WebSocket handshake"
+// @Failure 503 {string} string "event feed is not enabled or too many subscribers"
+// @Router /v1/person/ws [get]
+func (h *PersonHandler) StreamEventsWS(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		http.Error(w, "event feed is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	lastID, err := parseLastEventID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.acquireSubscriber() {
+		http.Error(w, "too many subscribers", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.releaseSubscriber()
+
+	// Upgrade writes the error response itself.
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Log.Warn("websocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	logger.Log.Info("websocket feed opened", zap.String("remote", r.RemoteAddr), zap.Uint64("last_event_id", lastID))
+
+	commands := make(chan model.StreamCommand)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go readWSCommands(conn, commands, done, stop)
+
+	// The connection joins the broker with its first subscription, so that
+	// the events missed since lastID are replayed through it.
+	var sub *events.Subscription
+	defer func() {
+		if sub != nil {
+			sub.Close()
+		}
+	}()
+	var feed <-chan model.PersonEvent
+
+	ws := wsConn{conn: conn, subs: map[string]*wsSubscription{}}
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-done:
+			return
+		case cmd := <-commands:
+			if sub != nil || cmd.Type != model.StreamSubscribe {
+				err = ws.handle(cmd)
+				break
+			}
+			// Join before confirming, so that no event published after
+			// the confirmation is missed.
+			var missed []model.PersonEvent
+			var complete bool
+			sub, missed, complete = h.events.Subscribe(lastID)
+			if err = ws.handle(cmd); err != nil || len(ws.subs) == 0 {
+				sub.Close()
+				sub = nil
+				break
+			}
+			feed = sub.C
+			if !complete {
+				err = ws.send(model.StreamMessage{Type: model.StreamReset})
+			}
+			for i := 0; i < len(missed) && err == nil; i++ {
+				err = ws.deliver(&missed[i])
+			}
+		case ev, ok := <-feed:
+			if !ok {
+				// The broker drops connections whose buffer is full
+				// rather than letting them hold up publishing.
+				logger.Log.Warn("websocket subscriber fell behind", zap.String("remote", r.RemoteAddr))
+				ws.close(websocket.CloseTryAgainLater, "too slow, reconnect with last_event_id")
+				return
+			}
+			err = ws.deliver(&ev)
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			logger.Log.Debug("websocket feed closed", zap.Error(err))
+			return
+		}
+	}
+}
+
+// readWSCommands reads client commands until the connection fails or stop
+// is closed. Pongs extend the read deadline, so a client that stops
+// answering pings is disconnected.
+func readWSCommands(conn *websocket.Conn, commands chan<- model.StreamCommand, done, stop chan struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(maxWSMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var cmd model.StreamCommand
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			cmd = model.StreamCommand{}
+		}
+		select {
+		case commands <- cmd:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// wsConn is the writing side of a feed connection and the client's
+// subscriptions. Only the connection's main loop uses it.
+type wsConn struct {
+	conn *websocket.Conn
+	subs map[string]*wsSubscription
+}
+
+func (c *wsConn) send(msg model.StreamMessage) error {
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsConn) fail(id, text string) error {
+	return c.send(model.StreamMessage{Type: model.StreamError, ID: id, Error: text})
+}
+
+func (c *wsConn) close(code int, text string) {
+	msg := websocket.FormatCloseMessage(code, text)
+	c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteWait))
+}
+
+func (c *wsConn) handle(cmd model.StreamCommand) error {
+	switch cmd.Type {
+	case "":
+		return c.fail(cmd.ID, "invalid command, expected a JSON object with a type")
+
+	case model.StreamPing:
+		return c.send(model.StreamMessage{Type: model.StreamPong, ID: cmd.ID})
+
+	case model.StreamUnsubscribe:
+		if _, ok := c.subs[cmd.ID]; !ok {
+			return c.fail(cmd.ID, "unknown subscription")
+		}
+		delete(c.subs, cmd.ID)
+		return c.send(model.StreamMessage{Type: model.StreamUnsubscribed, ID: cmd.ID})
+
+	case model.StreamSubscribe:
+		sub, err := c.subscription(cmd)
+		if err != nil {
+			return c.fail(cmd.ID, err.Error())
+		}
+		c.subs[cmd.ID] = sub
+		return c.send(model.StreamMessage{Type: model.StreamSubscribed, ID: cmd.ID})
+	}
+	return c.fail(cmd.ID, "unknown command, expected subscribe, unsubscribe or ping")
+}
+
+// subscription validates a subscribe command. Resubscribing with a known ID
+// replaces that subscription.
+func (c *wsConn) subscription(cmd model.StreamCommand) (*wsSubscription, error) {
+	switch {
+	case cmd.ID == "":
+		return nil, errors.New("subscription id is required")
+	case len(cmd.IDs) == 0 && cmd.Filter == "":
+		return nil, errors.New("ids or filter is required")
+	case len(cmd.IDs) > maxWSSubscribedIDs:
+		return nil, fmt.Errorf("ids must not exceed %d", maxWSSubscribedIDs)
+	}
+	if _, ok := c.subs[cmd.ID]; !ok && len(c.subs) >= maxWSSubscriptions {
+		return nil, fmt.Errorf("subscriptions must not exceed %d", maxWSSubscriptions)
+	}
+
+	sub := &wsSubscription{}
+	if len(cmd.IDs) > 0 {
+		sub.ids = make(map[uint]struct{}, len(cmd.IDs))
+		for _, id := range cmd.IDs {
+			sub.ids[id] = struct{}{}
+		}
+	}
+	if cmd.Filter != "" {
+		filter, err := parsePersonFilter(url.Values{"filter": {cmd.Filter}})
+		if err != nil {
+			return nil, err
+		}
+		sub.filter = &filter
+	}
+	return sub, nil
+}
+
+// deliver sends ev if it matches any of the client's subscriptions.
+func (c *wsConn) deliver(ev *model.PersonEvent) error {
+	var matched []string
+	for id, sub := range c.subs {
+		if sub.matches(ev) {
+			matched = append(matched, id)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	sort.Strings(matched)
+	return c.send(model.StreamMessage{Type: model.StreamEvent, Subscriptions: matched, Event: ev})
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// ChangePosition is the place of a change in the change feed. Changes are
+// ordered by the transaction that made them, then by their sequence within
+// it. The feed only reads changes of transactions older than any still
+// running, so a change that commits later always sorts after the changes
+// read before it, even if its sequence is lower.
+type ChangePosition struct {
+	XID      uint64
+	Sequence uint64
+}
+
+// Before reports whether p comes before q in the change feed.
+func (p ChangePosition) Before(q ChangePosition) bool {
+	if p.XID != q.XID {
+		return p.XID < q.XID
+	}
+	return p.Sequence < q.Sequence
+}
+
+// ChangePosition returns the position of the latest change of the person.
+func (p *Person) ChangePosition() ChangePosition {
+	return ChangePosition{XID: p.ChangeXID, Sequence: p.Sequence}
+}
+
+// PersonTombstone marks a deleted person in the change feed. Tombstones of
+// purged people are kept in their own table, since their rows are gone.
+type PersonTombstone struct {
+	Sequence  uint64    `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	ChangeXID uint64    `json:"-" gorm:"column:change_xid;->;-:migration"`
+	PersonID  uint      `json:"id" gorm:"index;not null" example:"42"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ChangePosition returns the position of the deletion.
+func (t *PersonTombstone) ChangePosition() ChangePosition {
+	return ChangePosition{XID: t.ChangeXID, Sequence: t.Sequence}
+}
+
+func (PersonTombstone) TableName() string {
+	return "person_tombstones"
+}
+
+// PersonChanges is a page of the change feed: the people created or
+// updated and the people deleted after the token the client sent. Token
+// is passed as since to get the next page; HasMore reports whether the
+// next page has changes already.
+type PersonChanges struct {
+	Changed []Person          `json:"changed"`
+	Deleted []PersonTombstone `json:"deleted"`
+	Token   string            `json:"token" example:"7731.1523"`
+	HasMore bool              `json:"has_more"`
+}
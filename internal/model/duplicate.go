@@ -0,0 +1,33 @@
+package model
+
+// DuplicateConflict is the response to creating a person who likely exists
+// already.
+type DuplicateConflict struct {
+	Error      string `json:"error" example:"person may already exist"`
+	Candidates []uint `json:"candidates" example:"12,31"`
+}
+
+// DuplicateCluster is a group of people who likely are the same person. ID
+// is the smallest ID in the group.
+type DuplicateCluster struct {
+	ID      uint     `json:"id"`
+	Persons []Person `json:"persons"`
+}
+
+// MergeRequest merges the source people into the target. By default the
+// target keeps its values and its empty fields are filled from the sources
+// in order. Fields overrides that per field with the ID of the person whose
+// value wins.
+type MergeRequest struct {
+	TargetID  uint            `json:"target_id" validate:"required" example:"12"`
+	SourceIDs []uint          `json:"source_ids" validate:"required,min=1,dive,required" example:"31"`
+	Fields    map[string]uint `json:"fields,omitempty"`
+}
+
+// MergeResult is the merged person together with the fields on which the
+// merged people disagreed.
+type MergeResult struct {
+	Person    Person   `json:"person"`
+	MergedIDs []uint   `json:"merged_ids"`
+	Conflicts []string `json:"conflicts,omitempty"`
+}
@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+const (
+	EventCreated  = "created"
+	EventUpdated  = "updated"
+	EventDeleted  = "deleted"
+	EventEnriched = "enriched"
+)
+
+// PersonEvent announces a change of a person that has been written. ID
+// increases with every event, so it can be used to resume a feed.
+type PersonEvent struct {
+	ID       uint64                 `json:"id"`
+	Type     string                 `json:"type" example:"created"`
+	PersonID uint                   `json:"person_id"`
+	Revision uint                   `json:"revision"`
+	Person   PersonSnapshot         `json:"person"`
+	Changes  map[string]FieldChange `json:"changes,omitempty"`
+	Actor    string                 `json:"actor"`
+	Source   string                 `json:"source" example:"api"`
+	Time     time.Time              `json:"time"`
+}
+
+// Subject returns the person as of the event, for matching against filters.
+func (e *PersonEvent) Subject() *Person {
+	return &Person{
+		ID:          e.PersonID,
+		Name:        e.Person.Name,
+		Surname:     e.Person.Surname,
+		Patronymic:  e.Person.Patronymic,
+		Gender:      e.Person.Gender,
+		Age:         e.Person.Age,
+		Nationality: e.Person.Nationality,
+		Version:     e.Revision,
+	}
+}
+
+// Commands a WebSocket client sends.
+const (
+	StreamSubscribe   = "subscribe"
+	StreamUnsubscribe = "unsubscribe"
+	StreamPing        = "ping"
+)
+
+// Messages the server sends over a WebSocket.
+const (
+	StreamSubscribed   = "subscribed"
+	StreamUnsubscribed = "unsubscribed"
+	StreamEvent        = "event"
+	StreamReset        = "reset"
+	StreamError        = "error"
+	StreamPong         = "pong"
+)
+
+// StreamCommand is a message from a WebSocket client. A subscription is
+// named by the client-chosen ID and selects events by person IDs, a filter
+// expression, or both.
+type StreamCommand struct {
+	Type   string `json:"type" example:"subscribe"`
+	ID     string `json:"id,omitempty" example:"adults"`
+	IDs    []uint `json:"ids,omitempty"`
+	Filter string `json:"filter,omitempty" example:"age >= 18"`
+}
+
+// StreamMessage is a message to a WebSocket client. Event messages list
+// the client's subscriptions that the event matched.
+type StreamMessage struct {
+	Type          string       `json:"type" example:"event"`
+	ID            string       `json:"id,omitempty"`
+	Subscriptions []string     `json:"subscriptions,omitempty"`
+	Event         *PersonEvent `json:"event,omitempty"`
+	Error         string       `json:"error,omitempty"`
+}
@@ -0,0 +1,120 @@
+package model
+
+import (
+	"strings"
+
+	"effective-mobile/pkg/filterexpr"
+	"effective-mobile/pkg/translit"
+)
+
+// PersonFilterFields are the attributes a filter expression may refer to.
+var PersonFilterFields = map[string]filterexpr.Type{
+	"id":          filterexpr.Int,
+	"name":        filterexpr.String,
+	"surname":     filterexpr.String,
+	"patronymic":  filterexpr.String,
+	"gender":      filterexpr.String,
+	"age":         filterexpr.Int,
+	"nationality": filterexpr.String,
+	"version":     filterexpr.Int,
+}
+
+// PersonFilter selects people by exact attribute values, an age range and
+// an optional filter expression over PersonFilterFields, all of which must
+// hold. Empty fields don't restrict the result. Limit and Offset paginate
+// list queries and are ignored by bulk operations.
+type PersonFilter struct {
+	Name        string
+	Surname     string
+	Patronymic  string
+	Gender      string
+	Nationality string
+	AgeMin      int
+	AgeMax      int
+	Expr        filterexpr.Node
+
+	// Sort orders list queries before the default order by ID.
+	Sort []SortField
+
+	Limit  int
+	Offset int
+}
+
+// IsEmpty reports whether the filter has no criteria and would match everyone.
+func (f PersonFilter) IsEmpty() bool {
+	return f.Name == "" && f.Surname == "" && f.Patronymic == "" &&
+		f.Gender == "" && f.Nationality == "" && f.AgeMin == 0 && f.AgeMax == 0 && f.Expr == nil
+}
+
+// Matches reports whether p satisfies the filter's criteria, evaluated the
+// same way the repository evaluates them in SQL. Limit, Offset and Sort are
+// ignored.
+func (f PersonFilter) Matches(p *Person) bool {
+	for _, c := range [][2]string{
+		{p.Name, f.Name},
+		{p.Surname, f.Surname},
+		{p.Patronymic, f.Patronymic},
+	} {
+		if c[1] != "" && translit.Normalize(c[0]) != translit.Normalize(c[1]) {
+			return false
+		}
+	}
+	if f.Gender != "" && !strings.EqualFold(p.Gender, f.Gender) {
+		return false
+	}
+	if f.Nationality != "" && !strings.EqualFold(p.Nationality, f.Nationality) {
+		return false
+	}
+	if (f.AgeMin > 0 && p.Age < f.AgeMin) || (f.AgeMax > 0 && p.Age > f.AgeMax) {
+		return false
+	}
+	if f.Expr != nil {
+		return filterexpr.Eval(normalizeNameValues(f.Expr), func(field string) any {
+			switch field {
+			case "id":
+				return int64(p.ID)
+			case "name":
+				return translit.Normalize(p.Name)
+			case "surname":
+				return translit.Normalize(p.Surname)
+			case "patronymic":
+				return translit.Normalize(p.Patronymic)
+			case "gender":
+				return p.Gender
+			case "age":
+				return int64(p.Age)
+			case "nationality":
+				return p.Nationality
+			case "version":
+				return int64(p.Version)
+			}
+			return nil
+		})
+	}
+	return true
+}
+
+// normalizeNameValues returns a copy of n whose name component values are
+// replaced by translit.Normalize of them, so that Matches compares names by
+// their search keys like the repository does.
+func normalizeNameValues(n filterexpr.Node) filterexpr.Node {
+	switch n := n.(type) {
+	case *filterexpr.And:
+		return &filterexpr.And{Left: normalizeNameValues(n.Left), Right: normalizeNameValues(n.Right), At: n.At}
+	case *filterexpr.Or:
+		return &filterexpr.Or{Left: normalizeNameValues(n.Left), Right: normalizeNameValues(n.Right), At: n.At}
+	case *filterexpr.Not:
+		return &filterexpr.Not{X: normalizeNameValues(n.X), At: n.At}
+	case *filterexpr.Compare:
+		switch n.Field {
+		case "name", "surname", "patronymic":
+			c := *n
+			c.Values = make([]any, len(n.Values))
+			for i, v := range n.Values {
+				c.Values[i] = translit.Normalize(v.(string))
+			}
+			return &c
+		}
+	}
+	return n
+}
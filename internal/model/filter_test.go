@@ -0,0 +1,31 @@
+package model_test
+
+import (
+	"testing"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/filterexpr"
+)
+
+func TestPersonFilterMatches_ExpressionComparesNameKeys(t *testing.T) {
+	p := &model.Person{Name: "Дмитрий", Surname: "Ушаков", Nationality: "RU"}
+
+	for _, tt := range []struct {
+		expr string
+		want bool
+	}{
+		{`name = "Dmitriy"`, true},
+		{`name = "Дмитрий"`, true},
+		{`surname in ("Ushakov", "Ivanov") and nationality = "ru"`, true},
+		{`name != "Dmitry"`, false},
+		{`name = "Dmitriy" and surname = "Ivanov"`, false},
+	} {
+		expr, err := filterexpr.Parse(tt.expr, model.PersonFilterFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (model.PersonFilter{Expr: expr}).Matches(p); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+const (
+	HistoryActionCreate   = "create"
+	HistoryActionEnrich   = "enrich"
+	HistoryActionUpdate   = "update"
+	HistoryActionDelete   = "delete"
+	HistoryActionRestore  = "restore"
+	HistoryActionPurge    = "purge"
+	HistoryActionRollback = "rollback"
+	HistoryActionMerge    = "merge"
+
+	HistorySourceAPI        = "api"
+	HistorySourceEnrichment = "enrichment"
+	HistorySourceImport     = "import"
+	HistorySourceRetention  = "retention"
+)
+
+// FieldChange holds the old and new value of one changed attribute.
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// PersonSnapshot is the editable state of a person at some revision.
+type PersonSnapshot struct {
+	Name        string `json:"name"`
+	Surname     string `json:"surname"`
+	Patronymic  string `json:"patronymic,omitempty"`
+	Gender      string `json:"gender,omitempty"`
+	Age         int    `json:"age,omitempty"`
+	Nationality string `json:"nationality,omitempty"`
+}
+
+// PersonHistory is one entry of a person's audit trail. Revision is the
+// person's version after the change.
+type PersonHistory struct {
+	ID        uint                   `json:"id" gorm:"primaryKey"`
+	PersonID  uint                   `json:"person_id" gorm:"index;not null"`
+	Revision  uint                   `json:"revision"`
+	Action    string                 `json:"action" example:"update"`
+	Source    string                 `json:"source" example:"api"`
+	Actor     string                 `json:"actor"`
+	Changes   map[string]FieldChange `json:"changes" gorm:"serializer:json"`
+	Snapshot  PersonSnapshot         `json:"snapshot" gorm:"serializer:json"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+func (PersonHistory) TableName() string {
+	return "person_history"
+}
+
+// Snapshot returns the editable state of the person.
+func (p *Person) Snapshot() PersonSnapshot {
+	return PersonSnapshot{
+		Name:        p.Name,
+		Surname:     p.Surname,
+		Patronymic:  p.Patronymic,
+		Gender:      p.Gender,
+		Age:         p.Age,
+		Nationality: p.Nationality,
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyRecord stores the first response produced for an
+// Idempotency-Key so that retries can be answered without side effects.
+// A zero StatusCode marks a request that is still being processed.
+type IdempotencyRecord struct {
+	Key         string      `gorm:"primaryKey;size:255"`
+	RequestHash string      `gorm:"not null"`
+	StatusCode  int         `gorm:"not null;default:0"`
+	Header      http.Header `gorm:"serializer:json"`
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
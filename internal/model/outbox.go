@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead"
+)
+
+// OutboxMessage is a person event stored in the same transaction as the
+// change it describes, until a dispatcher relays it. DispatchedAt is set
+// once a delivery to every sink is recorded, and Sequence numbers the
+// messages in the order they are dispatched. Sequence is the event ID: ID is
+// allocated at insert, and a message that commits late would get an ID
+// below ones that were already sent.
+type OutboxMessage struct {
+	ID           uint64          `gorm:"primaryKey"`
+	EventType    string          `gorm:"not null"`
+	PersonID     uint            `gorm:"not null"`
+	Payload      json.RawMessage `gorm:"type:jsonb;not null"`
+	Sequence     uint64          `gorm:"not null;default:0;index"`
+	CreatedAt    time.Time
+	DispatchedAt *time.Time `gorm:"index"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+// OutboxDelivery is the progress of one outbox message to one sink. Pending
+// deliveries are retried at NextAttemptAt; after too many failed attempts
+// the delivery is dead, a dead letter kept for inspection, and the sink
+// moves on to the next message.
+type OutboxDelivery struct {
+	MessageID     uint64 `gorm:"primaryKey;autoIncrement:false;index:idx_outbox_deliveries_sink,priority:3"`
+	Sink          string `gorm:"primaryKey;index:idx_outbox_deliveries_sink,priority:1"`
+	Status        string `gorm:"not null;index:idx_outbox_deliveries_sink,priority:2"`
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	UpdatedAt     time.Time
+
+	Message OutboxMessage `gorm:"-"`
+}
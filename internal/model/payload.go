@@ -4,6 +4,10 @@ type CreatePersonRequest struct {
 	Name       string `json:"name" validate:"required"`
 	Surname    string `json:"surname" validate:"required"`
 	Patronymic string `json:"patronymic"`
+
+	// Force skips the duplicate check. It is taken from the force query
+	// parameter.
+	Force bool `json:"-"`
 }
 
 type UpdatePersonRequest struct {
@@ -13,6 +17,80 @@ type UpdatePersonRequest struct {
 	Gender      string `json:"gender,omitempty"`
 	Age         int    `json:"age,omitempty"`
 	Nationality string `json:"nationality,omitempty"`
+
+	// Version is the expected current version taken from If-Match.
+	// Zero means the update is unconditional.
+	Version uint `json:"-"`
+}
+
+const (
+	BatchItemCreated = "created"
+	BatchItemInvalid = "invalid"
+	BatchItemFailed  = "failed"
+	BatchItemSkipped = "skipped"
+)
+
+// BatchItemResult reports the outcome of one item of a batch create request.
+type BatchItemResult struct {
+	Index  int     `json:"index"`
+	Status string  `json:"status" example:"created"`
+	Person *Person `json:"person,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+type RollbackRequest struct {
+	Revision uint `json:"revision" validate:"required"`
+}
+
+// BulkResult is returned by bulk update and delete. A dry run only reports
+// Matched together with the ConfirmToken required to run the operation.
+type BulkResult struct {
+	Matched      int64  `json:"matched"`
+	Affected     int64  `json:"affected"`
+	DryRun       bool   `json:"dry_run"`
+	ConfirmToken string `json:"confirm_token,omitempty"`
+}
+
+// ImportRow is one parsed CSV row. Pre-filled Gender, Age and Nationality
+// are kept as is; only the missing ones are enriched.
+type ImportRow struct {
+	Line        int
+	Person      CreatePersonRequest
+	Gender      string
+	Age         int
+	Nationality string
+}
+
+const (
+	ImportRowAccepted = "accepted"
+	ImportRowRejected = "rejected"
+)
+
+// ImportRowResult reports the outcome of one CSV row by its line number.
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	Status string `json:"status" example:"accepted"`
+	ID     uint   `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport summarises a CSV import.
+type ImportReport struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Rows     []ImportRowResult `json:"rows"`
+}
+
+// Add appends results to the report and updates the counters.
+func (r *ImportReport) Add(results ...ImportRowResult) {
+	for _, res := range results {
+		if res.Status == ImportRowAccepted {
+			r.Accepted++
+		} else {
+			r.Rejected++
+		}
+		r.Rows = append(r.Rows, res)
+	}
 }
 
 // type PersonResponse struct {
@@ -7,14 +7,52 @@ import (
 )
 
 type Person struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name"`
-	Surname     string         `json:"surname"`
-	Patronymic  string         `json:"patronymic,omitempty"`
-	Gender      string         `json:"gender,omitempty"`
-	Age         int            `json:"age,omitempty"`
-	Nationality string         `json:"nationality,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name"`
+	Surname     string `json:"surname"`
+	Patronymic  string `json:"patronymic,omitempty"`
+	Gender      string `json:"gender,omitempty"`
+	Age         int    `json:"age,omitempty"`
+	Nationality string `json:"nationality,omitempty"`
+
+	// Normalized romanized name components used to match names across
+	// Cyrillic and Latin spellings. See translit.Normalize.
+	NameTranslit       string `json:"-" gorm:"not null;default:'';index"`
+	SurnameTranslit    string `json:"-" gorm:"not null;default:'';index"`
+	PatronymicTranslit string `json:"-" gorm:"not null;default:''"`
+
+	// SurnamePhonetic is the sound-alike key of the surname used by
+	// phonetic search. See phonetic.Key.
+	SurnamePhonetic string `json:"-" gorm:"not null;default:'';index"`
+
+	// Sequence and ChangeXID place the latest change of the person in the
+	// change feed, see ChangePosition. The database sets them on every
+	// insert, versioned update and delete.
+	Sequence  uint64 `json:"-" gorm:"not null;default:0;index"`
+	ChangeXID uint64 `json:"-" gorm:"column:change_xid;->;-:migration"`
+
+	Version   uint           `json:"version" gorm:"not null;default:1"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// DeletedPerson is a person in the trash together with the time it was
+// deleted, which trash retention counts from.
+type DeletedPerson struct {
+	Person
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Search match modes.
+const (
+	SearchMatchFuzzy    = "fuzzy"
+	SearchMatchPhonetic = "phonetic"
+)
+
+// PersonSearchResult is a person found by fuzzy search with its relevance
+// score between 0 and 1.
+type PersonSearchResult struct {
+	Person `gorm:"embedded"`
+	Score  float64 `json:"score" example:"0.72"`
 }
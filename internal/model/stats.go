@@ -0,0 +1,44 @@
+package model
+
+// StatsOptions shapes the aggregates of PersonStats. AgeBuckets are the
+// ascending lower bounds of the age histogram buckets after the first one,
+// which starts at zero. TopNationalities limits the nationality ranking.
+type StatsOptions struct {
+	AgeBuckets       []int
+	TopNationalities int
+}
+
+// PersonStats are aggregates over the people matching a filter. People
+// without a known age are left out of the age histogram and averages.
+type PersonStats struct {
+	Total            int64              `json:"total" example:"1250"`
+	Genders          []GenderCount      `json:"genders"`
+	AgeHistogram     []AgeBucket        `json:"age_histogram"`
+	TopNationalities []NationalityCount `json:"top_nationalities"`
+	AverageAge       []AverageAge       `json:"average_age"`
+}
+
+type GenderCount struct {
+	Gender string `json:"gender" example:"female"`
+	Count  int64  `json:"count" example:"640"`
+}
+
+// AgeBucket counts people with Min <= age < Max. The last bucket has no Max.
+type AgeBucket struct {
+	Min   int   `json:"min" example:"18"`
+	Max   *int  `json:"max,omitempty" example:"30"`
+	Count int64 `json:"count" example:"312"`
+}
+
+type NationalityCount struct {
+	Nationality string `json:"nationality" example:"RU"`
+	Count       int64  `json:"count" example:"420"`
+}
+
+// AverageAge is the mean known age of people of one nationality and gender.
+type AverageAge struct {
+	Nationality string  `json:"nationality" example:"RU"`
+	Gender      string  `json:"gender" example:"male"`
+	Average     float64 `json:"average" example:"41.5"`
+	Count       int64   `json:"count" example:"210"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// PersonColumns are the person attributes a view can show and sort by.
+var PersonColumns = []string{"id", "name", "surname", "patronymic", "gender", "age", "nationality", "version", "created_at", "updated_at"}
+
+// SortField orders people by one of PersonColumns.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// SaveViewRequest saves a filter expression, sort order and column set
+// under a name. Sort is a comma-separated list of columns, each prefixed
+// with - for descending order. No columns means all of them.
+type SaveViewRequest struct {
+	Name    string   `json:"name" validate:"required,max=100,excludesall=/" example:"adults-ru"`
+	Filter  string   `json:"filter,omitempty" example:"age >= 18 and nationality = 'RU'"`
+	Sort    string   `json:"sort,omitempty" example:"-age,surname"`
+	Columns []string `json:"columns,omitempty" example:"id,name,surname,age"`
+}
+
+// View is one version of a saved view. Saving a view under an existing name
+// adds a version instead of overwriting it.
+type View struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_views_name_version"`
+	Version   uint      `json:"version" gorm:"not null;uniqueIndex:idx_views_name_version"`
+	Filter    string    `json:"filter,omitempty"`
+	Sort      string    `json:"sort,omitempty"`
+	Columns   []string  `json:"columns,omitempty" gorm:"serializer:json"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// CreateWebhookRequest registers a URL for person events. Without events the
+// webhook receives all of them; without a secret one is generated.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,startswith=http" example:"https://example.com/hooks/person"`
+	Events []string `json:"events" validate:"max=4,dive,oneof=created updated deleted enriched"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=255"`
+}
+
+// Webhook is a registered receiver of person events. The secret signs the
+// deliveries and is only returned when the webhook is created.
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	URL       string    `json:"url" gorm:"not null"`
+	Events    []string  `json:"events" gorm:"serializer:json"`
+	Secret    string    `json:"secret,omitempty" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Accepts reports whether the webhook subscribes to events of type t.
+func (w *Webhook) Accepts(t string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. Pending
+// deliveries are retried at NextAttemptAt until they succeed or run out of
+// attempts. A webhook has one delivery of an event besides its replays.
+type WebhookDelivery struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	WebhookID     uint            `json:"webhook_id" gorm:"index;not null;uniqueIndex:idx_webhook_deliveries_event,priority:1,where:replay_of IS NULL"`
+	EventID       uint64          `json:"event_id" gorm:"uniqueIndex:idx_webhook_deliveries_event,priority:2,where:replay_of IS NULL"`
+	EventType     string          `json:"event_type" example:"created"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:jsonb;not null" swaggertype:"object"`
+	Status        string          `json:"status" gorm:"not null;index:idx_webhook_deliveries_due,priority:1" example:"pending"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	ReplayOf      *uint           `json:"replay_of,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+// Package openapi holds the OpenAPI 3.1 document of the HTTP API and a
+// middleware that checks requests, and optionally responses, against it.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Document returns the OpenAPI document as JSON.
+func Document() []byte {
+	return document
+}
+
+// ServeDocument godoc
+// @Summary Документ OpenAPI 3.1
+// @Description Описание API в формате OpenAPI 3.1 с телами ошибок; по нему проверяются запросы, если включён OPENAPI_VALIDATE
+// @Tags openapi
+// @Produce json
+// @Success 200 {object} object
+// @Router /openapi.json [get]
+func ServeDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(document)
+}
@@ -0,0 +1,479 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"effective-mobile/pkg/logger"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.uber.org/zap"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	// documentURL names the document among the schema resources; schemas
+	// are compiled by JSON pointers into it.
+	documentURL = "https://localhost/openapi.json"
+
+	// maxBodySize bounds the JSON bodies buffered for validation.
+	maxBodySize = 10 << 20
+)
+
+var printer = message.NewPrinter(language.English)
+
+// Validator checks requests, and optionally responses, against the
+// operations of an OpenAPI document. Requests to paths the document does
+// not describe are passed through unchecked.
+type Validator struct {
+	routes    *http.ServeMux
+	ops       map[string]*operation
+	responses bool
+}
+
+type operation struct {
+	pattern      string
+	path         string
+	params       []parameter
+	body         content
+	bodyRequired bool
+	responses    map[string]content
+	// stream marks operations whose responses are streamed or hijacked
+	// and so cannot be buffered for validation.
+	stream bool
+}
+
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	typ      string
+	schema   *jsonschema.Schema
+}
+
+// content maps the media types of a body to their schemas. Bodies that
+// are not JSON have a nil schema and are not checked.
+type content map[string]*jsonschema.Schema
+
+type specDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]specParameter `json:"parameters"`
+	} `json:"components"`
+}
+
+type specOperation struct {
+	Parameters  []specParameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool                       `json:"required"`
+		Content  map[string]json.RawMessage `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]json.RawMessage `json:"content"`
+	} `json:"responses"`
+}
+
+type specParameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   struct {
+		Type string `json:"type"`
+	} `json:"schema"`
+}
+
+var methods = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true}
+
+// NewValidator compiles the operations of an OpenAPI 3.1 document, such as
+// Document(). Responses are not checked unless enabled with WithResponses.
+func NewValidator(doc []byte) (*Validator, error) {
+	var spec specDocument
+	if err := json.Unmarshal(doc, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	raw, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	if err := c.AddResource(documentURL, raw); err != nil {
+		return nil, err
+	}
+
+	v := &Validator{routes: http.NewServeMux(), ops: make(map[string]*operation)}
+	for path, item := range spec.Paths {
+		for method, data := range item {
+			if !methods[method] {
+				continue
+			}
+			var so specOperation
+			if err := json.Unmarshal(data, &so); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			op, err := compileOperation(c, &spec, "/paths/"+escapePointer(path)+"/"+method, &so)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			op.pattern = strings.ToUpper(method) + " " + path
+			op.path = path
+			v.routes.Handle(op.pattern, http.NotFoundHandler())
+			v.ops[op.pattern] = op
+		}
+	}
+	return v, nil
+}
+
+// WithResponses sets whether responses are checked too. A response that
+// does not match the document is logged and replaced with a 500, so this
+// is meant for tests and staging rather than production.
+func (v *Validator) WithResponses(enabled bool) *Validator {
+	v.responses = enabled
+	return v
+}
+
+func compileOperation(c *jsonschema.Compiler, spec *specDocument, ptr string, so *specOperation) (*operation, error) {
+	op := &operation{responses: make(map[string]content)}
+
+	for i, sp := range so.Parameters {
+		paramPtr := ptr + "/parameters/" + strconv.Itoa(i)
+		if sp.Ref != "" {
+			name, ok := strings.CutPrefix(sp.Ref, "#/components/parameters/")
+			if !ok {
+				return nil, fmt.Errorf("unsupported parameter reference %q", sp.Ref)
+			}
+			if sp, ok = spec.Components.Parameters[name]; !ok {
+				return nil, fmt.Errorf("unknown parameter %q", name)
+			}
+			paramPtr = "/components/parameters/" + escapePointer(name)
+		}
+		schema, err := c.Compile(documentURL + "#" + paramPtr + "/schema")
+		if err != nil {
+			return nil, err
+		}
+		op.params = append(op.params, parameter{
+			name:     sp.Name,
+			in:       sp.In,
+			required: sp.Required || sp.In == "path",
+			typ:      sp.Schema.Type,
+			schema:   schema,
+		})
+	}
+
+	if rb := so.RequestBody; rb != nil {
+		body, err := compileContent(c, ptr+"/requestBody", rb.Content)
+		if err != nil {
+			return nil, err
+		}
+		op.body, op.bodyRequired = body, rb.Required
+	}
+
+	for status, resp := range so.Responses {
+		body, err := compileContent(c, ptr+"/responses/"+status, resp.Content)
+		if err != nil {
+			return nil, err
+		}
+		op.responses[status] = body
+		if status == "101" || (strings.HasPrefix(status, "2") && len(body) > 0 && !body.hasJSON()) {
+			op.stream = true
+		}
+	}
+	return op, nil
+}
+
+func compileContent(c *jsonschema.Compiler, ptr string, media map[string]json.RawMessage) (content, error) {
+	body := make(content, len(media))
+	for mt := range media {
+		if !isJSON(mt) {
+			body[mt] = nil
+			continue
+		}
+		schema, err := c.Compile(documentURL + "#" + ptr + "/content/" + escapePointer(mt) + "/schema")
+		if err != nil {
+			return nil, err
+		}
+		body[mt] = schema
+	}
+	return body, nil
+}
+
+func (b content) hasJSON() bool {
+	for mt := range b {
+		if isJSON(mt) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware checks the requests to the operations of the document before
+// passing them to next. An invalid request is answered with 400, or 415
+// for a media type the operation does not accept.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := v.routes.Handler(r)
+		op := v.ops[pattern]
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if status, err := op.validateRequest(r); err != nil {
+			logger.Log.Warn("request does not match the OpenAPI document", zap.String("operation", op.pattern), zap.Error(err))
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if !v.responses || op.stream {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &responseBuffer{header: make(http.Header)}
+		next.ServeHTTP(buf, r)
+		if err := op.validateResponse(buf.statusCode(), buf.header, buf.body.Bytes()); err != nil {
+			logger.Log.Error("response does not match the OpenAPI document", zap.String("operation", op.pattern), zap.Error(err))
+			http.Error(w, "response does not match the OpenAPI document: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		buf.writeTo(w)
+	})
+}
+
+func (op *operation) validateRequest(r *http.Request) (int, error) {
+	var pathValues map[string]string
+	for _, p := range op.params {
+		var value string
+		switch p.in {
+		case "path":
+			if pathValues == nil {
+				pathValues = matchPath(op.path, r.URL)
+			}
+			value = pathValues[p.name]
+		case "query":
+			value = r.URL.Query().Get(p.name)
+		case "header":
+			value = r.Header.Get(p.name)
+		default:
+			continue
+		}
+		// The handlers read parameters with Get, so an empty one is absent.
+		if value == "" {
+			if p.required {
+				return http.StatusBadRequest, fmt.Errorf("missing %s parameter %q", p.in, p.name)
+			}
+			continue
+		}
+		if err := p.schema.Validate(p.parse(value)); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid %s parameter %q: %s", p.in, p.name, describe(err))
+		}
+	}
+
+	if op.body == nil {
+		return 0, nil
+	}
+	mt := mediaType(r.Header.Get("Content-Type"))
+	if mt == "" && op.body.hasJSON() {
+		mt = "application/json"
+	}
+	schema, ok := op.body[mt]
+	if !ok {
+		if r.ContentLength == 0 && !op.bodyRequired {
+			return 0, nil
+		}
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", mt)
+	}
+	if schema == nil {
+		return 0, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	switch {
+	case err != nil:
+		return http.StatusBadRequest, fmt.Errorf("failed to read body: %w", err)
+	case len(data) > maxBodySize:
+		return http.StatusRequestEntityTooLarge, errors.New("request body is too large to validate")
+	case len(data) == 0:
+		if op.bodyRequired {
+			return http.StatusBadRequest, errors.New("request body is required")
+		}
+		return 0, nil
+	}
+	if err := validateJSON(schema, data); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err)
+	}
+	return 0, nil
+}
+
+func (op *operation) validateResponse(status int, header http.Header, body []byte) error {
+	media, ok := op.responses[strconv.Itoa(status)]
+	if !ok {
+		if media, ok = op.responses["default"]; !ok {
+			return fmt.Errorf("undocumented status %d", status)
+		}
+	}
+	if len(media) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d must not have a body", status)
+		}
+		return nil
+	}
+
+	mt := mediaType(header.Get("Content-Type"))
+	schema, ok := media[mt]
+	if !ok {
+		return fmt.Errorf("undocumented content type %q for status %d", mt, status)
+	}
+	if schema == nil {
+		return nil
+	}
+	if err := validateJSON(schema, body); err != nil {
+		return fmt.Errorf("status %d: %w", status, err)
+	}
+	return nil
+}
+
+// parse converts a parameter to the JSON type of its schema; a value that
+// does not convert stays a string and fails validation.
+func (p *parameter) parse(value string) any {
+	switch p.typ {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return json.Number(value)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// matchPath returns the values of the parameters of the path template in
+// the request URL.
+func matchPath(template string, u *url.URL) map[string]string {
+	values := make(map[string]string)
+	names := strings.Split(template, "/")
+	segments := strings.Split(u.EscapedPath(), "/")
+	for i, name := range names {
+		if i >= len(segments) || !strings.HasPrefix(name, "{") {
+			continue
+		}
+		value, err := url.PathUnescape(segments[i])
+		if err != nil {
+			value = segments[i]
+		}
+		values[strings.Trim(name, "{}")] = value
+	}
+	return values
+}
+
+func validateJSON(schema *jsonschema.Schema, data []byte) error {
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := schema.Validate(v); err != nil {
+		return errors.New(describe(err))
+	}
+	return nil
+}
+
+// describe lists the failed leaf constraints of a validation error on one
+// line.
+func describe(err error) string {
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err.Error()
+	}
+	var msgs []string
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+			return
+		}
+		msg := e.ErrorKind.LocalizedString(printer)
+		if len(e.InstanceLocation) > 0 {
+			msg = "/" + strings.Join(e.InstanceLocation, "/") + ": " + msg
+		}
+		msgs = append(msgs, msg)
+	}
+	walk(ve)
+	return strings.Join(msgs, "; ")
+}
+
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mt
+}
+
+func isJSON(mt string) bool {
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// responseBuffer holds a response until it has been validated.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *responseBuffer) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
+
+func (b *responseBuffer) writeTo(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.statusCode())
+	w.Write(b.body.Bytes())
+}
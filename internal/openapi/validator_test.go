@@ -0,0 +1,181 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"effective-mobile/internal/openapi"
+	"effective-mobile/pkg/logger"
+)
+
+func init() {
+	logger.Init()
+}
+
+func operations(t *testing.T, doc []byte) []string {
+	t.Helper()
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(doc, &spec); err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for path, item := range spec.Paths {
+		for method := range item {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func TestDocumentCoversSwagger(t *testing.T) {
+	swagger, err := os.ReadFile("../../docs/swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(operations(t, swagger), "\n")
+	got := strings.Join(operations(t, openapi.Document()), "\n")
+	if got != want {
+		t.Errorf("operations of openapi.json differ from docs/swagger.json:\n%s\n\nwant:\n%s", got, want)
+	}
+}
+
+func newValidator(t *testing.T) *openapi.Validator {
+	t.Helper()
+	v, err := openapi.NewValidator(openapi.Document())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMiddleware_Requests(t *testing.T) {
+	var called bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		// The body is still readable after validation.
+		io.Copy(w, r.Body)
+	})
+	h := newValidator(t).Middleware(next)
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		wantStatus  int
+		wantError   string
+	}{
+		{"valid list", "GET", "/v1/person?limit=10&gender=female", "", "", http.StatusOK, ""},
+		{"limit not a number", "GET", "/v1/person?limit=ten", "", "", http.StatusBadRequest, `invalid query parameter "limit"`},
+		{"limit too large", "GET", "/v1/person?limit=5000", "", "", http.StatusBadRequest, `invalid query parameter "limit"`},
+		{"missing required query", "GET", "/v1/person/search", "", "", http.StatusBadRequest, `missing query parameter "q"`},
+		{"enum", "GET", "/v1/person/export?format=pdf", "", "", http.StatusBadRequest, `invalid query parameter "format"`},
+		{"path parameter", "GET", "/v1/person/abc", "", "", http.StatusBadRequest, `invalid path parameter "id"`},
+		{"header", "POST", "/v1/person", "application/json", `{"name":"A","surname":"B"}`, http.StatusBadRequest, `invalid header parameter "X-Actor"`},
+		{"valid body", "POST", "/v1/person", "application/json", `{"name":"A","surname":"B"}`, http.StatusOK, ""},
+		{"body without content type", "POST", "/v1/person", "", `{"name":"A","surname":"B"}`, http.StatusOK, ""},
+		{"missing property", "POST", "/v1/person", "application/json", `{"name":"A"}`, http.StatusBadRequest, "invalid request body"},
+		{"wrong type", "POST", "/v1/person/batch", "application/json", `[{"name":1}]`, http.StatusBadRequest, "/0/name"},
+		{"invalid JSON", "POST", "/v1/person", "application/json", `{`, http.StatusBadRequest, "invalid JSON"},
+		{"missing body", "POST", "/v1/person/merge", "application/json", "", http.StatusBadRequest, "request body is required"},
+		{"unsupported media type", "POST", "/v1/person", "application/xml", "<person/>", http.StatusUnsupportedMediaType, "unsupported content type"},
+		{"CSV import", "POST", "/v1/person/import", "text/csv", "name,surname\n", http.StatusOK, ""},
+		{"undocumented path", "GET", "/swagger/index.html", "", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = false
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.name == "header" {
+				req.Header.Set("X-Actor", strings.Repeat("a", 256))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantError != "" {
+				if called {
+					t.Error("expected the request not to reach the handler")
+				}
+				if !strings.Contains(rec.Body.String(), tt.wantError) {
+					t.Errorf("expected error containing %q, got %q", tt.wantError, rec.Body.String())
+				}
+			} else if rec.Body.String() != tt.body {
+				t.Errorf("expected the handler to read body %q, got %q", tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddleware_Responses(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		handler    http.HandlerFunc
+		wantStatus int
+	}{
+		{"valid", "/v1/person/1", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"1"`)
+			io.WriteString(w, `{"id":1,"name":"A","surname":"B","version":1,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}`)
+		}, http.StatusOK},
+		{"documented error", "/v1/person/1", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "person not found", http.StatusNotFound)
+		}, http.StatusNotFound},
+		{"not modified", "/v1/person/1", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		}, http.StatusNotModified},
+		{"missing field", "/v1/person/1", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"id":1,"name":"A"}`)
+		}, http.StatusInternalServerError},
+		{"undocumented status", "/v1/person/1", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "conflict", http.StatusConflict)
+		}, http.StatusInternalServerError},
+		{"undocumented content type", "/v1/person/1", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<p>hi</p>")
+		}, http.StatusInternalServerError},
+		{"streams are not buffered", "/v1/person/export?format=csv", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/csv")
+			w.(http.Flusher).Flush()
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newValidator(t).WithResponses(true).Middleware(tt.handler)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusInternalServerError && !strings.Contains(rec.Body.String(), "response does not match") {
+				t.Errorf("unexpected body %q", rec.Body.String())
+			}
+		})
+	}
+
+	// Without WithResponses the response is passed through.
+	h := newValidator(t).Middleware(tests[3].handler)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/person/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 without response validation, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,185 @@
+// Package outbox relays the person events stored in the outbox table to
+// sinks such as the live feeds, webhooks and message brokers.
+//
+// Events are written to the outbox in the same transaction as the change
+// they describe, so they survive a crash right after the change. Every sink
+// keeps its own progress, so a failing sink only holds up itself. Delivery
+// is at least once: a failed batch is sent to its sink again, so sinks
+// should tolerate repeated event IDs. A batch that still fails after the
+// maximum number of attempts is dead-lettered and the sink moves on.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize   = 100
+	defaultMaxAttempts = 10
+	retryBase          = 5 * time.Second
+	retryMax           = 10 * time.Minute
+	deliveryLease      = time.Minute
+)
+
+// Sink receives dispatched events, ordered by ID within a batch and across
+// batches.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, events []model.PersonEvent) error
+}
+
+// Dispatcher relays outbox messages to its sinks in batches.
+type Dispatcher struct {
+	repo        repository.OutboxRepositoryInterface
+	sinks       []Sink
+	batch       int
+	maxAttempts int
+	wake        chan struct{}
+}
+
+func NewDispatcher(repo repository.OutboxRepositoryInterface, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		sinks:       sinks,
+		batch:       defaultBatchSize,
+		maxAttempts: defaultMaxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// WithMaxAttempts sets how many times a batch is sent to a sink before it
+// is dead-lettered.
+func (d *Dispatcher) WithMaxAttempts(n int) *Dispatcher {
+	d.maxAttempts = n
+	return d
+}
+
+// Notify makes a running dispatcher check the outbox right away instead of
+// at its next interval.
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays messages until ctx is done. It checks the outbox every
+// interval and whenever Notify is called. Dispatched messages are deleted
+// after retention.
+func (d *Dispatcher) Run(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	purged := time.Now()
+
+	for {
+		for {
+			n, err := d.DispatchOnce(ctx)
+			if err != nil {
+				logger.Log.Error("outbox dispatch failed", zap.Error(err))
+			}
+			// A failed sink waits for its retry, the others carry on.
+			if n < d.batch {
+				break
+			}
+		}
+
+		if time.Since(purged) > time.Hour {
+			purged = time.Now()
+			if n, err := d.repo.PurgeDispatchedBefore(purged.Add(-retention)); err != nil {
+				logger.Log.Error("outbox purge failed", zap.Error(err))
+			} else if n > 0 {
+				logger.Log.Info("purged dispatched outbox messages", zap.Int64("count", n))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DispatchOnce hands one batch of new messages to the sinks, then sends
+// each sink one batch of its pending messages. It returns the size of the
+// largest batch, and the errors of the sinks that failed.
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	names := make([]string, len(d.sinks))
+	for i, s := range d.sinks {
+		names[i] = s.Name()
+	}
+	n, err := d.repo.FanOut(names, d.batch)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, s := range d.sinks {
+		sent, err := d.deliver(ctx, s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", s.Name(), err))
+		}
+		n = max(n, sent)
+	}
+	return n, errors.Join(errs...)
+}
+
+// deliver sends s one batch of its pending messages and records the
+// outcome.
+func (d *Dispatcher) deliver(ctx context.Context, s Sink) (int, error) {
+	deliveries, err := d.repo.ClaimDeliveries(s.Name(), time.Now(), deliveryLease, d.batch)
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	ids := make([]uint64, len(deliveries))
+	events := make([]model.PersonEvent, len(deliveries))
+	for i := range deliveries {
+		m := &deliveries[i].Message
+		ids[i] = deliveries[i].MessageID
+		if err = json.Unmarshal(m.Payload, &events[i]); err != nil {
+			err = fmt.Errorf("outbox message %d: %w", deliveries[i].MessageID, err)
+			break
+		}
+		events[i].ID = m.Sequence
+	}
+	if err == nil {
+		err = s.Send(ctx, events)
+	}
+	if err == nil {
+		return len(deliveries), d.repo.MarkDelivered(s.Name(), ids)
+	}
+
+	attempt := deliveries[0].Attempts + 1
+	dead, markErr := d.repo.MarkFailed(s.Name(), ids, err.Error(), time.Now().Add(backoff(attempt)), d.maxAttempts)
+	if markErr != nil {
+		return len(deliveries), errors.Join(err, markErr)
+	}
+	if dead > 0 {
+		logger.Log.Error("outbox messages dead-lettered",
+			zap.String("sink", s.Name()), zap.Uint64("first_id", ids[0]), zap.Int64("count", dead), zap.Error(err))
+	}
+	return len(deliveries), err
+}
+
+// backoff returns the delay before the given attempt of a failed batch:
+// exponential from retryBase up to retryMax, with jitter.
+func backoff(attempt int) time.Duration {
+	d := retryMax
+	if attempt < 20 {
+		d = min(retryBase<<(attempt-1), retryMax)
+	}
+	return d + mathrand.N(d/5+1)
+}
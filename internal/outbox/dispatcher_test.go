@@ -0,0 +1,229 @@
+package outbox_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/outbox"
+	"effective-mobile/pkg/logger"
+)
+
+func init() {
+	logger.Init()
+}
+
+// memOutbox keeps a delivery of every message per sink, like the tables.
+type memOutbox struct {
+	messages   []model.OutboxMessage
+	dispatched int
+	sequence   uint64
+	deliveries []model.OutboxDelivery
+}
+
+func (m *memOutbox) FanOut(sinks []string, limit int) (int, error) {
+	batch := m.messages[m.dispatched:min(m.dispatched+limit, len(m.messages))]
+	for i := range batch {
+		m.sequence++
+		batch[i].Sequence = m.sequence
+		msg := batch[i]
+		for _, sink := range sinks {
+			m.deliveries = append(m.deliveries, model.OutboxDelivery{MessageID: msg.ID, Sink: sink, Status: model.OutboxPending, Message: msg})
+		}
+	}
+	m.dispatched += len(batch)
+	return len(batch), nil
+}
+
+func (m *memOutbox) ClaimDeliveries(sink string, now time.Time, lease time.Duration, limit int) ([]model.OutboxDelivery, error) {
+	var claimed []model.OutboxDelivery
+	for _, d := range m.deliveries {
+		if d.Sink == sink && d.Status == model.OutboxPending && len(claimed) < limit {
+			claimed = append(claimed, d)
+		}
+	}
+	if len(claimed) > 0 && claimed[0].NextAttemptAt.After(now) {
+		return nil, nil
+	}
+	return claimed, nil
+}
+
+func (m *memOutbox) update(sink string, ids []uint64, fn func(d *model.OutboxDelivery)) {
+	for i := range m.deliveries {
+		for _, id := range ids {
+			if m.deliveries[i].Sink == sink && m.deliveries[i].MessageID == id {
+				fn(&m.deliveries[i])
+			}
+		}
+	}
+}
+
+func (m *memOutbox) MarkDelivered(sink string, ids []uint64) error {
+	m.update(sink, ids, func(d *model.OutboxDelivery) { d.Status = model.OutboxDelivered })
+	return nil
+}
+
+func (m *memOutbox) MarkFailed(sink string, ids []uint64, cause string, retryAt time.Time, maxAttempts int) (int64, error) {
+	var dead int64
+	m.update(sink, ids, func(d *model.OutboxDelivery) {
+		d.Attempts++
+		d.LastError, d.NextAttemptAt = cause, retryAt
+		if d.Attempts >= maxAttempts {
+			d.Status = model.OutboxDead
+			dead++
+		}
+	})
+	return dead, nil
+}
+
+// retryNow makes every pending delivery due.
+func (m *memOutbox) retryNow() {
+	for i := range m.deliveries {
+		m.deliveries[i].NextAttemptAt = time.Time{}
+	}
+}
+
+func (m *memOutbox) status(sink string, id uint64) string {
+	for _, d := range m.deliveries {
+		if d.Sink == sink && d.MessageID == id {
+			return d.Status
+		}
+	}
+	return ""
+}
+
+func (m *memOutbox) LastDispatchedID() (uint64, error)                { return 0, nil }
+func (m *memOutbox) PurgeDispatchedBefore(t time.Time) (int64, error) { return 0, nil }
+
+type recordingSink struct {
+	name   string
+	events []model.PersonEvent
+	err    error
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Send(ctx context.Context, events []model.PersonEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+func message(id uint64, ev model.PersonEvent) model.OutboxMessage {
+	payload, _ := json.Marshal(ev)
+	return model.OutboxMessage{ID: id, EventType: ev.Type, PersonID: ev.PersonID, Payload: payload}
+}
+
+func TestDispatchOnce_NumbersEventsInDispatchOrder(t *testing.T) {
+	repo := &memOutbox{sequence: 4, messages: []model.OutboxMessage{
+		message(9, model.PersonEvent{Type: model.EventUpdated, PersonID: 1, Revision: 2}),
+	}}
+	sink := &recordingSink{name: "recording"}
+	d := outbox.NewDispatcher(repo, sink)
+
+	n, err := d.DispatchOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("expected one dispatched message, got %d %v", n, err)
+	}
+
+	// A message with a lower ID that committed after 9 was dispatched
+	// still gets the next event ID.
+	repo.messages = append(repo.messages, message(7, model.PersonEvent{Type: model.EventCreated, PersonID: 2}))
+	if _, err := d.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.events) != 2 || sink.events[0].ID != 5 || sink.events[1].ID != 6 ||
+		sink.events[0].Revision != 2 || sink.events[1].PersonID != 2 {
+		t.Fatalf("expected events numbered in dispatch order, got %+v", sink.events)
+	}
+}
+
+func TestDispatchOnce_FailingSinkDoesNotHoldUpOthers(t *testing.T) {
+	repo := &memOutbox{messages: []model.OutboxMessage{message(1, model.PersonEvent{Type: model.EventDeleted})}}
+	failing := &recordingSink{name: "failing", err: errors.New("unavailable")}
+	after := &recordingSink{name: "after"}
+	d := outbox.NewDispatcher(repo, failing, after)
+
+	if _, err := d.DispatchOnce(context.Background()); err == nil {
+		t.Fatal("expected the sink error")
+	}
+	if len(after.events) != 1 || repo.status("after", 1) != model.OutboxDelivered {
+		t.Fatalf("expected the other sink to get the event, got %+v", after.events)
+	}
+	if repo.status("failing", 1) != model.OutboxPending {
+		t.Fatalf("expected the failed delivery to stay pending, got %q", repo.status("failing", 1))
+	}
+
+	// The retry waits for its backoff.
+	failing.err = nil
+	if _, err := d.DispatchOnce(context.Background()); err != nil || len(failing.events) != 0 {
+		t.Fatalf("expected no retry before the backoff, got %v %+v", err, failing.events)
+	}
+
+	repo.retryNow()
+	if _, err := d.DispatchOnce(context.Background()); err != nil || len(failing.events) != 1 || len(after.events) != 1 {
+		t.Fatalf("expected the retry to reach only the failed sink, got %v", err)
+	}
+}
+
+func TestDispatchOnce_DeadLettersAfterMaxAttempts(t *testing.T) {
+	repo := &memOutbox{messages: []model.OutboxMessage{message(1, model.PersonEvent{Type: model.EventCreated})}}
+	sink := &recordingSink{name: "broker", err: errors.New("rejected")}
+	d := outbox.NewDispatcher(repo, sink).WithMaxAttempts(2)
+
+	for range 2 {
+		if _, err := d.DispatchOnce(context.Background()); err == nil {
+			t.Fatal("expected the sink error")
+		}
+		repo.retryNow()
+	}
+	if got := repo.status("broker", 1); got != model.OutboxDead {
+		t.Fatalf("expected a dead letter, got %q", got)
+	}
+
+	// The sink moves on to later messages.
+	sink.err = nil
+	repo.messages = append(repo.messages, message(2, model.PersonEvent{Type: model.EventUpdated}))
+	if _, err := d.DispatchOnce(context.Background()); err != nil || len(sink.events) != 1 || sink.events[0].ID != 2 {
+		t.Fatalf("expected the next message to be sent, got %v %+v", err, sink.events)
+	}
+}
+
+func TestBrokerSink_FileBroker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	file, err := outbox.NewFileBroker(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := outbox.BrokerSink{Broker: file, Prefix: "person"}
+
+	err = sink.Send(context.Background(), []model.PersonEvent{{ID: 3, Type: model.EventEnriched, PersonID: 5}})
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ := os.Open(path)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	var line struct {
+		Subject string            `json:"subject"`
+		Data    model.PersonEvent `json:"data"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Subject != "person.enriched" || line.Data.ID != 3 || line.Data.PersonID != 5 {
+		t.Fatalf("unexpected message %+v", line)
+	}
+}
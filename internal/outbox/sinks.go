@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Publisher is anything that takes events without failing, such as the
+// live feed broker.
+type Publisher interface {
+	Publish(events ...model.PersonEvent)
+}
+
+type publisherSink struct {
+	name string
+	p    Publisher
+}
+
+// PublisherSink adapts p to a sink.
+func PublisherSink(name string, p Publisher) Sink {
+	return publisherSink{name: name, p: p}
+}
+
+func (s publisherSink) Name() string { return s.name }
+
+func (s publisherSink) Send(ctx context.Context, events []model.PersonEvent) error {
+	s.p.Publish(events...)
+	return nil
+}
+
+// LogSink writes every event to the application log.
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Send(ctx context.Context, events []model.PersonEvent) error {
+	for _, ev := range events {
+		logger.Log.Info("person event",
+			zap.Uint64("id", ev.ID), zap.String("type", ev.Type), zap.Uint("person_id", ev.PersonID),
+			zap.Uint("revision", ev.Revision), zap.String("actor", ev.Actor), zap.String("source", ev.Source))
+	}
+	return nil
+}
+
+// MessageBroker publishes raw messages on subjects. Its method set matches
+// the NATS client's Publish, so a *nats.Conn can be used directly.
+type MessageBroker interface {
+	Publish(subject string, data []byte) error
+}
+
+// BrokerSink publishes each event as JSON on the subject
+// "<prefix>.<event type>", for example "person.created".
+type BrokerSink struct {
+	Broker MessageBroker
+	Prefix string
+}
+
+func (s BrokerSink) Name() string { return "broker" }
+
+func (s BrokerSink) Send(ctx context.Context, events []model.PersonEvent) error {
+	for i := range events {
+		data, err := json.Marshal(&events[i])
+		if err != nil {
+			return err
+		}
+		if err := s.Broker.Publish(s.Prefix+"."+events[i].Type, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileBroker is a MessageBroker that appends messages to a local file as
+// JSON lines of subject and data. It stands in for a real broker in
+// development and tests.
+type FileBroker struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileBroker(path string) (*FileBroker, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBroker{file: f}, nil
+}
+
+func (b *FileBroker) Publish(subject string, data []byte) error {
+	line, err := json.Marshal(struct {
+		Subject string          `json:"subject"`
+		Data    json.RawMessage `json:"data"`
+	}{subject, data})
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return b.file.Sync()
+}
+
+func (b *FileBroker) Close() error {
+	return b.file.Close()
+}
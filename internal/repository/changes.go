@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// changedAfter selects the changes after since, see
+// model.ChangePosition. Changes of transactions that may still be running
+// are left for a later read: they could commit with a lower sequence than
+// changes that are already visible.
+const changedAfter = "(change_xid, sequence) > (?::text::xid8, ?) AND change_xid < pg_snapshot_xmin(pg_current_snapshot())"
+
+// FindChanges returns up to limit people changed after since, deleted ones
+// included, and up to limit tombstones of people purged after it, each in
+// feed order. Both are read from one snapshot. With since zero the result
+// is a full download, so only live people are returned.
+func (r *PersonRepository) FindChanges(since model.ChangePosition, limit int) ([]model.Person, []model.PersonTombstone, error) {
+	var people []model.Person
+	var tombstones []model.PersonTombstone
+	xid := strconv.FormatUint(since.XID, 10)
+	full := since == model.ChangePosition{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		q := tx.Unscoped().Where(changedAfter, xid, since.Sequence)
+		if full {
+			q = q.Where("deleted_at IS NULL")
+		}
+		if err := q.Order("change_xid, sequence").Limit(limit).Find(&people).Error; err != nil {
+			return err
+		}
+		if full {
+			return nil
+		}
+		return tx.Where(changedAfter, xid, since.Sequence).Order("change_xid, sequence").Limit(limit).Find(&tombstones).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	return people, tombstones, err
+}
+
+// CheckChangeFeed fails unless the triggers that maintain the change feed
+// are installed. AutoMigrate creates the columns but not the triggers, which
+// come with migrations/00005_person_changes.sql and
+// migrations/00009_person_changes_xid.sql; without them the feed stays
+// empty.
+func (r *PersonRepository) CheckChangeFeed() error {
+	var ok bool
+	err := r.db.Raw(`SELECT
+		EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'people'::regclass AND tgname = 'people_changes_sequence')
+		AND EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'people'::regclass AND tgname = 'people_changes_tombstone')
+		AND EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'person_tombstones' AND column_name = 'change_xid')`).
+		Scan(&ok).Error
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("change feed triggers are missing, apply the migrations with make local-migration-up")
+	}
+	return nil
+}
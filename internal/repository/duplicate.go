@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// duplicateKey is what likely duplicates have in common: the normalized full
+// name. Gender and nationality are left out: enrichment derives them from the
+// name, and a new person is checked before it is enriched.
+const duplicateKey = "name_translit, surname_translit, patronymic_translit"
+
+// FindDuplicates returns up to limit stored people with the duplicate key
+// of p.
+func (r *PersonRepository) FindDuplicates(p *model.Person, limit int) ([]model.Person, error) {
+	var people []model.Person
+	err := r.db.
+		Where("name_translit = ? AND surname_translit = ? AND patronymic_translit = ?", p.NameTranslit, p.SurnameTranslit, p.PatronymicTranslit).
+		Where("id <> ?", p.ID).
+		Order("id").Limit(limit).Find(&people).Error
+	return people, err
+}
+
+type duplicateRow struct {
+	model.Person `gorm:"embedded"`
+	ClusterID    uint
+}
+
+// FindDuplicateClusters groups the people matching filter by duplicate key
+// and returns the groups with more than one member, ordered by their
+// smallest ID. The filter's limit and offset paginate the groups.
+func (r *PersonRepository) FindDuplicateClusters(filter model.PersonFilter) ([]model.DuplicateCluster, error) {
+	window := "OVER (PARTITION BY " + duplicateKey + ")"
+	members := applyFilter(r.db.Model(&model.Person{}).
+		Select("people.*, COUNT(*) "+window+" AS cluster_size, MIN(id) "+window+" AS cluster_id"), filter)
+	ranked := r.db.Table("(?) AS d", members).
+		Select("d.*, DENSE_RANK() OVER (ORDER BY cluster_id) AS cluster_no").
+		Where("cluster_size > 1")
+
+	q := r.db.Table("(?) AS c", ranked).Order("cluster_id, id")
+	if filter.Offset > 0 {
+		q = q.Where("cluster_no > ?", filter.Offset)
+	}
+	if filter.Limit > 0 {
+		q = q.Where("cluster_no <= ?", filter.Offset+filter.Limit)
+	}
+
+	var rows []duplicateRow
+	if err := q.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	clusters := []model.DuplicateCluster{}
+	for _, row := range rows {
+		if n := len(clusters); n == 0 || clusters[n-1].ID != row.ClusterID {
+			clusters = append(clusters, model.DuplicateCluster{ID: row.ClusterID})
+		}
+		last := &clusters[len(clusters)-1]
+		last.Persons = append(last.Persons, row.Person)
+	}
+	return clusters, nil
+}
+
+// Merge saves the merged target and soft-deletes the sources in one
+// transaction. Every row must still have the version it was read with.
+func (r *PersonRepository) Merge(target *model.Person, sources []model.Person) error {
+	version := target.Version
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, target); err != nil {
+			return err
+		}
+		for _, s := range sources {
+			res := tx.Where("version = ?", s.Version).Delete(&model.Person{}, s.ID)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrVersionConflict
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		target.Version = version
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"effective-mobile/pkg/filterexpr"
+	"effective-mobile/pkg/translit"
+)
+
+// exprColumns maps the fields of model.PersonFilterFields to columns. Only
+// these columns can ever appear in SQL compiled from a filter expression.
+var exprColumns = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"surname":     "surname",
+	"patronymic":  "patronymic",
+	"gender":      "gender",
+	"age":         "age",
+	"nationality": "nationality",
+	"version":     "version",
+}
+
+// exprKeys maps name components to their search keys. Like the name filters
+// and search, comparisons of names are made on translit.Normalize of both
+// sides, so "Дмитрий" and "Dmitriy" select the same people.
+var exprKeys = map[string]string{
+	"name":       "name_translit",
+	"surname":    "surname_translit",
+	"patronymic": "patronymic_translit",
+}
+
+// compileExpr translates a parsed filter expression into a SQL condition
+// with placeholders for every value. Names are compared by their search
+// keys, other strings case-insensitively, like the other list filters.
+func compileExpr(n filterexpr.Node) (string, []any, error) {
+	switch n := n.(type) {
+	case *filterexpr.And:
+		return compileBinary("AND", n.Left, n.Right)
+	case *filterexpr.Or:
+		return compileBinary("OR", n.Left, n.Right)
+	case *filterexpr.Not:
+		sql, args, err := compileExpr(n.X)
+		return "NOT (" + sql + ")", args, err
+	case *filterexpr.Compare:
+		return compileCompare(n)
+	}
+	return "", nil, fmt.Errorf("unsupported filter expression %T", n)
+}
+
+func compileBinary(op string, left, right filterexpr.Node) (string, []any, error) {
+	l, largs, err := compileExpr(left)
+	if err != nil {
+		return "", nil, err
+	}
+	r, rargs, err := compileExpr(right)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + l + " " + op + " " + r + ")", append(largs, rargs...), nil
+}
+
+func compileCompare(n *filterexpr.Compare) (string, []any, error) {
+	column, ok := exprColumns[n.Field]
+	if !ok {
+		return "", nil, fmt.Errorf("field %q can't be filtered", n.Field)
+	}
+
+	values := n.Values
+	if key, ok := exprKeys[n.Field]; ok {
+		column = key
+		values = make([]any, len(n.Values))
+		for i, v := range n.Values {
+			values[i] = translit.Normalize(v.(string))
+		}
+	} else if n.Type == filterexpr.String {
+		column = "LOWER(COALESCE(" + column + ", ''))"
+		values = make([]any, len(n.Values))
+		for i, v := range n.Values {
+			values[i] = strings.ToLower(v.(string))
+		}
+	}
+
+	switch n.Op {
+	case filterexpr.OpIn:
+		return column + " IN ?", []any{values}, nil
+	case filterexpr.OpNotIn:
+		return column + " NOT IN ?", []any{values}, nil
+	case filterexpr.OpNe:
+		return column + " <> ?", values, nil
+	case filterexpr.OpEq, filterexpr.OpLt, filterexpr.OpLe, filterexpr.OpGt, filterexpr.OpGe:
+		return column + " " + n.Op + " ?", values, nil
+	}
+	return "", nil, fmt.Errorf("unsupported operator %q", n.Op)
+}
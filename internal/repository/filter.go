@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"fmt"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/translit"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// applyFilter adds the filter criteria to q. Name components are matched by
+// their normalized romanization, so a Latin spelling finds Cyrillic records
+// and vice versa. Other string attributes are compared case-insensitively.
+// The filter expression is compiled to a parameterized condition.
+func applyFilter(q *gorm.DB, f model.PersonFilter) *gorm.DB {
+	for column, value := range map[string]string{
+		"name_translit":       f.Name,
+		"surname_translit":    f.Surname,
+		"patronymic_translit": f.Patronymic,
+	} {
+		if value != "" {
+			q = q.Where(column+" = ?", translit.Normalize(value))
+		}
+	}
+	for column, value := range map[string]string{
+		"gender":      f.Gender,
+		"nationality": f.Nationality,
+	} {
+		if value != "" {
+			q = q.Where("LOWER("+column+") = LOWER(?)", value)
+		}
+	}
+	if f.AgeMin > 0 {
+		q = q.Where("age >= ?", f.AgeMin)
+	}
+	if f.AgeMax > 0 {
+		q = q.Where("age <= ?", f.AgeMax)
+	}
+	if f.Expr != nil {
+		sql, args, err := compileExpr(f.Expr)
+		if err != nil {
+			q.AddError(err)
+			return q
+		}
+		q = q.Where(sql, args...)
+	}
+	return q
+}
+
+// sortColumns maps model.PersonColumns to the columns list queries can be
+// sorted by.
+var sortColumns = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"surname":     "surname",
+	"patronymic":  "patronymic",
+	"gender":      "gender",
+	"age":         "age",
+	"nationality": "nationality",
+	"version":     "version",
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+}
+
+// orderBy applies the filter's sort fields to q, followed by the ID so that
+// pages are stable.
+func orderBy(q *gorm.DB, f model.PersonFilter) *gorm.DB {
+	for _, s := range f.Sort {
+		column, ok := sortColumns[s.Field]
+		if !ok {
+			q.AddError(fmt.Errorf("can't sort by %q", s.Field))
+			return q
+		}
+		q = q.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: s.Desc})
+	}
+	return q.Order("id")
+}
+
+// paginate applies the filter's limit and offset to q.
+func paginate(q *gorm.DB, f model.PersonFilter) *gorm.DB {
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		q = q.Offset(f.Offset)
+	}
+	return q
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"errors"
+
+	"effective-mobile/database"
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// HistoryRepositoryInterface reads the audit trail. Entries are written by
+// PersonRepositoryInterface.AppendHistory, in the transaction of the change.
+type HistoryRepositoryInterface interface {
+	FindByPerson(personID uint) ([]model.PersonHistory, error)
+	FindRevision(personID uint, revision uint) (*model.PersonHistory, error)
+}
+
+type HistoryRepository struct {
+	db *database.DB
+}
+
+func NewHistoryRepository(db *database.DB) *HistoryRepository {
+	return &HistoryRepository{db: db}
+}
+
+// FindByPerson returns the person's history, oldest entry first.
+func (r *HistoryRepository) FindByPerson(personID uint) ([]model.PersonHistory, error) {
+	var entries []model.PersonHistory
+	err := r.db.Where("person_id = ?", personID).Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// FindRevision returns the latest entry that produced the given revision.
+func (r *HistoryRepository) FindRevision(personID uint, revision uint) (*model.PersonHistory, error) {
+	var entry model.PersonHistory
+	err := r.db.Where("person_id = ? AND revision = ?", personID, revision).Order("id DESC").First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
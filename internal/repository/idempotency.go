@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"effective-mobile/database"
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepositoryInterface interface {
+	Reserve(rec *model.IdempotencyRecord) (bool, error)
+	FindByKey(key string) (*model.IdempotencyRecord, error)
+	Complete(rec *model.IdempotencyRecord) error
+	Release(key string) error
+}
+
+type IdempotencyRepository struct {
+	db *database.DB
+}
+
+func NewIdempotencyRepository(db *database.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve inserts an in-progress record for rec.Key. It returns false if the
+// key is already held by a record that has not expired yet.
+func (r *IdempotencyRepository) Reserve(rec *model.IdempotencyRecord) (bool, error) {
+	if err := r.db.Where("expires_at < ?", time.Now()).Delete(&model.IdempotencyRecord{}).Error; err != nil {
+		return false, err
+	}
+
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *IdempotencyRepository) FindByKey(key string) (*model.IdempotencyRecord, error) {
+	var rec model.IdempotencyRecord
+	err := r.db.Where("key = ? AND expires_at >= ?", key, time.Now()).First(&rec).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (r *IdempotencyRepository) Complete(rec *model.IdempotencyRecord) error {
+	return r.db.Model(rec).Select("status_code", "header", "body").Updates(rec).Error
+}
+
+func (r *IdempotencyRepository) Release(key string) error {
+	return r.db.Where("key = ?", key).Delete(&model.IdempotencyRecord{}).Error
+}
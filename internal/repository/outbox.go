@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"time"
+
+	"effective-mobile/database"
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepositoryInterface interface {
+	FanOut(sinks []string, limit int) (int, error)
+	ClaimDeliveries(sink string, now time.Time, lease time.Duration, limit int) ([]model.OutboxDelivery, error)
+	MarkDelivered(sink string, ids []uint64) error
+	MarkFailed(sink string, ids []uint64, cause string, retryAt time.Time, maxAttempts int) (int64, error)
+	LastDispatchedID() (uint64, error)
+	PurgeDispatchedBefore(t time.Time) (int64, error)
+}
+
+type OutboxRepository struct {
+	db *database.DB
+}
+
+func NewOutboxRepository(db *database.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// FanOut takes up to limit undispatched messages, oldest first, records a
+// pending delivery of each to every sink, marks them dispatched and numbers
+// them after the last dispatched message. It returns the number of
+// messages. Fan-outs hold a lock until they commit, so sequences become
+// visible in order: a reader that has seen one has seen all below it.
+func (r *OutboxRepository) FanOut(sinks []string, limit int) (int, error) {
+	var messages []model.OutboxMessage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(0, hashtext('outbox_fan_out'))").Error; err != nil {
+			return err
+		}
+		err := tx.Where("dispatched_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		var last uint64
+		if err := tx.Model(&model.OutboxMessage{}).Select("COALESCE(MAX(sequence), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		ids := make([]uint64, len(messages))
+		var deliveries []model.OutboxDelivery
+		for i := range messages {
+			ids[i] = messages[i].ID
+			for _, sink := range sinks {
+				deliveries = append(deliveries, model.OutboxDelivery{
+					MessageID:     messages[i].ID,
+					Sink:          sink,
+					Status:        model.OutboxPending,
+					NextAttemptAt: now,
+				})
+			}
+		}
+		if len(deliveries) > 0 {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(deliveries, 100).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`UPDATE outbox SET dispatched_at = ?, sequence = ? + numbered.n
+			FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS n FROM outbox WHERE id IN ?) AS numbered
+			WHERE outbox.id = numbered.id`, now, last, ids).Error
+	})
+	return len(messages), err
+}
+
+// ClaimDeliveries returns up to limit pending deliveries to sink with their
+// messages, in sequence order, and postpones them by lease, so that another
+// dispatcher does not send them at the same time. Nothing is returned while
+// the oldest pending delivery is not due, so a sink gets its messages in
+// order: a failed batch is retried before anything newer is sent.
+func (r *OutboxRepository) ClaimDeliveries(sink string, now time.Time, lease time.Duration, limit int) ([]model.OutboxDelivery, error) {
+	var deliveries []model.OutboxDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Without SKIP LOCKED a concurrent claim waits here and then finds
+		// the oldest delivery postponed, instead of skipping ahead of it.
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "outbox_deliveries"}}).
+			Select("outbox_deliveries.*").
+			Joins("JOIN outbox ON outbox.id = outbox_deliveries.message_id").
+			Where("outbox_deliveries.sink = ? AND outbox_deliveries.status = ?", sink, model.OutboxPending).
+			Order("outbox.sequence").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+		if deliveries[0].NextAttemptAt.After(now) {
+			deliveries = nil
+			return nil
+		}
+
+		ids := make([]uint64, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].MessageID
+		}
+		var messages []model.OutboxMessage
+		if err := tx.Where("id IN ?", ids).Find(&messages).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint64]model.OutboxMessage, len(messages))
+		for _, m := range messages {
+			byID[m.ID] = m
+		}
+		for i := range deliveries {
+			deliveries[i].Message = byID[deliveries[i].MessageID]
+		}
+		return tx.Model(&model.OutboxDelivery{}).Where("sink = ? AND message_id IN ?", sink, ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return deliveries, err
+}
+
+func (r *OutboxRepository) MarkDelivered(sink string, ids []uint64) error {
+	return r.db.Model(&model.OutboxDelivery{}).
+		Where("sink = ? AND message_id IN ?", sink, ids).
+		Updates(map[string]any{"status": model.OutboxDelivered, "last_error": ""}).Error
+}
+
+// MarkFailed records a failed attempt of the deliveries to sink and
+// schedules the next one at retryAt. Deliveries that have used up
+// maxAttempts become dead; their number is returned.
+func (r *OutboxRepository) MarkFailed(sink string, ids []uint64, cause string, retryAt time.Time, maxAttempts int) (int64, error) {
+	var dead int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.OutboxDelivery{}).
+			Where("sink = ? AND message_id IN ?", sink, ids).
+			Updates(map[string]any{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"last_error":      cause,
+				"next_attempt_at": retryAt,
+			}).Error
+		if err != nil {
+			return err
+		}
+		res := tx.Model(&model.OutboxDelivery{}).
+			Where("sink = ? AND message_id IN ? AND attempts >= ?", sink, ids, maxAttempts).
+			Update("status", model.OutboxDead)
+		dead = res.RowsAffected
+		return res.Error
+	})
+	return dead, err
+}
+
+// LastDispatchedID returns the event ID, the sequence, of the newest
+// dispatched message, or 0.
+func (r *OutboxRepository) LastDispatchedID() (uint64, error) {
+	var id uint64
+	err := r.db.Model(&model.OutboxMessage{}).
+		Select("COALESCE(MAX(sequence), 0)").
+		Scan(&id).Error
+	return id, err
+}
+
+// PurgeDispatchedBefore deletes messages dispatched before t that every
+// sink has received, with their deliveries. Dead letters are kept, and so
+// is the newest message, so that LastDispatchedID survives quiet periods.
+func (r *OutboxRepository) PurgeDispatchedBefore(t time.Time) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Where("dispatched_at < ? AND sequence < (SELECT MAX(sequence) FROM outbox)", t).
+			Where("NOT EXISTS (SELECT 1 FROM outbox_deliveries d WHERE d.message_id = outbox.id AND d.status <> ?)", model.OutboxDelivered).
+			Delete(&model.OutboxMessage{})
+		if res.Error != nil {
+			return res.Error
+		}
+		purged = res.RowsAffected
+		return tx.
+			Where("NOT EXISTS (SELECT 1 FROM outbox o WHERE o.id = outbox_deliveries.message_id)").
+			Delete(&model.OutboxDelivery{}).Error
+	})
+	return purged, err
+}
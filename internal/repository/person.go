@@ -2,17 +2,47 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"effective-mobile/database"
 	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrVersionConflict = errors.New("version conflict")
 )
 
 type PersonRepositoryInterface interface {
 	Save(p *model.Person) error
-	FindAll() ([]model.Person, error)
+	SaveAll(people []*model.Person) error
+	FindAll(filter model.PersonFilter) ([]model.Person, error)
+	Stream(filter model.PersonFilter, fn func(p *model.Person) error) error
+	Search(query string, filter model.PersonFilter) ([]model.PersonSearchResult, error)
+	SearchPhonetic(keys []string, query string, filter model.PersonFilter) ([]model.PersonSearchResult, error)
 	FindByID(id uint) (*model.Person, error)
 	Update(p *model.Person) (*model.Person, error)
-	Delete(id uint) error
+	Delete(id uint, version uint) error
+	CountByFilter(filter model.PersonFilter) (int64, error)
+	Stats(filter model.PersonFilter, opts model.StatsOptions) (*model.PersonStats, error)
+	UpdateByFilter(filter model.PersonFilter, fields map[string]any, guard func(matched int64) error) ([]model.Person, error)
+	DeleteByFilter(filter model.PersonFilter, guard func(matched int64) error) ([]model.Person, error)
+	FindDeleted(filter model.PersonFilter) ([]model.Person, error)
+	Restore(id uint) error
+	Purge(id uint) (*model.Person, error)
+	PurgeDeletedBefore(t time.Time) ([]model.Person, error)
+	FindChanges(since model.ChangePosition, limit int) ([]model.Person, []model.PersonTombstone, error)
+	FindStaleSearchKeys(afterID uint, limit int) ([]model.Person, error)
+	UpdateSearchKeys(p *model.Person) error
+	FindDuplicates(p *model.Person, limit int) ([]model.Person, error)
+	FindDuplicateClusters(filter model.PersonFilter) ([]model.DuplicateCluster, error)
+	Merge(target *model.Person, sources []model.Person) error
+	Transaction(fn func(repo PersonRepositoryInterface) error) error
+	AppendOutbox(messages ...model.OutboxMessage) error
+	AppendHistory(entries ...model.PersonHistory) error
 }
 
 type PersonRepository struct {
@@ -23,33 +53,271 @@ func NewPersonRepository(db *database.DB) *PersonRepository {
 	return &PersonRepository{db: db}
 }
 
+// Transaction runs fn with a repository whose operations all belong to one
+// database transaction, which is committed if fn returns nil.
+func (r *PersonRepository) Transaction(fn func(repo PersonRepositoryInterface) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&PersonRepository{db: &database.DB{DB: tx}})
+	})
+}
+
+// AppendOutbox stores event messages for the dispatcher. Inside Transaction
+// they are committed together with the change they describe.
+func (r *PersonRepository) AppendOutbox(messages ...model.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(messages, 100).Error
+}
+
+// AppendHistory stores history entries. Inside Transaction they are
+// committed together with the change they describe.
+func (r *PersonRepository) AppendHistory(entries ...model.PersonHistory) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(entries, 100).Error
+}
+
 func (r *PersonRepository) Save(p *model.Person) error {
 	return r.db.Create(p).Error
 }
 
-func (r *PersonRepository) FindAll() ([]model.Person, error) {
+// SaveAll inserts all people in a single transaction.
+func (r *PersonRepository) SaveAll(people []*model.Person) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(people, 100).Error
+	})
+}
+
+func (r *PersonRepository) FindAll(filter model.PersonFilter) ([]model.Person, error) {
 	var people []model.Person
-	err := r.db.Find(&people).Error
+	q := paginate(applyFilter(orderBy(r.db.Model(&model.Person{}), filter), filter), filter)
+	err := q.Find(&people).Error
 	return people, err
 }
 
+// Stream reads the people matching filter through a database cursor and
+// passes them to fn one at a time. Returning an error from fn stops the scan.
+func (r *PersonRepository) Stream(filter model.PersonFilter, fn func(p *model.Person) error) error {
+	q := paginate(applyFilter(orderBy(r.db.Model(&model.Person{}), filter), filter), filter)
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p model.Person
+		if err := r.db.ScanRows(rows, &p); err != nil {
+			return err
+		}
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func (r *PersonRepository) FindByID(id uint) (*model.Person, error) {
 	var p model.Person
 	if err := r.db.First(&p, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 	return &p, nil
 }
 
+// Update saves p only if the stored row still has p.Version and bumps the
+// version on success, so concurrent writers can't overwrite each other.
 func (r *PersonRepository) Update(p *model.Person) (*model.Person, error) {
-	err := r.db.Save(p).Error
-	return p, err
+	return p, updateVersioned(r.db.DB, p)
 }
 
-func (r *PersonRepository) Delete(id uint) error {
-	res := r.db.Delete(&model.Person{}, id)
+func updateVersioned(db *gorm.DB, p *model.Person) error {
+	current := p.Version
+	p.Version++
+
+	res := db.Model(p).
+		Where("version = ?", current).
+		Select("*").
+		Omit("id", "created_at", "deleted_at").
+		Updates(p)
+	if res.Error != nil {
+		p.Version = current
+		return res.Error
+	}
 	if res.RowsAffected == 0 {
-		return errors.New("not found")
+		p.Version = current
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Delete soft-deletes the person. A non-zero version makes the delete
+// conditional on the stored version.
+func (r *PersonRepository) Delete(id uint, version uint) error {
+	q := r.db.DB
+	if version != 0 {
+		q = q.Where("version = ?", version)
+	}
+
+	res := q.Delete(&model.Person{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		if version != 0 && r.exists(id) {
+			return ErrVersionConflict
+		}
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PersonRepository) exists(id uint) bool {
+	var count int64
+	r.db.Model(&model.Person{}).Where("id = ?", id).Count(&count)
+	return count > 0
+}
+
+func (r *PersonRepository) CountByFilter(filter model.PersonFilter) (int64, error) {
+	var count int64
+	err := applyFilter(r.db.Model(&model.Person{}), filter).Count(&count).Error
+	return count, err
+}
+
+// UpdateByFilter sets fields on every person matching filter and bumps their
+// versions. The matching rows are locked and guard is called inside the
+// transaction with their number; returning an error from guard aborts the
+// update. The rows are returned as they were before the update.
+func (r *PersonRepository) UpdateByFilter(filter model.PersonFilter, fields map[string]any, guard func(matched int64) error) ([]model.Person, error) {
+	var matched []model.Person
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids, err := lockByFilter(tx, filter, guard, &matched)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		updates := make(map[string]any, len(fields)+1)
+		for k, v := range fields {
+			updates[k] = v
+		}
+		updates["version"] = gorm.Expr("version + 1")
+
+		return tx.Model(&model.Person{}).Where("id IN ?", ids).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-	return res.Error
+	return matched, nil
+}
+
+// DeleteByFilter soft-deletes every person matching filter. guard works as in
+// UpdateByFilter. The deleted rows are returned.
+func (r *PersonRepository) DeleteByFilter(filter model.PersonFilter, guard func(matched int64) error) ([]model.Person, error) {
+	var matched []model.Person
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids, err := lockByFilter(tx, filter, guard, &matched)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&model.Person{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+func lockByFilter(tx *gorm.DB, filter model.PersonFilter, guard func(matched int64) error, matched *[]model.Person) ([]uint, error) {
+	q := applyFilter(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("id"), filter)
+	if err := q.Find(matched).Error; err != nil {
+		return nil, err
+	}
+	if err := guard(int64(len(*matched))); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(*matched))
+	for i, p := range *matched {
+		ids[i] = p.ID
+	}
+	return ids, nil
+}
+
+// FindDeleted lists soft-deleted people, most recently deleted first.
+func (r *PersonRepository) FindDeleted(filter model.PersonFilter) ([]model.Person, error) {
+	var people []model.Person
+	q := r.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC")
+	err := paginate(applyFilter(q, filter), filter).Find(&people).Error
+	return people, err
+}
+
+// Restore undoes a soft delete and bumps the version.
+func (r *PersonRepository) Restore(id uint) error {
+	res := r.db.Unscoped().Model(&model.Person{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Purge permanently removes the person, whether soft-deleted or not, and
+// returns the removed row.
+func (r *PersonRepository) Purge(id uint) (*model.Person, error) {
+	var p model.Person
+	res := r.db.Unscoped().Clauses(clause.Returning{}).Delete(&p, id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+// PurgeDeletedBefore permanently removes people soft-deleted before t and
+// returns the removed rows.
+func (r *PersonRepository) PurgeDeletedBefore(t time.Time) ([]model.Person, error) {
+	var purged []model.Person
+	err := r.db.Unscoped().Clauses(clause.Returning{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", t).
+		Delete(&purged).Error
+	return purged, err
+}
+
+// FindStaleSearchKeys returns up to limit people with an ID above afterID
+// whose derived search keys have not been filled in yet, ordered by ID.
+// Keys of rows stored before the columns existed may be NULL. A name whose
+// key is empty stays stale after an update, so callers page by ID.
+func (r *PersonRepository) FindStaleSearchKeys(afterID uint, limit int) ([]model.Person, error) {
+	var people []model.Person
+	err := r.db.Unscoped().
+		Where("id > ?", afterID).
+		Where("(COALESCE(name_translit, '') = '' AND name <> '') OR (COALESCE(surname_phonetic, '') = '' AND surname <> '')").
+		Order("id").Limit(limit).
+		Find(&people).Error
+	return people, err
+}
+
+// UpdateSearchKeys stores the derived search keys of p without touching its
+// version or update time.
+func (r *PersonRepository) UpdateSearchKeys(p *model.Person) error {
+	return r.db.Unscoped().Model(p).UpdateColumns(map[string]any{
+		"name_translit":       p.NameTranslit,
+		"surname_translit":    p.SurnameTranslit,
+		"patronymic_translit": p.PatronymicTranslit,
+		"surname_phonetic":    p.SurnamePhonetic,
+	}).Error
 }
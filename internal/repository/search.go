@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/translit"
+)
+
+// fullNameExpr and fullTranslitExpr must match the expressions of the
+// full name trigram indexes so that they can serve word similarity lookups.
+const (
+	fullNameExpr     = "(name || ' ' || surname || ' ' || coalesce(patronymic, ''))"
+	fullTranslitExpr = "(name_translit || ' ' || surname_translit || ' ' || patronymic_translit)"
+)
+
+// Search ranks people by trigram similarity of query to their name, surname,
+// patronymic or full name, both as written and in normalized romanization,
+// so that "Dmitry" also finds "Дмитрий". It relies on the pg_trgm indexes
+// from the migrations and honours the list filter and pagination.
+func (r *PersonRepository) Search(query string, filter model.PersonFilter) ([]model.PersonSearchResult, error) {
+	score := "GREATEST(" +
+		"similarity(name, @q), similarity(surname, @q), similarity(patronymic, @q), " +
+		"word_similarity(@q, " + fullNameExpr + "), " +
+		"similarity(name_translit, @t), similarity(surname_translit, @t), similarity(patronymic_translit, @t), " +
+		"word_similarity(@t, " + fullTranslitExpr + "))"
+	match := "name % @q OR surname % @q OR patronymic % @q OR @q <% " + fullNameExpr +
+		" OR name_translit % @t OR surname_translit % @t OR patronymic_translit % @t OR @t <% " + fullTranslitExpr
+	args := map[string]any{"q": query, "t": translit.Normalize(query)}
+
+	q := r.db.Model(&model.Person{}).
+		Select("people.*, "+score+" AS score", args).
+		Where(match, args).
+		Order("score DESC, id")
+
+	var results []model.PersonSearchResult
+	err := paginate(applyFilter(q, filter), filter).Scan(&results).Error
+	return results, err
+}
+
+// SearchPhonetic returns people whose surname has one of the phonetic keys,
+// ranked by how close the romanized surname is to query.
+func (r *PersonRepository) SearchPhonetic(keys []string, query string, filter model.PersonFilter) ([]model.PersonSearchResult, error) {
+	q := r.db.Model(&model.Person{}).
+		Select("people.*, word_similarity(surname_translit, ?) AS score", translit.Normalize(query)).
+		Where("surname_phonetic IN ?", keys).
+		Order("score DESC, id")
+
+	var results []model.PersonSearchResult
+	err := paginate(applyFilter(q, filter), filter).Scan(&results).Error
+	return results, err
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Stats aggregates the people matching filter in SQL. All aggregates are
+// read from one snapshot, so they add up even under concurrent writes.
+// The filter's limit and offset are ignored.
+func (r *PersonRepository) Stats(filter model.PersonFilter, opts model.StatsOptions) (*model.PersonStats, error) {
+	stats := &model.PersonStats{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		people := func() *gorm.DB {
+			return applyFilter(tx.Model(&model.Person{}), filter)
+		}
+
+		if err := people().Count(&stats.Total).Error; err != nil {
+			return err
+		}
+
+		if err := people().
+			Select("LOWER(gender) AS gender, COUNT(*) AS count").
+			Group("LOWER(gender)").
+			Order("count DESC, gender").
+			Scan(&stats.Genders).Error; err != nil {
+			return err
+		}
+
+		histogram, err := ageHistogram(people(), opts.AgeBuckets)
+		if err != nil {
+			return err
+		}
+		stats.AgeHistogram = histogram
+
+		if err := people().
+			Select("UPPER(nationality) AS nationality, COUNT(*) AS count").
+			Where("nationality <> ''").
+			Group("UPPER(nationality)").
+			Order("count DESC, nationality").
+			Limit(opts.TopNationalities).
+			Scan(&stats.TopNationalities).Error; err != nil {
+			return err
+		}
+
+		return people().
+			Select("UPPER(nationality) AS nationality, LOWER(gender) AS gender, AVG(age) AS average, COUNT(*) AS count").
+			Where("age > 0").
+			Group("UPPER(nationality), LOWER(gender)").
+			Order("nationality, gender").
+			Scan(&stats.AverageAge).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ageHistogram counts known ages per bucket with width_bucket, which numbers
+// the buckets below, between and above the bounds from 0 to len(bounds).
+// Empty buckets are included with a zero count.
+func ageHistogram(q *gorm.DB, bounds []int) ([]model.AgeBucket, error) {
+	parts := make([]string, len(bounds))
+	for i, b := range bounds {
+		parts[i] = strconv.Itoa(b)
+	}
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	err := q.Select("width_bucket(age, CAST(? AS int[])) AS bucket, COUNT(*) AS count", fmt.Sprintf("{%s}", strings.Join(parts, ","))).
+		Where("age > 0").
+		Group("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	histogram := make([]model.AgeBucket, len(bounds)+1)
+	for i := range histogram {
+		if i > 0 {
+			histogram[i].Min = bounds[i-1]
+		}
+		if i < len(bounds) {
+			histogram[i].Max = &bounds[i]
+		}
+	}
+	for _, row := range rows {
+		histogram[row.Bucket].Count = row.Count
+	}
+	return histogram, nil
+}
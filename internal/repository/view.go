@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+
+	"effective-mobile/database"
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ViewRepositoryInterface interface {
+	Save(view *model.View) error
+	FindLatest() ([]model.View, error)
+	FindByName(name string, version uint) (*model.View, error)
+	FindVersions(name string) ([]model.View, error)
+}
+
+type ViewRepository struct {
+	db *database.DB
+}
+
+func NewViewRepository(db *database.DB) *ViewRepository {
+	return &ViewRepository{db: db}
+}
+
+// Save stores view as the next version of its name and sets view.Version.
+// Earlier versions are kept. Concurrent saves of the same name are
+// serialized by a transaction-scoped advisory lock on the name.
+func (r *ViewRepository) Save(view *model.View) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", view.Name).Error; err != nil {
+			return err
+		}
+
+		var latest uint
+		err := tx.Model(&model.View{}).
+			Where("name = ?", view.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+
+		view.Version = latest + 1
+		return tx.Create(view).Error
+	})
+}
+
+// FindLatest returns the latest version of every view, ordered by name.
+func (r *ViewRepository) FindLatest() ([]model.View, error) {
+	var views []model.View
+	err := r.db.Raw("SELECT DISTINCT ON (name) * FROM views ORDER BY name, version DESC").Scan(&views).Error
+	return views, err
+}
+
+// FindByName returns the given version of the view, or its latest version
+// if version is zero.
+func (r *ViewRepository) FindByName(name string, version uint) (*model.View, error) {
+	q := r.db.Where("name = ?", name)
+	if version != 0 {
+		q = q.Where("version = ?", version)
+	}
+
+	var view model.View
+	if err := q.Order("version DESC").First(&view).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &view, nil
+}
+
+// FindVersions returns all versions of the view, oldest first.
+func (r *ViewRepository) FindVersions(name string) ([]model.View, error) {
+	var views []model.View
+	err := r.db.Where("name = ?", name).Order("version").Find(&views).Error
+	return views, err
+}
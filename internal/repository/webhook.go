@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"effective-mobile/database"
+	"effective-mobile/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepositoryInterface interface {
+	Create(w *model.Webhook) error
+	FindAll() ([]model.Webhook, error)
+	FindByID(id uint) (*model.Webhook, error)
+	Delete(id uint) error
+	CreateDeliveries(deliveries []model.WebhookDelivery) error
+	FindDeliveries(webhookID uint, status string, limit, offset int) ([]model.WebhookDelivery, error)
+	FindDelivery(webhookID, id uint) (*model.WebhookDelivery, error)
+	ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error)
+	UpdateDelivery(d *model.WebhookDelivery) error
+}
+
+type WebhookRepository struct {
+	db *database.DB
+}
+
+func NewWebhookRepository(db *database.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(w *model.Webhook) error {
+	return r.db.Create(w).Error
+}
+
+func (r *WebhookRepository) FindAll() ([]model.Webhook, error) {
+	var hooks []model.Webhook
+	err := r.db.Order("id").Find(&hooks).Error
+	return hooks, err
+}
+
+func (r *WebhookRepository) FindByID(id uint) (*model.Webhook, error) {
+	var w model.Webhook
+	if err := r.db.First(&w, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+// Delete removes the webhook and its delivery log.
+func (r *WebhookRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&model.Webhook{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error
+	})
+}
+
+// CreateDeliveries records the deliveries, skipping those of an event the
+// webhook already has a delivery of, so that a batch the outbox sends again
+// is not delivered twice.
+func (r *WebhookRepository) CreateDeliveries(deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(deliveries, 100).Error
+}
+
+// FindDeliveries returns the webhook's delivery log, newest first,
+// optionally only deliveries with the given status.
+func (r *WebhookRepository) FindDeliveries(webhookID uint, status string, limit, offset int) ([]model.WebhookDelivery, error) {
+	query := r.db.Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	var deliveries []model.WebhookDelivery
+	err := query.Order("id DESC").Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *WebhookRepository) FindDelivery(webhookID, id uint) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	if err := r.db.Where("webhook_id = ?", webhookID).First(&d, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ClaimDueDeliveries returns up to limit pending deliveries whose next
+// attempt is due and postpones them by lease, so that another dispatcher
+// does not send them at the same time. A dispatcher that dies mid-send
+// leaves the delivery to be retried once the lease expires.
+func (r *WebhookRepository) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+		}
+		return tx.Model(&model.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return deliveries, err
+}
+
+func (r *WebhookRepository) UpdateDelivery(d *model.WebhookDelivery) error {
+	return r.db.Model(d).Select("status", "attempts", "next_attempt_at", "response_code", "last_error", "updated_at").Updates(d).Error
+}
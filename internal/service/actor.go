@@ -0,0 +1,21 @@
+package service
+
+import "context"
+
+// DefaultActor is recorded in history when the caller did not identify itself.
+const DefaultActor = "anonymous"
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes changes made with it to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored by WithActor or DefaultActor.
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return DefaultActor
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+)
+
+// ErrBatchAborted is returned by CreatePersons in atomic mode when at least
+// one item could not be created and therefore nothing was stored.
+var ErrBatchAborted = errors.New("batch aborted")
+
+// enrichConcurrency caps the number of names enriched in parallel.
+const enrichConcurrency = 8
+
+// CreatePersons enriches and stores reqs. Each distinct name is enriched
+// only once. In atomic mode all people are inserted in one transaction and
+// any failure aborts the whole batch; otherwise every item is stored on its
+// own and failures are reported per item.
+func (s *PersonService) CreatePersons(ctx context.Context, reqs []model.CreatePersonRequest, atomic bool) ([]model.BatchItemResult, error) {
+	names := make([]string, len(reqs))
+	for i, req := range reqs {
+		names[i] = req.Name
+	}
+	enriched := s.enrichNames(names)
+
+	results := make([]model.BatchItemResult, len(reqs))
+	people := make([]*model.Person, 0, len(reqs))
+	indexes := make([]int, 0, len(reqs))
+	failed := false
+
+	for i, req := range reqs {
+		results[i].Index = i
+
+		e := enriched[req.Name]
+		if e.err != nil {
+			results[i].Status = model.BatchItemFailed
+			results[i].Error = "enrichment failed: " + e.err.Error()
+			failed = true
+			continue
+		}
+
+		p := &model.Person{
+			Name:        req.Name,
+			Surname:     req.Surname,
+			Patronymic:  req.Patronymic,
+			Gender:      e.data.Gender,
+			Age:         e.data.Age,
+			Nationality: e.data.Nationality,
+		}
+		normalizeNames(p)
+		people = append(people, p)
+		indexes = append(indexes, i)
+	}
+
+	if atomic {
+		if !failed {
+			err := s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+				if err := repo.SaveAll(people); err != nil {
+					return nil, err
+				}
+				var entries []model.PersonHistory
+				for _, p := range people {
+					entries = append(entries, s.creationHistory(ctx, p, model.HistorySourceAPI, enrichedFields)...)
+				}
+				return entries, nil
+			})
+			if err != nil {
+				for _, i := range indexes {
+					results[i].Status = model.BatchItemFailed
+					results[i].Error = err.Error()
+				}
+				return results, ErrBatchAborted
+			}
+			for n, i := range indexes {
+				results[i].Status = model.BatchItemCreated
+				results[i].Person = people[n]
+			}
+			return results, nil
+		}
+
+		for _, i := range indexes {
+			results[i].Status = model.BatchItemSkipped
+		}
+		return results, ErrBatchAborted
+	}
+
+	for n, i := range indexes {
+		err := s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+			if err := repo.Save(people[n]); err != nil {
+				return nil, err
+			}
+			return s.creationHistory(ctx, people[n], model.HistorySourceAPI, enrichedFields), nil
+		})
+		if err != nil {
+			results[i].Status = model.BatchItemFailed
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Status = model.BatchItemCreated
+		results[i].Person = people[n]
+	}
+	return results, nil
+}
+
+type enrichResult struct {
+	data EnrichedData
+	err  error
+}
+
+// enrichNames enriches every distinct name once, in parallel.
+func (s *PersonService) enrichNames(all []string) map[string]enrichResult {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range all {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	results := make(map[string]enrichResult, len(names))
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, enrichConcurrency)
+	)
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			data, err := s.enrich(name)
+			mu.Lock()
+			results[name] = enrichResult{data: data, err: err}
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/phonetic"
+	"effective-mobile/pkg/translit"
+)
+
+var (
+	ErrEmptyFilter          = errors.New("filter must have at least one criterion")
+	ErrEmptyUpdate          = errors.New("update must set at least one field")
+	ErrConfirmationRequired = errors.New("confirmation token required, run with dry_run=true first")
+	ErrConfirmationMismatch = errors.New("confirmation token does not match the current selection")
+)
+
+// UpdatePersons applies update to every person matching filter. With dryRun
+// it only counts the matches and returns the token that confirm must carry
+// for the real run. The token covers the filter, the update and the number
+// of matches, so it goes stale if the selection changes in between.
+func (s *PersonService) UpdatePersons(ctx context.Context, filter model.PersonFilter, update model.UpdatePersonRequest, dryRun bool, confirm string) (*model.BulkResult, error) {
+	if filter.IsEmpty() {
+		return nil, ErrEmptyFilter
+	}
+	fields := updateFields(update)
+	if len(fields) == 0 {
+		return nil, ErrEmptyUpdate
+	}
+
+	if dryRun {
+		return s.previewBulk("update", filter, fields)
+	}
+	if confirm == "" {
+		return nil, ErrConfirmationRequired
+	}
+
+	var n int64
+	err := s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		updated, err := repo.UpdateByFilter(filter, fields, func(n int64) error {
+			return checkConfirmToken(confirm, "update", filter, fields, n)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]model.PersonHistory, len(updated))
+		for i := range updated {
+			p := &updated[i]
+			before := p.Snapshot()
+			applyUpdate(p, update)
+			p.Version++
+			entries[i] = s.historyEntry(ctx, p, model.HistoryActionUpdate, model.HistorySourceAPI, diffSnapshots(before, p.Snapshot()))
+		}
+		n = int64(len(updated))
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.BulkResult{Matched: n, Affected: n}, nil
+}
+
+// DeletePersons soft-deletes every person matching filter. dryRun and
+// confirm work as in UpdatePersons.
+func (s *PersonService) DeletePersons(ctx context.Context, filter model.PersonFilter, dryRun bool, confirm string) (*model.BulkResult, error) {
+	if filter.IsEmpty() {
+		return nil, ErrEmptyFilter
+	}
+
+	if dryRun {
+		return s.previewBulk("delete", filter, nil)
+	}
+	if confirm == "" {
+		return nil, ErrConfirmationRequired
+	}
+
+	var n int64
+	err := s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		deleted, err := repo.DeleteByFilter(filter, func(n int64) error {
+			return checkConfirmToken(confirm, "delete", filter, nil, n)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		entries := make([]model.PersonHistory, len(deleted))
+		for i := range deleted {
+			entries[i] = s.historyEntry(ctx, &deleted[i], model.HistoryActionDelete, model.HistorySourceAPI, nil)
+		}
+		n = int64(len(deleted))
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.BulkResult{Matched: n, Affected: n}, nil
+}
+
+func (s *PersonService) previewBulk(op string, filter model.PersonFilter, fields map[string]any) (*model.BulkResult, error) {
+	matched, err := s.repo.CountByFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &model.BulkResult{
+		Matched:      matched,
+		DryRun:       true,
+		ConfirmToken: confirmToken(op, filter, fields, matched),
+	}, nil
+}
+
+func checkConfirmToken(token, op string, filter model.PersonFilter, fields map[string]any, matched int64) error {
+	if token != confirmToken(op, filter, fields, matched) {
+		return ErrConfirmationMismatch
+	}
+	return nil
+}
+
+func confirmToken(op string, filter model.PersonFilter, fields map[string]any, matched int64) string {
+	filter.Limit, filter.Offset = 0, 0
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%+v\n%d\n", op, filter, matched)
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%v\n", k, fields[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// updateFields maps the non-empty fields of update to column values,
+// including the search keys derived from changed name components.
+func updateFields(update model.UpdatePersonRequest) map[string]any {
+	fields := map[string]any{}
+	if update.Name != "" {
+		fields["name"] = update.Name
+		fields["name_translit"] = translit.Normalize(update.Name)
+	}
+	if update.Surname != "" {
+		fields["surname"] = update.Surname
+		fields["surname_translit"] = translit.Normalize(update.Surname)
+		fields["surname_phonetic"] = phonetic.Key(update.Surname)
+	}
+	if update.Patronymic != "" {
+		fields["patronymic"] = update.Patronymic
+		fields["patronymic_translit"] = translit.Normalize(update.Patronymic)
+	}
+	if update.Gender != "" {
+		fields["gender"] = update.Gender
+	}
+	if update.Age != 0 {
+		fields["age"] = update.Age
+	}
+	if update.Nationality != "" {
+		fields["nationality"] = update.Nationality
+	}
+	return fields
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"effective-mobile/internal/model"
+)
+
+const defaultChangesPageSize = 500
+
+var ErrInvalidChangeToken = errors.New("invalid change token")
+
+// GetChanges returns the changes made after token, at most limit of them,
+// oldest first. An empty token starts a full download. Applying the pages
+// in order, until HasMore is false, brings a replica up to date; a person
+// may appear in several pages, the last appearance wins.
+func (s *PersonService) GetChanges(ctx context.Context, token string, limit int) (*model.PersonChanges, error) {
+	var since model.ChangePosition
+	if token != "" {
+		var err error
+		if since, err = parseChangeToken(token); err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = defaultChangesPageSize
+	}
+
+	// One more than the page tells whether another page follows.
+	people, tombstones, err := s.repo.FindChanges(since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &model.PersonChanges{Changed: []model.Person{}, Deleted: []model.PersonTombstone{}}
+	last := since
+	for n := 0; n < limit && (len(people) > 0 || len(tombstones) > 0); n++ {
+		if len(tombstones) == 0 || len(people) > 0 && people[0].ChangePosition().Before(tombstones[0].ChangePosition()) {
+			p := people[0]
+			people = people[1:]
+			last = p.ChangePosition()
+			if p.DeletedAt.Valid {
+				changes.Deleted = append(changes.Deleted, model.PersonTombstone{Sequence: p.Sequence, ChangeXID: p.ChangeXID, PersonID: p.ID, DeletedAt: p.DeletedAt.Time})
+			} else {
+				changes.Changed = append(changes.Changed, p)
+			}
+			continue
+		}
+		last = tombstones[0].ChangePosition()
+		changes.Deleted = append(changes.Deleted, tombstones[0])
+		tombstones = tombstones[1:]
+	}
+
+	changes.Token = changeToken(last)
+	changes.HasMore = len(people) > 0 || len(tombstones) > 0
+	return changes, nil
+}
+
+// changeToken encodes a feed position as "<xid>.<sequence>".
+func changeToken(p model.ChangePosition) string {
+	return strconv.FormatUint(p.XID, 10) + "." + strconv.FormatUint(p.Sequence, 10)
+}
+
+func parseChangeToken(token string) (model.ChangePosition, error) {
+	xid, seq, ok := strings.Cut(token, ".")
+	if !ok {
+		return model.ChangePosition{}, ErrInvalidChangeToken
+	}
+	var p model.ChangePosition
+	var err error
+	if p.XID, err = strconv.ParseUint(xid, 10, 64); err != nil {
+		return p, ErrInvalidChangeToken
+	}
+	if p.Sequence, err = strconv.ParseUint(seq, 10, 64); err != nil {
+		return p, ErrInvalidChangeToken
+	}
+	return p, nil
+}
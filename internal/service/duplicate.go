@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+)
+
+const maxDuplicateCandidates = 10
+
+// ErrInvalidMerge is returned for merge requests that name a person twice or
+// resolve a field to an unknown field or person.
+var ErrInvalidMerge = errors.New("invalid merge")
+
+// DuplicateError is returned by CreatePerson when people with the same
+// normalized full name already exist.
+type DuplicateError struct {
+	Candidates []uint
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("person may already exist: %v", e.Candidates)
+}
+
+// mergeFields are the attributes MergePersons reconciles.
+var mergeFields = []string{"name", "surname", "patronymic", "gender", "age", "nationality"}
+
+// checkDuplicates fails with a DuplicateError if p likely exists already.
+func (s *PersonService) checkDuplicates(p *model.Person) error {
+	candidates, err := s.repo.FindDuplicates(p, maxDuplicateCandidates)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+	return &DuplicateError{Candidates: ids}
+}
+
+func (s *PersonService) FindDuplicates(ctx context.Context, filter model.PersonFilter) ([]model.DuplicateCluster, error) {
+	return s.repo.FindDuplicateClusters(filter)
+}
+
+// MergePersons merges the source people into the target and moves the
+// sources to the trash. Histories of all of them are kept, and each gets a
+// merge entry pointing at the other side.
+func (s *PersonService) MergePersons(ctx context.Context, req model.MergeRequest) (*model.MergeResult, error) {
+	target, err := s.repo.FindByID(req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := map[uint]*model.Person{target.ID: target}
+	sources := make([]model.Person, 0, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		if participants[id] != nil {
+			return nil, fmt.Errorf("%w: person %d is listed twice", ErrInvalidMerge, id)
+		}
+		p, err := s.repo.FindByID(id)
+		if err != nil {
+			return nil, err
+		}
+		participants[id] = p
+		sources = append(sources, *p)
+	}
+	for field, id := range req.Fields {
+		if !slices.Contains(mergeFields, field) {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidMerge, field)
+		}
+		if participants[id] == nil {
+			return nil, fmt.Errorf("%w: field %q resolves to person %d, who is not merged", ErrInvalidMerge, field, id)
+		}
+	}
+
+	before := target.Snapshot()
+	var conflicts []string
+	for _, field := range mergeFields {
+		if hasConflict(field, target, sources) {
+			conflicts = append(conflicts, field)
+		}
+
+		if id, ok := req.Fields[field]; ok {
+			setPersonField(target, field, personField(participants[id], field))
+			continue
+		}
+		if !isZero(personField(target, field)) {
+			continue
+		}
+		for i := range sources {
+			if v := personField(&sources[i], field); !isZero(v) {
+				setPersonField(target, field, v)
+				break
+			}
+		}
+	}
+	normalizeNames(target)
+
+	ids := make([]uint, len(sources))
+	for i, src := range sources {
+		ids[i] = src.ID
+	}
+
+	err = s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if err := repo.Merge(target, sources); err != nil {
+			return nil, err
+		}
+
+		changes := diffSnapshots(before, target.Snapshot())
+		changes["merged_from"] = model.FieldChange{New: ids}
+		entries := []model.PersonHistory{s.historyEntry(ctx, target, model.HistoryActionMerge, model.HistorySourceAPI, changes)}
+		for i := range sources {
+			entries = append(entries, s.historyEntry(ctx, &sources[i], model.HistoryActionMerge, model.HistorySourceAPI, map[string]model.FieldChange{
+				"merged_into": {New: target.ID},
+			}))
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.MergeResult{Person: *target, MergedIDs: ids, Conflicts: conflicts}, nil
+}
+
+// hasConflict reports whether the merged people have different non-empty
+// values of field.
+func hasConflict(field string, target *model.Person, sources []model.Person) bool {
+	first := personField(target, field)
+	for i := range sources {
+		v := personField(&sources[i], field)
+		if isZero(v) {
+			continue
+		}
+		if isZero(first) {
+			first = v
+		} else if v != first {
+			return true
+		}
+	}
+	return false
+}
+
+func personField(p *model.Person, field string) any {
+	switch field {
+	case "name":
+		return p.Name
+	case "surname":
+		return p.Surname
+	case "patronymic":
+		return p.Patronymic
+	case "gender":
+		return p.Gender
+	case "age":
+		return p.Age
+	case "nationality":
+		return p.Nationality
+	}
+	return nil
+}
+
+func setPersonField(p *model.Person, field string, v any) {
+	switch field {
+	case "name":
+		p.Name = v.(string)
+	case "surname":
+		p.Surname = v.(string)
+	case "patronymic":
+		p.Patronymic = v.(string)
+	case "gender":
+		p.Gender = v.(string)
+	case "age":
+		p.Age = v.(int)
+	case "nationality":
+		p.Nationality = v.(string)
+	}
+}
+
+func isZero(v any) bool {
+	return v == "" || v == 0
+}
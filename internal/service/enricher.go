@@ -11,6 +11,9 @@ type EnrichedData struct {
 	Nationality string
 }
 
+// EnricherFunc looks up the most likely gender, age and nationality for a name.
+type EnricherFunc func(name string) (EnrichedData, error)
+
 func EnrichPerson(name string) (EnrichedData, error) {
 	var genderResp struct {
 		Gender string `json:"gender"`
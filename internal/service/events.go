@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+)
+
+// EventPublisher receives the change events of written people.
+type EventPublisher interface {
+	Publish(events ...model.PersonEvent)
+}
+
+// WithEvents publishes a change event after every successful write. The
+// events of a change are lost if the process stops right after it; use
+// WithOutbox where that matters.
+func (s *PersonService) WithEvents(events EventPublisher) *PersonService {
+	s.events = events
+	return s
+}
+
+// WithOutbox stores the change events in the outbox table, in the same
+// transaction as each change, for a dispatcher to relay. wake, if not nil,
+// is called after each commit to let the dispatcher know. Events are then
+// no longer handed to the WithEvents publisher directly.
+func (s *PersonService) WithOutbox(wake func()) *PersonService {
+	s.outbox = true
+	s.wake = wake
+	return s
+}
+
+// write runs fn, which changes people and returns the history of the
+// change, in a transaction together with that history and its outbox
+// messages, so that a change is never stored without either. Once
+// committed the events are published.
+func (s *PersonService) write(ctx context.Context, fn func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error)) error {
+	var entries []model.PersonHistory
+	err := s.repo.Transaction(func(repo repository.PersonRepositoryInterface) error {
+		var err error
+		if entries, err = fn(repo); err != nil {
+			return err
+		}
+		if s.history != nil {
+			if err := repo.AppendHistory(entries...); err != nil {
+				return err
+			}
+		}
+		if !s.outbox {
+			return nil
+		}
+		messages, err := outboxMessages(personEvents(entries))
+		if err != nil {
+			return err
+		}
+		return repo.AppendOutbox(messages...)
+	})
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	if s.outbox && s.wake != nil {
+		s.wake()
+	}
+	s.publish(entries)
+	return nil
+}
+
+// publish hands the events of history entries to the publisher, unless
+// they went to the outbox.
+func (s *PersonService) publish(entries []model.PersonHistory) {
+	if s.events == nil || s.outbox {
+		return
+	}
+	s.events.Publish(personEvents(entries)...)
+}
+
+// personEvents turns history entries, which every write produces, into
+// events.
+func personEvents(entries []model.PersonHistory) []model.PersonEvent {
+	now := time.Now()
+	events := make([]model.PersonEvent, len(entries))
+	for i, e := range entries {
+		events[i] = model.PersonEvent{
+			Type:     eventType(e),
+			PersonID: e.PersonID,
+			Revision: e.Revision,
+			Person:   e.Snapshot,
+			Changes:  e.Changes,
+			Actor:    e.Actor,
+			Source:   e.Source,
+			Time:     now,
+		}
+	}
+	return events
+}
+
+func outboxMessages(events []model.PersonEvent) ([]model.OutboxMessage, error) {
+	messages := make([]model.OutboxMessage, len(events))
+	for i := range events {
+		payload, err := json.Marshal(&events[i])
+		if err != nil {
+			return nil, err
+		}
+		messages[i] = model.OutboxMessage{
+			EventType: events[i].Type,
+			PersonID:  events[i].PersonID,
+			Payload:   payload,
+		}
+	}
+	return messages, nil
+}
+
+func eventType(e model.PersonHistory) string {
+	switch e.Action {
+	case model.HistoryActionCreate:
+		return model.EventCreated
+	case model.HistoryActionEnrich:
+		return model.EventEnriched
+	case model.HistoryActionDelete, model.HistoryActionPurge:
+		return model.EventDeleted
+	case model.HistoryActionMerge:
+		if _, merged := e.Changes["merged_into"]; merged {
+			return model.EventDeleted
+		}
+	}
+	return model.EventUpdated
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+)
+
+// ErrHistoryDisabled is returned by history queries when the service was
+// built without a history repository.
+var ErrHistoryDisabled = errors.New("history is not enabled")
+
+// enrichedFields are the attributes filled in by EnrichPerson.
+var enrichedFields = []string{"gender", "age", "nationality"}
+
+// WithHistory enables the audit trail. Without it changes are not recorded.
+// Entries are written in the transaction of each change; history only
+// serves the reads.
+func (s *PersonService) WithHistory(history repository.HistoryRepositoryInterface) *PersonService {
+	s.history = history
+	return s
+}
+
+func (s *PersonService) GetPersonHistory(ctx context.Context, id uint) ([]model.PersonHistory, error) {
+	if s.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+	return s.history.FindByPerson(id)
+}
+
+// RollbackPerson restores the editable attributes the person had at
+// revision. The rollback itself is a new revision, so it can be undone too.
+func (s *PersonService) RollbackPerson(ctx context.Context, id uint, revision uint) (*model.Person, error) {
+	if s.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	entry, err := s.history.FindRevision(id, revision)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := p.Snapshot()
+	target := entry.Snapshot
+	p.Name = target.Name
+	p.Surname = target.Surname
+	p.Patronymic = target.Patronymic
+	p.Gender = target.Gender
+	p.Age = target.Age
+	p.Nationality = target.Nationality
+	normalizeNames(p)
+
+	err = s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if _, err := repo.Update(p); err != nil {
+			return nil, err
+		}
+		return []model.PersonHistory{s.historyEntry(ctx, p, model.HistoryActionRollback, model.HistorySourceAPI, diffSnapshots(before, p.Snapshot()))}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *PersonService) historyEntry(ctx context.Context, p *model.Person, action, source string, changes map[string]model.FieldChange) model.PersonHistory {
+	return model.PersonHistory{
+		PersonID: p.ID,
+		Revision: p.Version,
+		Action:   action,
+		Source:   source,
+		Actor:    ActorFrom(ctx),
+		Changes:  changes,
+		Snapshot: p.Snapshot(),
+	}
+}
+
+// creationHistory describes a newly created person as a create entry for
+// the supplied attributes and an enrich entry for the enriched ones.
+func (s *PersonService) creationHistory(ctx context.Context, p *model.Person, source string, enriched []string) []model.PersonHistory {
+	changes := diffSnapshots(model.PersonSnapshot{}, p.Snapshot())
+
+	enrichChanges := map[string]model.FieldChange{}
+	for _, field := range enriched {
+		if change, ok := changes[field]; ok {
+			enrichChanges[field] = change
+			delete(changes, field)
+		}
+	}
+
+	entries := []model.PersonHistory{s.historyEntry(ctx, p, model.HistoryActionCreate, source, changes)}
+	if len(enrichChanges) > 0 {
+		entries = append(entries, s.historyEntry(ctx, p, model.HistoryActionEnrich, model.HistorySourceEnrichment, enrichChanges))
+	}
+	return entries
+}
+
+// diffSnapshots returns the attributes that differ between old and new.
+func diffSnapshots(old, new model.PersonSnapshot) map[string]model.FieldChange {
+	changes := map[string]model.FieldChange{}
+	add := func(field string, o, n any) {
+		if o != n {
+			changes[field] = model.FieldChange{Old: o, New: n}
+		}
+	}
+	add("name", old.Name, new.Name)
+	add("surname", old.Surname, new.Surname)
+	add("patronymic", old.Patronymic, new.Patronymic)
+	add("gender", old.Gender, new.Gender)
+	add("age", old.Age, new.Age)
+	add("nationality", old.Nationality, new.Nationality)
+	return changes
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+)
+
+// ImportPersons stores one batch of already validated CSV rows. Rows missing
+// any of gender, age or nationality are enriched by name and only the
+// missing attributes are filled in. The batch is inserted in one
+// transaction; if that fails, rows are retried one by one so that a single
+// bad row doesn't reject its neighbours.
+func (s *PersonService) ImportPersons(ctx context.Context, rows []model.ImportRow) []model.ImportRowResult {
+	results := make([]model.ImportRowResult, len(rows))
+
+	var names []string
+	for _, row := range rows {
+		if needsEnrichment(row) {
+			names = append(names, row.Person.Name)
+		}
+	}
+	enriched := s.enrichNames(names)
+
+	people := make([]*model.Person, 0, len(rows))
+	indexes := make([]int, 0, len(rows))
+	enrichedByRow := make([][]string, 0, len(rows))
+	for i, row := range rows {
+		results[i].Line = row.Line
+
+		p := &model.Person{
+			Name:        row.Person.Name,
+			Surname:     row.Person.Surname,
+			Patronymic:  row.Person.Patronymic,
+			Gender:      row.Gender,
+			Age:         row.Age,
+			Nationality: row.Nationality,
+		}
+		var filled []string
+		if needsEnrichment(row) {
+			e := enriched[row.Person.Name]
+			if e.err != nil {
+				results[i].Status = model.ImportRowRejected
+				results[i].Error = "enrichment failed: " + e.err.Error()
+				continue
+			}
+			if p.Gender == "" {
+				p.Gender = e.data.Gender
+				filled = append(filled, "gender")
+			}
+			if p.Age == 0 {
+				p.Age = e.data.Age
+				filled = append(filled, "age")
+			}
+			if p.Nationality == "" {
+				p.Nationality = e.data.Nationality
+				filled = append(filled, "nationality")
+			}
+		}
+
+		normalizeNames(p)
+		people = append(people, p)
+		indexes = append(indexes, i)
+		enrichedByRow = append(enrichedByRow, filled)
+	}
+
+	saveAll := func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if err := repo.SaveAll(people); err != nil {
+			return nil, err
+		}
+		var entries []model.PersonHistory
+		for n := range people {
+			entries = append(entries, s.creationHistory(ctx, people[n], model.HistorySourceImport, enrichedByRow[n])...)
+		}
+		return entries, nil
+	}
+	if len(people) > 0 && s.write(ctx, saveAll) == nil {
+		for n, i := range indexes {
+			results[i].Status = model.ImportRowAccepted
+			results[i].ID = people[n].ID
+		}
+		return results
+	}
+
+	for n, i := range indexes {
+		people[n].ID = 0
+		err := s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+			if err := repo.Save(people[n]); err != nil {
+				return nil, err
+			}
+			return s.creationHistory(ctx, people[n], model.HistorySourceImport, enrichedByRow[n]), nil
+		})
+		if err != nil {
+			results[i].Status = model.ImportRowRejected
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Status = model.ImportRowAccepted
+		results[i].ID = people[n].ID
+	}
+	return results
+}
+
+func needsEnrichment(row model.ImportRow) bool {
+	return row.Gender == "" || row.Age == 0 || row.Nationality == ""
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/phonetic"
+	"effective-mobile/pkg/translit"
+)
+
+const searchKeysBatchSize = 500
+
+// normalizeNames derives the search keys of p from its name components.
+// It must run before every write that may change a name.
+func normalizeNames(p *model.Person) {
+	p.NameTranslit = translit.Normalize(p.Name)
+	p.SurnameTranslit = translit.Normalize(p.Surname)
+	p.PatronymicTranslit = translit.Normalize(p.Patronymic)
+	p.SurnamePhonetic = phonetic.Key(p.Surname)
+}
+
+// phoneticKeys returns the surname keys a search query may refer to: the key
+// of the whole query, for multi-word surnames, and the key of each word.
+func phoneticKeys(query string) []string {
+	full := phonetic.Key(query)
+	if full == "" {
+		return nil
+	}
+
+	keys := []string{full}
+	for _, word := range strings.Fields(full) {
+		if word != full {
+			keys = append(keys, word)
+		}
+	}
+	return keys
+}
+
+// RefreshSearchKeys derives missing search keys for people stored before
+// the keys existed. It returns the number of updated people. Each person is
+// visited once, even if a name has no key, such as one without letters.
+func (s *PersonService) RefreshSearchKeys(ctx context.Context) (int, error) {
+	total := 0
+	var lastID uint
+	for ctx.Err() == nil {
+		stale, err := s.repo.FindStaleSearchKeys(lastID, searchKeysBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(stale) == 0 {
+			break
+		}
+
+		for i := range stale {
+			normalizeNames(&stale[i])
+			if err := s.repo.UpdateSearchKeys(&stale[i]); err != nil {
+				return total, err
+			}
+		}
+		total += len(stale)
+		lastID = stale[len(stale)-1].ID
+	}
+	return total, ctx.Err()
+}
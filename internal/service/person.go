@@ -1,62 +1,203 @@
 package service
 
 import (
+	"context"
+
 	"effective-mobile/internal/model"
 	"effective-mobile/internal/repository"
 )
 
 type PersonServiceInterface interface {
-	CreatePerson(req model.CreatePersonRequest) (*model.Person, error)
-	GetAllPersons() ([]model.Person, error)
-	GetPersonByID(id uint) (*model.Person, error)
-	UpdatePerson(id uint, req model.UpdatePersonRequest) (*model.Person, error)
-	DeletePerson(id uint) error
+	CreatePerson(ctx context.Context, req model.CreatePersonRequest) (*model.Person, error)
+	CreatePersons(ctx context.Context, reqs []model.CreatePersonRequest, atomic bool) ([]model.BatchItemResult, error)
+	ImportPersons(ctx context.Context, rows []model.ImportRow) []model.ImportRowResult
+	GetAllPersons(ctx context.Context, filter model.PersonFilter) ([]model.Person, error)
+	CountPersons(ctx context.Context, filter model.PersonFilter) (int64, error)
+	ExportPersons(ctx context.Context, filter model.PersonFilter, fn func(p *model.Person) error) error
+	SearchPersons(ctx context.Context, query string, match string, filter model.PersonFilter) ([]model.PersonSearchResult, error)
+	GetPersonByID(ctx context.Context, id uint) (*model.Person, error)
+	UpdatePerson(ctx context.Context, id uint, req model.UpdatePersonRequest) (*model.Person, error)
+	DeletePerson(ctx context.Context, id uint, version uint) error
+	ReenrichPerson(ctx context.Context, id uint) (*model.Person, error)
+	UpdatePersons(ctx context.Context, filter model.PersonFilter, update model.UpdatePersonRequest, dryRun bool, confirm string) (*model.BulkResult, error)
+	DeletePersons(ctx context.Context, filter model.PersonFilter, dryRun bool, confirm string) (*model.BulkResult, error)
+	GetDeletedPersons(ctx context.Context, filter model.PersonFilter) ([]model.DeletedPerson, error)
+	RestorePerson(ctx context.Context, id uint) (*model.Person, error)
+	PurgePerson(ctx context.Context, id uint) error
+	GetChanges(ctx context.Context, token string, limit int) (*model.PersonChanges, error)
+	GetPersonHistory(ctx context.Context, id uint) ([]model.PersonHistory, error)
+	RollbackPerson(ctx context.Context, id uint, revision uint) (*model.Person, error)
+	GetPersonStats(ctx context.Context, filter model.PersonFilter, opts model.StatsOptions) (*model.PersonStats, error)
+	FindDuplicates(ctx context.Context, filter model.PersonFilter) ([]model.DuplicateCluster, error)
+	MergePersons(ctx context.Context, req model.MergeRequest) (*model.MergeResult, error)
+	SaveView(ctx context.Context, req model.SaveViewRequest) (*model.View, error)
+	GetViews(ctx context.Context) ([]model.View, error)
+	GetView(ctx context.Context, name string, version uint) (*model.View, error)
+	GetViewVersions(ctx context.Context, name string) ([]model.View, error)
+	GetViewPersons(ctx context.Context, name string, version uint, limit, offset int) (*model.View, []model.Person, error)
 }
 
 type PersonService struct {
-	repo repository.PersonRepositoryInterface
+	repo    repository.PersonRepositoryInterface
+	history repository.HistoryRepositoryInterface
+	views   repository.ViewRepositoryInterface
+	events  EventPublisher
+	outbox  bool
+	wake    func()
+	enrich  EnricherFunc
 }
 
 func NewPersonService(repo repository.PersonRepositoryInterface) *PersonService {
-	return &PersonService{repo: repo}
+	return &PersonService{repo: repo, enrich: EnrichPerson}
 }
 
-func (s *PersonService) CreatePerson(req model.CreatePersonRequest) (*model.Person, error) {
-	data, err := EnrichPerson(req.Name)
-	if err != nil {
-		return nil, err
-	}
+// WithEnricher replaces the enrichment source, which defaults to EnrichPerson.
+func (s *PersonService) WithEnricher(enrich EnricherFunc) *PersonService {
+	s.enrich = enrich
+	return s
+}
 
+// CreatePerson enriches and stores a new person. Unless req.Force is set it
+// fails with a DuplicateError when the person likely exists already. The
+// check runs before enrichment, which derives the attributes from the name
+// alone, so a rejected duplicate costs no lookups.
+func (s *PersonService) CreatePerson(ctx context.Context, req model.CreatePersonRequest) (*model.Person, error) {
 	person := &model.Person{
-		Name:        req.Name,
-		Surname:     req.Surname,
-		Patronymic:  req.Patronymic,
-		Gender:      data.Gender,
-		Age:         data.Age,
-		Nationality: data.Nationality,
+		Name:       req.Name,
+		Surname:    req.Surname,
+		Patronymic: req.Patronymic,
+	}
+	normalizeNames(person)
+
+	if !req.Force {
+		if err := s.checkDuplicates(person); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.repo.Save(person); err != nil {
+	data, err := s.enrich(req.Name)
+	if err != nil {
 		return nil, err
 	}
+	person.Gender = data.Gender
+	person.Age = data.Age
+	person.Nationality = data.Nationality
 
+	err = s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if err := repo.Save(person); err != nil {
+			return nil, err
+		}
+		return s.creationHistory(ctx, person, model.HistorySourceAPI, enrichedFields), nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return person, nil
 }
 
-func (s *PersonService) GetAllPersons() ([]model.Person, error) {
-	return s.repo.FindAll()
+func (s *PersonService) GetAllPersons(ctx context.Context, filter model.PersonFilter) ([]model.Person, error) {
+	return s.repo.FindAll(filter)
+}
+
+// CountPersons counts all people matching the filter, regardless of its
+// limit and offset.
+func (s *PersonService) CountPersons(ctx context.Context, filter model.PersonFilter) (int64, error) {
+	return s.repo.CountByFilter(filter)
+}
+
+func (s *PersonService) ExportPersons(ctx context.Context, filter model.PersonFilter, fn func(p *model.Person) error) error {
+	return s.repo.Stream(filter, fn)
+}
+
+// SearchPersons finds people by trigram similarity or, with the phonetic
+// match mode, by the sound of their surname.
+func (s *PersonService) SearchPersons(ctx context.Context, query string, match string, filter model.PersonFilter) ([]model.PersonSearchResult, error) {
+	if match == model.SearchMatchPhonetic {
+		keys := phoneticKeys(query)
+		if len(keys) == 0 {
+			return []model.PersonSearchResult{}, nil
+		}
+		return s.repo.SearchPhonetic(keys, query, filter)
+	}
+	return s.repo.Search(query, filter)
 }
 
-func (s *PersonService) GetPersonByID(id uint) (*model.Person, error) {
+func (s *PersonService) GetPersonByID(ctx context.Context, id uint) (*model.Person, error) {
 	return s.repo.FindByID(id)
 }
 
-func (s *PersonService) UpdatePerson(id uint, update model.UpdatePersonRequest) (*model.Person, error) {
+func (s *PersonService) UpdatePerson(ctx context.Context, id uint, update model.UpdatePersonRequest) (*model.Person, error) {
+	p, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if update.Version != 0 && update.Version != p.Version {
+		return nil, repository.ErrVersionConflict
+	}
+
+	before := p.Snapshot()
+	applyUpdate(p, update)
+	normalizeNames(p)
+
+	err = s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if _, err := repo.Update(p); err != nil {
+			return nil, err
+		}
+		return []model.PersonHistory{s.historyEntry(ctx, p, model.HistoryActionUpdate, model.HistorySourceAPI, diffSnapshots(before, p.Snapshot()))}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ReenrichPerson looks up gender, age and nationality for the person's name
+// again and stores the values found. Attributes the lookup has no value for
+// are kept.
+func (s *PersonService) ReenrichPerson(ctx context.Context, id uint) (*model.Person, error) {
 	p, err := s.repo.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	data, err := s.enrich(p.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	before := p.Snapshot()
+	applyUpdate(p, model.UpdatePersonRequest{Gender: data.Gender, Age: data.Age, Nationality: data.Nationality})
+	changes := diffSnapshots(before, p.Snapshot())
+	if len(changes) == 0 {
+		return p, nil
+	}
+
+	err = s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if _, err := repo.Update(p); err != nil {
+			return nil, err
+		}
+		return []model.PersonHistory{s.historyEntry(ctx, p, model.HistoryActionEnrich, model.HistorySourceEnrichment, changes)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *PersonService) DeletePerson(ctx context.Context, id uint, version uint) error {
+	p, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if err := repo.Delete(id, version); err != nil {
+			return nil, err
+		}
+		return []model.PersonHistory{s.historyEntry(ctx, p, model.HistoryActionDelete, model.HistorySourceAPI, nil)}, nil
+	})
+}
 
+// applyUpdate copies the non-empty fields of update onto p.
+func applyUpdate(p *model.Person, update model.UpdatePersonRequest) {
 	if update.Name != "" {
 		p.Name = update.Name
 	}
@@ -75,10 +216,4 @@ func (s *PersonService) UpdatePerson(id uint, update model.UpdatePersonRequest)
 	if update.Nationality != "" {
 		p.Nationality = update.Nationality
 	}
-
-	return s.repo.Update(p)
-}
-
-func (s *PersonService) DeletePerson(id uint) error {
-	return s.repo.Delete(id)
 }
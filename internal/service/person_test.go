@@ -1,20 +1,29 @@
 package service_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
 	"effective-mobile/internal/service"
+	"effective-mobile/pkg/filterexpr"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // ---- MOCK REPO ----
 
 type mockRepo struct {
 	mock.Mock
+	outbox  []model.OutboxMessage
+	history []model.PersonHistory
+	// historyErr, if set, fails AppendHistory.
+	historyErr error
 }
 
 func (m *mockRepo) Save(p *model.Person) error {
@@ -22,11 +31,36 @@ func (m *mockRepo) Save(p *model.Person) error {
 	return args.Error(0)
 }
 
-func (m *mockRepo) FindAll() ([]model.Person, error) {
-	args := m.Called()
+func (m *mockRepo) SaveAll(people []*model.Person) error {
+	args := m.Called(people)
+	return args.Error(0)
+}
+
+func (m *mockRepo) FindAll(filter model.PersonFilter) ([]model.Person, error) {
+	args := m.Called(filter)
 	return args.Get(0).([]model.Person), args.Error(1)
 }
 
+func (m *mockRepo) Stream(filter model.PersonFilter, fn func(p *model.Person) error) error {
+	args := m.Called(filter)
+	for _, p := range args.Get(0).([]model.Person) {
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *mockRepo) Search(query string, filter model.PersonFilter) ([]model.PersonSearchResult, error) {
+	args := m.Called(query, filter)
+	return args.Get(0).([]model.PersonSearchResult), args.Error(1)
+}
+
+func (m *mockRepo) SearchPhonetic(keys []string, query string, filter model.PersonFilter) ([]model.PersonSearchResult, error) {
+	args := m.Called(keys, query, filter)
+	return args.Get(0).([]model.PersonSearchResult), args.Error(1)
+}
+
 func (m *mockRepo) FindByID(id uint) (*model.Person, error) {
 	args := m.Called(id)
 	return args.Get(0).(*model.Person), args.Error(1)
@@ -37,25 +71,171 @@ func (m *mockRepo) Update(p *model.Person) (*model.Person, error) {
 	return args.Get(0).(*model.Person), args.Error(1)
 }
 
-func (m *mockRepo) Delete(id uint) error {
+func (m *mockRepo) Delete(id uint, version uint) error {
+	args := m.Called(id, version)
+	return args.Error(0)
+}
+
+func stubEnricher(name string) (service.EnrichedData, error) {
+	return service.EnrichedData{Gender: "female", Age: 30, Nationality: "US"}, nil
+}
+
+func (m *mockRepo) CountByFilter(filter model.PersonFilter) (int64, error) {
+	args := m.Called(filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepo) UpdateByFilter(filter model.PersonFilter, fields map[string]any, guard func(int64) error) ([]model.Person, error) {
+	args := m.Called(filter, fields)
+	matched := args.Get(0).([]model.Person)
+	if err := guard(int64(len(matched))); err != nil {
+		return nil, err
+	}
+	return matched, args.Error(1)
+}
+
+func (m *mockRepo) DeleteByFilter(filter model.PersonFilter, guard func(int64) error) ([]model.Person, error) {
+	args := m.Called(filter)
+	matched := args.Get(0).([]model.Person)
+	if err := guard(int64(len(matched))); err != nil {
+		return nil, err
+	}
+	return matched, args.Error(1)
+}
+
+func (m *mockRepo) FindDeleted(filter model.PersonFilter) ([]model.Person, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]model.Person), args.Error(1)
+}
+
+func (m *mockRepo) Restore(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockRepo) Purge(id uint) (*model.Person, error) {
 	args := m.Called(id)
+	return args.Get(0).(*model.Person), args.Error(1)
+}
+
+func (m *mockRepo) PurgeDeletedBefore(t time.Time) ([]model.Person, error) {
+	args := m.Called(t)
+	return args.Get(0).([]model.Person), args.Error(1)
+}
+
+func (m *mockRepo) FindChanges(since model.ChangePosition, limit int) ([]model.Person, []model.PersonTombstone, error) {
+	args := m.Called(since, limit)
+	return args.Get(0).([]model.Person), args.Get(1).([]model.PersonTombstone), args.Error(2)
+}
+
+func (m *mockRepo) FindStaleSearchKeys(afterID uint, limit int) ([]model.Person, error) {
+	args := m.Called(afterID, limit)
+	return args.Get(0).([]model.Person), args.Error(1)
+}
+
+func (m *mockRepo) UpdateSearchKeys(p *model.Person) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *mockRepo) FindDuplicates(p *model.Person, limit int) ([]model.Person, error) {
+	args := m.Called(p, limit)
+	return args.Get(0).([]model.Person), args.Error(1)
+}
+
+func (m *mockRepo) FindDuplicateClusters(filter model.PersonFilter) ([]model.DuplicateCluster, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]model.DuplicateCluster), args.Error(1)
+}
+
+func (m *mockRepo) Merge(target *model.Person, sources []model.Person) error {
+	args := m.Called(target, sources)
+	return args.Error(0)
+}
+
+func (m *mockRepo) Stats(filter model.PersonFilter, opts model.StatsOptions) (*model.PersonStats, error) {
+	args := m.Called(filter, opts)
+	return args.Get(0).(*model.PersonStats), args.Error(1)
+}
+
+// Transaction runs fn on the mock itself; the outbox and history are kept
+// only if fn succeeds, as a commit would.
+func (m *mockRepo) Transaction(fn func(repo repository.PersonRepositoryInterface) error) error {
+	n, h := len(m.outbox), len(m.history)
+	err := fn(m)
+	if err != nil {
+		m.outbox = m.outbox[:n]
+		m.history = m.history[:h]
+	}
+	return err
+}
+
+func (m *mockRepo) AppendOutbox(messages ...model.OutboxMessage) error {
+	m.outbox = append(m.outbox, messages...)
+	return nil
+}
+
+func (m *mockRepo) AppendHistory(entries ...model.PersonHistory) error {
+	if m.historyErr != nil {
+		return m.historyErr
+	}
+	m.history = append(m.history, entries...)
+	return nil
+}
+
+type mockHistory struct {
+	mock.Mock
+}
+
+func (m *mockHistory) FindByPerson(personID uint) ([]model.PersonHistory, error) {
+	args := m.Called(personID)
+	return args.Get(0).([]model.PersonHistory), args.Error(1)
+}
+
+func (m *mockHistory) FindRevision(personID uint, revision uint) (*model.PersonHistory, error) {
+	args := m.Called(personID, revision)
+	return args.Get(0).(*model.PersonHistory), args.Error(1)
+}
+
+type mockViews struct {
+	mock.Mock
+}
+
+func (m *mockViews) Save(view *model.View) error {
+	args := m.Called(view)
 	return args.Error(0)
 }
 
+func (m *mockViews) FindLatest() ([]model.View, error) {
+	args := m.Called()
+	return args.Get(0).([]model.View), args.Error(1)
+}
+
+func (m *mockViews) FindByName(name string, version uint) (*model.View, error) {
+	args := m.Called(name, version)
+	return args.Get(0).(*model.View), args.Error(1)
+}
+
+func (m *mockViews) FindVersions(name string) ([]model.View, error) {
+	args := m.Called(name)
+	return args.Get(0).([]model.View), args.Error(1)
+}
+
 // ---- TESTS ----
 
 func TestCreatePerson(t *testing.T) {
 	mockRepo := new(mockRepo)
-	svc := service.NewPersonService(mockRepo)
+	svc := service.NewPersonService(mockRepo).WithEnricher(stubEnricher)
 
 	req := model.CreatePersonRequest{
 		Name:    "Alice",
 		Surname: "Smith",
 	}
 
+	mockRepo.On("FindDuplicates", mock.Anything, mock.Anything).Return([]model.Person{}, nil)
 	mockRepo.On("Save", mock.AnythingOfType("*model.Person")).Return(nil)
 
-	result, err := svc.CreatePerson(req)
+	result, err := svc.CreatePerson(context.Background(), req)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Alice", result.Name)
@@ -86,7 +266,7 @@ func TestUpdatePerson_OnlyUpdatesProvidedFields(t *testing.T) {
 		Age:  30,
 	}
 
-	updated, err := svc.UpdatePerson(1, req)
+	updated, err := svc.UpdatePerson(context.Background(), 1, req)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "NewName", updated.Name)
@@ -101,7 +281,586 @@ func TestGetPersonByID_NotFound(t *testing.T) {
 
 	mockRepo.On("FindByID", uint(100)).Return(&model.Person{}, errors.New("not found"))
 
-	_, err := svc.GetPersonByID(100)
+	_, err := svc.GetPersonByID(context.Background(), 100)
 
 	assert.Error(t, err)
 }
+
+func TestUpdatePerson_VersionMismatch(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	existing := &model.Person{ID: 1, Name: "OldName", Version: 3}
+	mockRepo.On("FindByID", uint(1)).Return(existing, nil)
+
+	_, err := svc.UpdatePerson(context.Background(), 1, model.UpdatePersonRequest{Name: "NewName", Version: 2})
+
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestCreatePersons_EnrichesEachNameOnce(t *testing.T) {
+	mockRepo := new(mockRepo)
+	calls := map[string]int{}
+	svc := service.NewPersonService(mockRepo).WithEnricher(func(name string) (service.EnrichedData, error) {
+		calls[name]++
+		return stubEnricher(name)
+	})
+
+	mockRepo.On("SaveAll", mock.AnythingOfType("[]*model.Person")).Return(nil)
+
+	results, err := svc.CreatePersons(context.Background(), []model.CreatePersonRequest{
+		{Name: "Alice", Surname: "Smith"},
+		{Name: "Alice", Surname: "Jones"},
+	}, true)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, model.BatchItemCreated, results[1].Status)
+	assert.Equal(t, 1, calls["Alice"])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreatePersons_AtomicAbortsOnEnrichmentFailure(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo).WithEnricher(func(name string) (service.EnrichedData, error) {
+		if name == "Bob" {
+			return service.EnrichedData{}, errors.New("rate limited")
+		}
+		return stubEnricher(name)
+	})
+
+	results, err := svc.CreatePersons(context.Background(), []model.CreatePersonRequest{
+		{Name: "Alice", Surname: "Smith"},
+		{Name: "Bob", Surname: "Smith"},
+	}, true)
+
+	assert.ErrorIs(t, err, service.ErrBatchAborted)
+	assert.Equal(t, model.BatchItemSkipped, results[0].Status)
+	assert.Equal(t, model.BatchItemFailed, results[1].Status)
+	mockRepo.AssertNotCalled(t, "SaveAll", mock.Anything)
+}
+
+func TestDeletePersons_RequiresMatchingConfirmToken(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	filter := model.PersonFilter{Surname: "Test"}
+	mockRepo.On("CountByFilter", filter).Return(int64(3), nil)
+
+	preview, err := svc.DeletePersons(context.Background(), filter, true, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), preview.Matched)
+	assert.NotEmpty(t, preview.ConfirmToken)
+
+	_, err = svc.DeletePersons(context.Background(), filter, false, "")
+	assert.ErrorIs(t, err, service.ErrConfirmationRequired)
+
+	mockRepo.On("DeleteByFilter", filter).Return(make([]model.Person, 4), nil).Once()
+	_, err = svc.DeletePersons(context.Background(), filter, false, preview.ConfirmToken)
+	assert.ErrorIs(t, err, service.ErrConfirmationMismatch)
+
+	mockRepo.On("DeleteByFilter", filter).Return(make([]model.Person, 3), nil).Once()
+	result, err := svc.DeletePersons(context.Background(), filter, false, preview.ConfirmToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), result.Affected)
+}
+
+func TestDeletePersons_RejectsEmptyFilter(t *testing.T) {
+	svc := service.NewPersonService(new(mockRepo))
+
+	_, err := svc.DeletePersons(context.Background(), model.PersonFilter{Limit: 10}, true, "")
+
+	assert.ErrorIs(t, err, service.ErrEmptyFilter)
+}
+
+func TestImportPersons_KeepsPrefilledAttributes(t *testing.T) {
+	mockRepo := new(mockRepo)
+	enriched := 0
+	svc := service.NewPersonService(mockRepo).WithEnricher(func(name string) (service.EnrichedData, error) {
+		enriched++
+		return stubEnricher(name)
+	})
+
+	mockRepo.On("SaveAll", mock.MatchedBy(func(people []*model.Person) bool {
+		return people[0].Nationality == "RU" && people[0].Age == 40 && people[1].Nationality == "US"
+	})).Return(nil)
+
+	results := svc.ImportPersons(context.Background(), []model.ImportRow{
+		{Line: 2, Person: model.CreatePersonRequest{Name: "Dmitriy", Surname: "Ushakov"}, Gender: "male", Age: 40, Nationality: "RU"},
+		{Line: 3, Person: model.CreatePersonRequest{Name: "Alice", Surname: "Smith"}},
+	})
+
+	assert.Equal(t, 1, enriched)
+	assert.Equal(t, model.ImportRowAccepted, results[0].Status)
+	assert.Equal(t, 3, results[1].Line)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPurgeDeleted_UsesRetentionCutoff(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	mockRepo.On("PurgeDeletedBefore", mock.MatchedBy(func(cutoff time.Time) bool {
+		age := time.Since(cutoff)
+		return age > 47*time.Hour && age < 49*time.Hour
+	})).Return(make([]model.Person, 5), nil)
+
+	purged, err := svc.PurgeDeleted(context.Background(), 48*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), purged)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPurgeDeleted_RecordsHistoryAndOutbox(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo).WithHistory(new(mockHistory)).WithOutbox(nil)
+
+	mockRepo.On("PurgeDeletedBefore", mock.Anything).Return([]model.Person{{ID: 3, Name: "Old", Version: 2}}, nil)
+
+	_, err := svc.PurgeDeleted(context.Background(), time.Hour)
+
+	assert.NoError(t, err)
+	if assert.Len(t, mockRepo.history, 1) {
+		assert.Equal(t, model.HistoryActionPurge, mockRepo.history[0].Action)
+		assert.Equal(t, model.HistorySourceRetention, mockRepo.history[0].Source)
+	}
+	if assert.Len(t, mockRepo.outbox, 1) {
+		assert.Equal(t, model.EventDeleted, mockRepo.outbox[0].EventType)
+	}
+}
+
+func TestUpdatePerson_RecordsHistory(t *testing.T) {
+	mockRepo := new(mockRepo)
+	history := new(mockHistory)
+	svc := service.NewPersonService(mockRepo).WithHistory(history)
+
+	existing := &model.Person{ID: 1, Name: "Dmitriy", Surname: "Ushakov", Nationality: "UA", Version: 1}
+	mockRepo.On("FindByID", uint(1)).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.Person).Version++
+	}).Return(existing, nil)
+
+	ctx := service.WithActor(context.Background(), "operator@example.com")
+	_, err := svc.UpdatePerson(ctx, 1, model.UpdatePersonRequest{Nationality: "RU"})
+
+	assert.NoError(t, err)
+	if assert.Len(t, mockRepo.history, 1) {
+		e := mockRepo.history[0]
+		assert.Equal(t, model.HistoryActionUpdate, e.Action)
+		assert.Equal(t, "operator@example.com", e.Actor)
+		assert.Equal(t, uint(2), e.Revision)
+		assert.Equal(t, map[string]model.FieldChange{"nationality": {Old: "UA", New: "RU"}}, e.Changes)
+	}
+}
+
+func TestUpdatePerson_FailsWhenHistoryCannotBeWritten(t *testing.T) {
+	mockRepo := &mockRepo{historyErr: errors.New("disk full")}
+	svc := service.NewPersonService(mockRepo).WithHistory(new(mockHistory)).WithOutbox(nil)
+
+	existing := &model.Person{ID: 1, Name: "Dmitriy", Surname: "Ushakov", Nationality: "UA", Version: 1}
+	mockRepo.On("FindByID", uint(1)).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything).Return(existing, nil)
+
+	_, err := svc.UpdatePerson(context.Background(), 1, model.UpdatePersonRequest{Nationality: "RU"})
+
+	assert.EqualError(t, err, "disk full")
+	assert.Empty(t, mockRepo.outbox)
+}
+
+func TestRollbackPerson_RestoresSnapshot(t *testing.T) {
+	mockRepo := new(mockRepo)
+	history := new(mockHistory)
+	svc := service.NewPersonService(mockRepo).WithHistory(history)
+
+	current := &model.Person{ID: 1, Name: "Dmitriy", Surname: "Ushakov", Nationality: "RU", Version: 3}
+	history.On("FindRevision", uint(1), uint(1)).Return(&model.PersonHistory{
+		PersonID: 1,
+		Revision: 1,
+		Snapshot: model.PersonSnapshot{Name: "Dmitriy", Surname: "Ushakov", Nationality: "UA"},
+	}, nil)
+	mockRepo.On("FindByID", uint(1)).Return(current, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(p *model.Person) bool {
+		return p.Nationality == "UA"
+	})).Return(current, nil)
+
+	p, err := svc.RollbackPerson(context.Background(), 1, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "UA", p.Nationality)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreatePerson_NormalizesNames(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo).WithEnricher(stubEnricher)
+
+	mockRepo.On("FindDuplicates", mock.Anything, mock.Anything).Return([]model.Person{}, nil)
+	mockRepo.On("Save", mock.MatchedBy(func(p *model.Person) bool {
+		return p.NameTranslit == "dmitri" && p.SurnameTranslit == "ushakov" && p.PatronymicTranslit == "vasilevich"
+	})).Return(nil)
+
+	_, err := svc.CreatePerson(context.Background(), model.CreatePersonRequest{
+		Name:       "Дмитрий",
+		Surname:    "Ушаков",
+		Patronymic: "Васильевич",
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshSearchKeys(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	mockRepo.On("FindStaleSearchKeys", uint(0), mock.Anything).Return([]model.Person{{ID: 1, Name: "Dmitriy", Surname: "Ushakov"}}, nil).Once()
+	mockRepo.On("FindStaleSearchKeys", uint(1), mock.Anything).Return([]model.Person{}, nil).Once()
+	mockRepo.On("UpdateSearchKeys", mock.MatchedBy(func(p *model.Person) bool {
+		return p.ID == 1 && p.NameTranslit == "dmitri" && p.SurnamePhonetic != ""
+	})).Return(nil)
+
+	n, err := svc.RefreshSearchKeys(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRefreshSearchKeys_PagesPastNamesWithoutKeys(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	// The repository keeps returning a name without letters as stale, as
+	// the database would, unless the next page starts after it.
+	unkeyed := []model.Person{{ID: 7, Name: "???", Surname: "---"}}
+	mockRepo.On("FindStaleSearchKeys", uint(0), mock.Anything).Return(unkeyed, nil)
+	mockRepo.On("FindStaleSearchKeys", uint(7), mock.Anything).Return([]model.Person{}, nil).Once()
+	mockRepo.On("UpdateSearchKeys", mock.MatchedBy(func(p *model.Person) bool {
+		return p.ID == 7 && p.NameTranslit == "" && p.SurnamePhonetic == ""
+	})).Return(nil).Once()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	n, err := svc.RefreshSearchKeys(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearchPersons_Phonetic(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	found := []model.PersonSearchResult{{Person: model.Person{ID: 1, Surname: "Шевченко"}, Score: 0.4}}
+	mockRepo.On("SearchPhonetic", []string{"SfCnk"}, "Schewtschenko", model.PersonFilter{}).Return(found, nil)
+
+	results, err := svc.SearchPersons(context.Background(), "Schewtschenko", model.SearchMatchPhonetic, model.PersonFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, found, results)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreatePerson_RejectsLikelyDuplicate(t *testing.T) {
+	mockRepo := new(mockRepo)
+	lookups := 0
+	svc := service.NewPersonService(mockRepo).WithEnricher(func(name string) (service.EnrichedData, error) {
+		lookups++
+		return stubEnricher(name)
+	})
+
+	mockRepo.On("FindDuplicates", mock.MatchedBy(func(p *model.Person) bool {
+		return p.SurnameTranslit == "ushakov"
+	}), mock.Anything).Return([]model.Person{{ID: 7}, {ID: 9}}, nil)
+
+	req := model.CreatePersonRequest{Name: "Dmitry", Surname: "Ушаков"}
+	_, err := svc.CreatePerson(context.Background(), req)
+
+	var dup *service.DuplicateError
+	assert.ErrorAs(t, err, &dup)
+	assert.Equal(t, []uint{7, 9}, dup.Candidates)
+	assert.Zero(t, lookups, "a rejected duplicate is not enriched")
+	mockRepo.AssertNotCalled(t, "Save", mock.Anything)
+
+	mockRepo.On("Save", mock.Anything).Return(nil)
+	req.Force = true
+	_, err = svc.CreatePerson(context.Background(), req)
+
+	assert.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "FindDuplicates", 1)
+}
+
+func TestMergePersons_ResolvesFieldsAndRecordsHistory(t *testing.T) {
+	mockRepo := new(mockRepo)
+	history := new(mockHistory)
+	svc := service.NewPersonService(mockRepo).WithHistory(history)
+
+	target := &model.Person{ID: 1, Name: "Dmitriy", Surname: "Ushakov", Age: 40, Nationality: "RU", Version: 2}
+	source := &model.Person{ID: 2, Name: "Дмитрий", Surname: "Ушаков", Patronymic: "Васильевич", Gender: "male", Age: 41, Nationality: "RU", Version: 1}
+	mockRepo.On("FindByID", uint(1)).Return(target, nil)
+	mockRepo.On("FindByID", uint(2)).Return(source, nil)
+	mockRepo.On("Merge", mock.MatchedBy(func(p *model.Person) bool {
+		return p.Name == "Dmitriy" && p.Patronymic == "Васильевич" && p.Gender == "male" && p.Age == 41
+	}), []model.Person{*source}).Return(nil)
+
+	result, err := svc.MergePersons(context.Background(), model.MergeRequest{
+		TargetID:  1,
+		SourceIDs: []uint{2},
+		Fields:    map[string]uint{"age": 2},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2}, result.MergedIDs)
+	assert.Equal(t, []string{"name", "surname", "age"}, result.Conflicts)
+	mockRepo.AssertExpectations(t)
+	if assert.Len(t, mockRepo.history, 2) {
+		entries := mockRepo.history
+		assert.Equal(t, uint(1), entries[0].PersonID)
+		assert.Equal(t, model.HistoryActionMerge, entries[0].Action)
+		assert.Equal(t, model.FieldChange{Old: 40, New: 41}, entries[0].Changes["age"])
+		assert.Equal(t, uint(2), entries[1].PersonID)
+		assert.Equal(t, model.FieldChange{New: uint(1)}, entries[1].Changes["merged_into"])
+	}
+}
+
+func TestMergePersons_RejectsUnknownField(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	mockRepo.On("FindByID", uint(1)).Return(&model.Person{ID: 1}, nil)
+	mockRepo.On("FindByID", uint(2)).Return(&model.Person{ID: 2}, nil)
+
+	_, err := svc.MergePersons(context.Background(), model.MergeRequest{
+		TargetID:  1,
+		SourceIDs: []uint{2},
+		Fields:    map[string]uint{"version": 2},
+	})
+
+	assert.ErrorIs(t, err, service.ErrInvalidMerge)
+	mockRepo.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything)
+}
+
+func TestGetPersonStats_DefaultOptions(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	filter := model.PersonFilter{Nationality: "RU"}
+	mockRepo.On("Stats", filter, mock.MatchedBy(func(opts model.StatsOptions) bool {
+		return len(opts.AgeBuckets) > 0 && opts.TopNationalities > 0
+	})).Return(&model.PersonStats{Total: 3}, nil)
+
+	stats, err := svc.GetPersonStats(context.Background(), filter, model.StatsOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Total)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeletePersons_ConfirmTokenCoversFilterExpression(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	parse := func(src string) model.PersonFilter {
+		expr, err := filterexpr.Parse(src, model.PersonFilterFields)
+		assert.NoError(t, err)
+		return model.PersonFilter{Expr: expr}
+	}
+	mockRepo.On("CountByFilter", mock.Anything).Return(int64(2), nil)
+
+	preview, err := svc.DeletePersons(context.Background(), parse(`age > 90`), true, "")
+	assert.NoError(t, err)
+	again, err := svc.DeletePersons(context.Background(), parse(`age>90`), true, "")
+	assert.NoError(t, err)
+	other, err := svc.DeletePersons(context.Background(), parse(`age > 9`), true, "")
+	assert.NoError(t, err)
+
+	assert.Equal(t, preview.ConfirmToken, again.ConfirmToken)
+	assert.NotEqual(t, preview.ConfirmToken, other.ConfirmToken)
+}
+
+func TestSaveView_RejectsInvalidSortAndColumns(t *testing.T) {
+	views := new(mockViews)
+	svc := service.NewPersonService(new(mockRepo)).WithViews(views)
+
+	for _, req := range []model.SaveViewRequest{
+		{Name: "v", Filter: "age >>= 1"},
+		{Name: "v", Sort: "-salary"},
+		{Name: "v", Columns: []string{"id", "password"}},
+	} {
+		_, err := svc.SaveView(context.Background(), req)
+		assert.ErrorIs(t, err, service.ErrInvalidView)
+	}
+	views.AssertNotCalled(t, "Save", mock.Anything)
+}
+
+func TestGetViewPersons_UsesViewFilterAndSort(t *testing.T) {
+	mockRepo := new(mockRepo)
+	views := new(mockViews)
+	svc := service.NewPersonService(mockRepo).WithViews(views)
+
+	views.On("FindByName", "adults", uint(0)).Return(&model.View{Name: "adults", Version: 3, Filter: "age >= 18", Sort: "-age, surname"}, nil)
+	mockRepo.On("FindAll", mock.MatchedBy(func(f model.PersonFilter) bool {
+		return f.Expr != nil && f.Expr.String() == "age >= 18" &&
+			len(f.Sort) == 2 && f.Sort[0] == model.SortField{Field: "age", Desc: true} && f.Sort[1] == model.SortField{Field: "surname"} &&
+			f.Limit == 20 && f.Offset == 40
+	})).Return([]model.Person{{ID: 1}}, nil)
+
+	view, people, err := svc.GetViewPersons(context.Background(), "adults", 0, 20, 40)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), view.Version)
+	assert.Len(t, people, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+type recordingPublisher struct {
+	events []model.PersonEvent
+}
+
+func (p *recordingPublisher) Publish(events ...model.PersonEvent) {
+	p.events = append(p.events, events...)
+}
+
+func TestUpdatePerson_PublishesEvent(t *testing.T) {
+	mockRepo := new(mockRepo)
+	publisher := &recordingPublisher{}
+	svc := service.NewPersonService(mockRepo).WithEvents(publisher)
+
+	existing := &model.Person{ID: 1, Name: "Dmitriy", Surname: "Ushakov", Nationality: "UA", Version: 1}
+	mockRepo.On("FindByID", uint(1)).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.Person).Version++
+	}).Return(existing, nil)
+
+	ctx := service.WithActor(context.Background(), "operator@example.com")
+	_, err := svc.UpdatePerson(ctx, 1, model.UpdatePersonRequest{Nationality: "RU"})
+
+	assert.NoError(t, err)
+	assert.Len(t, publisher.events, 1)
+	ev := publisher.events[0]
+	assert.Equal(t, model.EventUpdated, ev.Type)
+	assert.Equal(t, uint(1), ev.PersonID)
+	assert.Equal(t, uint(2), ev.Revision)
+	assert.Equal(t, "RU", ev.Person.Nationality)
+	assert.Equal(t, "operator@example.com", ev.Actor)
+}
+
+func TestUpdatePerson_NoEventOnFailure(t *testing.T) {
+	mockRepo := new(mockRepo)
+	publisher := &recordingPublisher{}
+	svc := service.NewPersonService(mockRepo).WithEvents(publisher)
+
+	mockRepo.On("FindByID", uint(1)).Return(&model.Person{ID: 1, Name: "Dmitriy", Version: 1}, nil)
+	mockRepo.On("Update", mock.Anything).Return((*model.Person)(nil), repository.ErrVersionConflict)
+
+	_, err := svc.UpdatePerson(context.Background(), 1, model.UpdatePersonRequest{Nationality: "RU"})
+
+	assert.Error(t, err)
+	assert.Empty(t, publisher.events)
+}
+
+func TestUpdatePerson_WritesOutboxInsteadOfPublishing(t *testing.T) {
+	mockRepo := new(mockRepo)
+	publisher := &recordingPublisher{}
+	woken := 0
+	svc := service.NewPersonService(mockRepo).WithEvents(publisher).WithOutbox(func() { woken++ })
+
+	existing := &model.Person{ID: 1, Name: "Dmitriy", Surname: "Ushakov", Nationality: "UA", Version: 1}
+	mockRepo.On("FindByID", uint(1)).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.Person).Version++
+	}).Return(existing, nil)
+
+	_, err := svc.UpdatePerson(context.Background(), 1, model.UpdatePersonRequest{Nationality: "RU"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, publisher.events)
+	assert.Equal(t, 1, woken)
+	assert.Len(t, mockRepo.outbox, 1)
+	assert.Equal(t, model.EventUpdated, mockRepo.outbox[0].EventType)
+	assert.Contains(t, string(mockRepo.outbox[0].Payload), `"nationality":"RU"`)
+}
+
+func TestDeletePersons_NoOutboxWhenTransactionFails(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo).WithOutbox(nil)
+
+	filter := model.PersonFilter{Nationality: "RU"}
+	mockRepo.On("DeleteByFilter", filter).Return([]model.Person{{ID: 1, Nationality: "RU"}}, nil)
+
+	_, err := svc.DeletePersons(context.Background(), filter, false, "stale-token")
+
+	assert.ErrorIs(t, err, service.ErrConfirmationMismatch)
+	assert.Empty(t, mockRepo.outbox)
+}
+
+func TestGetChanges_MergesTombstonesInFeedOrder(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	// Transaction 8 took sequence 14 before transaction 9 took 12 and 13,
+	// so feed order is by transaction first.
+	deleted := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockRepo.On("FindChanges", model.ChangePosition{XID: 7, Sequence: 10}, 4).Return([]model.Person{
+		{ID: 1, ChangeXID: 8, Sequence: 14},
+		{ID: 2, ChangeXID: 9, Sequence: 13, DeletedAt: gorm.DeletedAt{Time: deleted, Valid: true}},
+		{ID: 4, ChangeXID: 10, Sequence: 11},
+	}, []model.PersonTombstone{
+		{PersonID: 3, ChangeXID: 9, Sequence: 12, DeletedAt: deleted},
+	}, nil)
+
+	changes, err := svc.GetChanges(context.Background(), "7.10", 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Person{{ID: 1, ChangeXID: 8, Sequence: 14}}, changes.Changed)
+	assert.Equal(t, []model.PersonTombstone{
+		{PersonID: 3, ChangeXID: 9, Sequence: 12, DeletedAt: deleted},
+		{PersonID: 2, ChangeXID: 9, Sequence: 13, DeletedAt: deleted},
+	}, changes.Deleted)
+	assert.Equal(t, "9.13", changes.Token)
+	assert.True(t, changes.HasMore)
+}
+
+func TestGetChanges_KeepsTokenWhenNothingChanged(t *testing.T) {
+	mockRepo := new(mockRepo)
+	svc := service.NewPersonService(mockRepo)
+
+	mockRepo.On("FindChanges", model.ChangePosition{XID: 3, Sequence: 42}, 501).Return([]model.Person{}, []model.PersonTombstone{}, nil)
+
+	changes, err := svc.GetChanges(context.Background(), "3.42", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.42", changes.Token)
+	assert.False(t, changes.HasMore)
+	assert.NotNil(t, changes.Changed)
+
+	for _, token := range []string{"42", "-1.3", "3.x"} {
+		_, err = svc.GetChanges(context.Background(), token, 0)
+		assert.ErrorIs(t, err, service.ErrInvalidChangeToken, token)
+	}
+}
+
+func TestReenrichPerson_RecordsEnrichedChanges(t *testing.T) {
+	mockRepo := new(mockRepo)
+	publisher := &recordingPublisher{}
+	svc := service.NewPersonService(mockRepo).WithEvents(publisher).WithEnricher(func(name string) (service.EnrichedData, error) {
+		return service.EnrichedData{Gender: "female", Age: 31}, nil
+	})
+
+	existing := &model.Person{ID: 1, Name: "Alice", Gender: "female", Age: 30, Nationality: "US", Version: 1}
+	mockRepo.On("FindByID", uint(1)).Return(existing, nil)
+	mockRepo.On("Update", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*model.Person).Version++
+	}).Return(existing, nil)
+
+	p, err := svc.ReenrichPerson(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 31, p.Age)
+	assert.Equal(t, "US", p.Nationality, "attributes without a lookup value are kept")
+	assert.Len(t, publisher.events, 1)
+	assert.Equal(t, model.EventEnriched, publisher.events[0].Type)
+	assert.Equal(t, map[string]model.FieldChange{"age": {Old: 30, New: 31}}, publisher.events[0].Changes)
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"effective-mobile/internal/model"
+)
+
+const defaultTopNationalities = 10
+
+// defaultAgeBuckets are the histogram bounds used when none are requested.
+var defaultAgeBuckets = []int{18, 25, 35, 45, 55, 65}
+
+// GetPersonStats aggregates the people matching filter, filling in default
+// histogram buckets and nationality limit when opts leaves them empty.
+func (s *PersonService) GetPersonStats(ctx context.Context, filter model.PersonFilter, opts model.StatsOptions) (*model.PersonStats, error) {
+	if len(opts.AgeBuckets) == 0 {
+		opts.AgeBuckets = defaultAgeBuckets
+	}
+	if opts.TopNationalities == 0 {
+		opts.TopNationalities = defaultTopNationalities
+	}
+	return s.repo.Stats(filter, opts)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+func (s *PersonService) GetDeletedPersons(ctx context.Context, filter model.PersonFilter) ([]model.DeletedPerson, error) {
+	people, err := s.repo.FindDeleted(filter)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make([]model.DeletedPerson, len(people))
+	for i, p := range people {
+		deleted[i] = model.DeletedPerson{Person: p, DeletedAt: p.DeletedAt.Time}
+	}
+	return deleted, nil
+}
+
+func (s *PersonService) RestorePerson(ctx context.Context, id uint) (*model.Person, error) {
+	var p *model.Person
+	err := s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		if err := repo.Restore(id); err != nil {
+			return nil, err
+		}
+		var err error
+		if p, err = repo.FindByID(id); err != nil {
+			return nil, err
+		}
+		return []model.PersonHistory{s.historyEntry(ctx, p, model.HistoryActionRestore, model.HistorySourceAPI, nil)}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *PersonService) PurgePerson(ctx context.Context, id uint) error {
+	return s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		p, err := repo.Purge(id)
+		if err != nil {
+			return nil, err
+		}
+		return []model.PersonHistory{s.historyEntry(ctx, p, model.HistoryActionPurge, model.HistorySourceAPI, nil)}, nil
+	})
+}
+
+// PurgeDeleted permanently removes people that were soft-deleted more than
+// retention ago and records a purge for each of them.
+func (s *PersonService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	var purged int64
+	err := s.write(ctx, func(repo repository.PersonRepositoryInterface) ([]model.PersonHistory, error) {
+		people, err := repo.PurgeDeletedBefore(time.Now().Add(-retention))
+		if err != nil {
+			return nil, err
+		}
+		purged = int64(len(people))
+		entries := make([]model.PersonHistory, len(people))
+		for i := range people {
+			entries[i] = s.historyEntry(ctx, &people[i], model.HistoryActionPurge, model.HistorySourceRetention, nil)
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// RunTrashRetention calls PurgeDeleted every interval until ctx is done.
+func (s *PersonService) RunTrashRetention(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeDeleted(ctx, retention)
+		if err != nil {
+			logger.Log.Error("trash retention failed", zap.Error(err))
+		} else if purged > 0 {
+			logger.Log.Info("purged deleted persons", zap.Int64("count", purged), zap.Duration("retention", retention))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
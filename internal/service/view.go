@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/filterexpr"
+)
+
+var (
+	// ErrViewsDisabled is returned by view operations when the service was
+	// built without a view repository.
+	ErrViewsDisabled = errors.New("saved views are not enabled")
+	ErrInvalidView   = errors.New("invalid view")
+)
+
+// WithViews enables saved views.
+func (s *PersonService) WithViews(views repository.ViewRepositoryInterface) *PersonService {
+	s.views = views
+	return s
+}
+
+// SaveView validates the view and stores it as the next version of its name.
+func (s *PersonService) SaveView(ctx context.Context, req model.SaveViewRequest) (*model.View, error) {
+	if s.views == nil {
+		return nil, ErrViewsDisabled
+	}
+
+	view := &model.View{
+		Name:    req.Name,
+		Filter:  req.Filter,
+		Sort:    req.Sort,
+		Columns: req.Columns,
+		Actor:   ActorFrom(ctx),
+	}
+	if _, err := viewFilter(view); err != nil {
+		return nil, err
+	}
+	if err := s.views.Save(view); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// GetViews returns the latest version of every saved view.
+func (s *PersonService) GetViews(ctx context.Context) ([]model.View, error) {
+	if s.views == nil {
+		return nil, ErrViewsDisabled
+	}
+	return s.views.FindLatest()
+}
+
+// GetView returns the given version of a view, or its latest version if
+// version is zero.
+func (s *PersonService) GetView(ctx context.Context, name string, version uint) (*model.View, error) {
+	if s.views == nil {
+		return nil, ErrViewsDisabled
+	}
+	return s.views.FindByName(name, version)
+}
+
+func (s *PersonService) GetViewVersions(ctx context.Context, name string) ([]model.View, error) {
+	if s.views == nil {
+		return nil, ErrViewsDisabled
+	}
+	views, err := s.views.FindVersions(name)
+	if err == nil && len(views) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return views, err
+}
+
+// GetViewPersons runs a view through the regular person listing and
+// returns the page of people together with the view version used.
+func (s *PersonService) GetViewPersons(ctx context.Context, name string, version uint, limit, offset int) (*model.View, []model.Person, error) {
+	view, err := s.GetView(ctx, name, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter, err := viewFilter(view)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter.Limit, filter.Offset = limit, offset
+
+	people, err := s.repo.FindAll(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return view, people, nil
+}
+
+// viewFilter builds the person filter of a view and checks its columns.
+func viewFilter(view *model.View) (model.PersonFilter, error) {
+	var filter model.PersonFilter
+
+	if view.Filter != "" {
+		expr, err := filterexpr.Parse(view.Filter, model.PersonFilterFields)
+		if err != nil {
+			return filter, fmt.Errorf("%w: filter: %w", ErrInvalidView, err)
+		}
+		filter.Expr = expr
+	}
+
+	if view.Sort != "" {
+		for _, field := range strings.Split(view.Sort, ",") {
+			field = strings.TrimSpace(field)
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimLeft(field, "+-")
+			if !slices.Contains(model.PersonColumns, field) {
+				return filter, fmt.Errorf("%w: can't sort by %q", ErrInvalidView, field)
+			}
+			filter.Sort = append(filter.Sort, model.SortField{Field: field, Desc: desc})
+		}
+	}
+
+	for _, column := range view.Columns {
+		if !slices.Contains(model.PersonColumns, column) {
+			return filter, fmt.Errorf("%w: unknown column %q", ErrInvalidView, column)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/repository"
+	"effective-mobile/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWebhookAttempts = 8
+	webhookRetryBase       = 10 * time.Second
+	webhookRetryMax        = time.Hour
+	webhookTimeout         = 10 * time.Second
+	webhookLease           = time.Minute
+	webhookBatchSize       = 50
+	webhookWorkers         = 8
+)
+
+// Headers of a webhook delivery. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret, prefixed with
+// "sha256=".
+const (
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// ErrWebhookTarget is returned by CreateWebhook for a URL that is not
+// http(s) or points into a loopback, link-local or private network, and
+// fails deliveries that would connect to such an address.
+var ErrWebhookTarget = errors.New("webhook target not allowed")
+
+type WebhookServiceInterface interface {
+	CreateWebhook(ctx context.Context, req model.CreateWebhookRequest) (*model.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id uint) error
+	GetDeliveries(ctx context.Context, webhookID uint, status string, limit, offset int) ([]model.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, webhookID, deliveryID uint) (*model.WebhookDelivery, error)
+}
+
+// WebhookService registers webhooks and delivers person events to them.
+// Send only records the deliveries; Run sends them in the background and
+// retries failures with exponential backoff, so a slow receiver never holds
+// up a write.
+type WebhookService struct {
+	repo        repository.WebhookRepositoryInterface
+	client      *http.Client
+	maxAttempts int
+	wake        chan struct{}
+}
+
+func NewWebhookService(repo repository.WebhookRepositoryInterface) *WebhookService {
+	return &WebhookService{
+		repo:        repo,
+		client:      &http.Client{Timeout: webhookTimeout, Transport: webhookTransport()},
+		maxAttempts: defaultWebhookAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// webhookTransport checks every address it connects to, redirects
+// included, with checkWebhookAddr, so that a receiver can't reach the
+// internal network by resolving its name to an internal address after it
+// was registered. Proxies are not used, as they would be checked instead
+// of the receiver.
+func webhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			return checkWebhookAddr(addr)
+		},
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+// checkWebhookAddr fails with ErrWebhookTarget unless addr is a public
+// unicast address.
+func checkWebhookAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return fmt.Errorf("%w: %s is not a public address", ErrWebhookTarget, addr)
+	}
+	return nil
+}
+
+// checkWebhookURL fails with ErrWebhookTarget unless raw is an http(s) URL
+// whose host only resolves to public addresses.
+func checkWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookTarget, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not http or https", ErrWebhookTarget, u.Scheme)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookTarget, err)
+	}
+	for _, addr := range addrs {
+		if err := checkWebhookAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithMaxAttempts sets how many times a delivery is tried before it is
+// marked failed.
+func (s *WebhookService) WithMaxAttempts(n int) *WebhookService {
+	s.maxAttempts = n
+	return s
+}
+
+// WithHTTPClient sets the client deliveries are sent with.
+func (s *WebhookService) WithHTTPClient(c *http.Client) *WebhookService {
+	s.client = c
+	return s
+}
+
+// CreateWebhook registers a webhook after checking its URL, see
+// ErrWebhookTarget.
+func (s *WebhookService) CreateWebhook(ctx context.Context, req model.CreateWebhookRequest) (*model.Webhook, error) {
+	if err := checkWebhookURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	hook := &model.Webhook{URL: req.URL, Events: req.Events, Secret: req.Secret}
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+	if err := s.repo.Create(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// GetWebhooks returns the registered webhooks without their secrets.
+func (s *WebhookService) GetWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	hooks, err := s.repo.FindAll()
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, err
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	return s.repo.Delete(id)
+}
+
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID uint, status string, limit, offset int) ([]model.WebhookDelivery, error) {
+	if _, err := s.repo.FindByID(webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindDeliveries(webhookID, status, limit, offset)
+}
+
+// ReplayDelivery sends a logged delivery again as a new delivery, leaving
+// the original entry of the log as it is.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, webhookID, deliveryID uint) (*model.WebhookDelivery, error) {
+	original, err := s.repo.FindDelivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := []model.WebhookDelivery{{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: time.Now(),
+		ReplayOf:      &original.ID,
+	}}
+	if err := s.repo.CreateDeliveries(replay); err != nil {
+		return nil, err
+	}
+	s.notify()
+	return &replay[0], nil
+}
+
+func (s *WebhookService) Name() string {
+	return "webhooks"
+}
+
+// Send records a pending delivery of each event for every webhook that
+// subscribes to it. Events it has already recorded are skipped.
+func (s *WebhookService) Send(ctx context.Context, events []model.PersonEvent) error {
+	hooks, err := s.repo.FindAll()
+	if err != nil {
+		return err
+	}
+
+	var deliveries []model.WebhookDelivery
+	for i := range events {
+		ev := &events[i]
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		for _, hook := range hooks {
+			if !hook.Accepts(ev.Type) {
+				continue
+			}
+			deliveries = append(deliveries, model.WebhookDelivery{
+				WebhookID:     hook.ID,
+				EventID:       ev.ID,
+				EventType:     ev.Type,
+				Payload:       payload,
+				Status:        model.DeliveryPending,
+				NextAttemptAt: ev.Time,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := s.repo.CreateDeliveries(deliveries); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries until ctx is done. It checks for due retries
+// every interval and for new deliveries as soon as they are recorded.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.Dispatch(ctx)
+			if err != nil {
+				logger.Log.Error("webhook dispatch failed", zap.Error(err))
+			}
+			if err != nil || n < webhookBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Dispatch sends one batch of due deliveries and returns its size.
+func (s *WebhookService) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.ClaimDueDeliveries(time.Now(), webhookLease, webhookBatchSize)
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	hooks := map[uint]*model.Webhook{}
+	for _, d := range deliveries {
+		if _, ok := hooks[d.WebhookID]; ok {
+			continue
+		}
+		hook, err := s.repo.FindByID(d.WebhookID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return 0, err
+		}
+		hooks[d.WebhookID] = hook
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, webhookWorkers)
+	for i := range deliveries {
+		d := &deliveries[i]
+		hook := hooks[d.WebhookID]
+		if hook == nil {
+			// Deleted while the delivery was being claimed.
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.attempt(ctx, hook, d)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// attempt sends d once and records the outcome, scheduling a retry when
+// attempts remain. A target that is not allowed is not retried.
+func (s *WebhookService) attempt(ctx context.Context, hook *model.Webhook, d *model.WebhookDelivery) {
+	d.Attempts++
+	d.ResponseCode, d.LastError = 0, ""
+
+	code, err := s.send(ctx, hook, d)
+	d.ResponseCode = code
+	switch {
+	case err == nil:
+		d.Status = model.DeliveryDelivered
+	case d.Attempts >= s.maxAttempts || errors.Is(err, ErrWebhookTarget):
+		d.Status = model.DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.NextAttemptAt = time.Now().Add(webhookBackoff(d.Attempts))
+		d.LastError = err.Error()
+	}
+
+	if err != nil {
+		logger.Log.Warn("webhook delivery failed",
+			zap.Uint("webhook_id", hook.ID), zap.Uint("delivery_id", d.ID),
+			zap.Int("attempt", d.Attempts), zap.String("status", d.Status), zap.Error(err))
+	}
+	if err := s.repo.UpdateDelivery(d); err != nil {
+		logger.Log.Error("failed to update webhook delivery", zap.Uint("delivery_id", d.ID), zap.Error(err))
+	}
+}
+
+func (s *WebhookService) send(ctx context.Context, hook *model.Webhook, d *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, strconv.FormatUint(uint64(hook.ID), 10))
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, WebhookSignature(hook.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// WebhookSignature returns the X-Webhook-Signature value of a delivery.
+// Receivers recompute it to check that a delivery is authentic and compare
+// the timestamp with their clock to reject replays.
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the delay before the retry that follows the given
+// attempt: exponential from webhookRetryBase up to webhookRetryMax, with up
+// to 20% jitter so that retries to one receiver spread out.
+func webhookBackoff(attempt int) time.Duration {
+	d := webhookRetryMax
+	if attempt < 20 {
+		d = min(webhookRetryBase<<(attempt-1), webhookRetryMax)
+	}
+	return d + mathrand.N(d/5+1)
+}
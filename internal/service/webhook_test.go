@@ -0,0 +1,220 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/internal/service"
+	"effective-mobile/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func init() {
+	logger.Init()
+}
+
+type mockWebhooks struct {
+	mock.Mock
+}
+
+func (m *mockWebhooks) Create(w *model.Webhook) error {
+	args := m.Called(w)
+	return args.Error(0)
+}
+
+func (m *mockWebhooks) FindAll() ([]model.Webhook, error) {
+	args := m.Called()
+	return args.Get(0).([]model.Webhook), args.Error(1)
+}
+
+func (m *mockWebhooks) FindByID(id uint) (*model.Webhook, error) {
+	args := m.Called(id)
+	return args.Get(0).(*model.Webhook), args.Error(1)
+}
+
+func (m *mockWebhooks) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockWebhooks) CreateDeliveries(deliveries []model.WebhookDelivery) error {
+	args := m.Called(deliveries)
+	return args.Error(0)
+}
+
+func (m *mockWebhooks) FindDeliveries(webhookID uint, status string, limit, offset int) ([]model.WebhookDelivery, error) {
+	args := m.Called(webhookID, status, limit, offset)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *mockWebhooks) FindDelivery(webhookID, id uint) (*model.WebhookDelivery, error) {
+	args := m.Called(webhookID, id)
+	return args.Get(0).(*model.WebhookDelivery), args.Error(1)
+}
+
+func (m *mockWebhooks) ClaimDueDeliveries(now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	args := m.Called(now, lease, limit)
+	return args.Get(0).([]model.WebhookDelivery), args.Error(1)
+}
+
+func (m *mockWebhooks) UpdateDelivery(d *model.WebhookDelivery) error {
+	args := m.Called(d)
+	return args.Error(0)
+}
+
+func TestWebhookSend_RecordsDeliveriesForSubscribedHooks(t *testing.T) {
+	repo := new(mockWebhooks)
+	svc := service.NewWebhookService(repo)
+
+	repo.On("FindAll").Return([]model.Webhook{
+		{ID: 1, URL: "http://a.example"},
+		{ID: 2, URL: "http://b.example", Events: []string{model.EventDeleted}},
+	}, nil)
+	repo.On("CreateDeliveries", mock.MatchedBy(func(ds []model.WebhookDelivery) bool {
+		return len(ds) == 1 && ds[0].WebhookID == 1 && ds[0].EventID == 7 &&
+			ds[0].Status == model.DeliveryPending && string(ds[0].Payload) != ""
+	})).Return(nil)
+
+	err := svc.Send(context.Background(), []model.PersonEvent{{ID: 7, Type: model.EventCreated, PersonID: 1, Time: time.Now()}})
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookDispatch_SignsAndRetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	secret := "0123456789abcdef"
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(service.WebhookTimestampHeader), 10, 64)
+		if r.Header.Get(service.WebhookSignatureHeader) != service.WebhookSignature(secret, ts, body) {
+			t.Errorf("bad signature %q", r.Header.Get(service.WebhookSignatureHeader))
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer receiver.Close()
+
+	repo := new(mockWebhooks)
+	svc := service.NewWebhookService(repo).WithMaxAttempts(2).WithHTTPClient(receiver.Client())
+	hook := &model.Webhook{ID: 1, URL: receiver.URL, Secret: secret}
+	delivery := model.WebhookDelivery{ID: 5, WebhookID: 1, EventType: model.EventCreated, Payload: []byte(`{"id":7}`), Status: model.DeliveryPending}
+
+	repo.On("FindByID", uint(1)).Return(hook, nil)
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]model.WebhookDelivery{delivery}, nil).Once()
+	var updated []model.WebhookDelivery
+	repo.On("UpdateDelivery", mock.Anything).Run(func(args mock.Arguments) {
+		updated = append(updated, *args.Get(0).(*model.WebhookDelivery))
+	}).Return(nil)
+
+	start := time.Now()
+	n, err := svc.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, model.DeliveryPending, updated[0].Status)
+	assert.Equal(t, http.StatusBadGateway, updated[0].ResponseCode)
+	assert.WithinRange(t, updated[0].NextAttemptAt, start.Add(10*time.Second), start.Add(13*time.Second))
+
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]model.WebhookDelivery{updated[0]}, nil).Once()
+	_, err = svc.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, model.DeliveryDelivered, updated[1].Status)
+	assert.Equal(t, 2, updated[1].Attempts)
+	assert.Empty(t, updated[1].LastError)
+}
+
+func TestWebhookDispatch_FailsAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	repo := new(mockWebhooks)
+	svc := service.NewWebhookService(repo).WithMaxAttempts(3).WithHTTPClient(receiver.Client())
+	repo.On("FindByID", uint(1)).Return(&model.Webhook{ID: 1, URL: receiver.URL, Secret: "s"}, nil)
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).
+		Return([]model.WebhookDelivery{{ID: 5, WebhookID: 1, Attempts: 2, Status: model.DeliveryPending}}, nil)
+	repo.On("UpdateDelivery", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+		return d.Status == model.DeliveryFailed && d.Attempts == 3 && d.LastError != ""
+	})).Return(nil)
+
+	_, err := svc.Dispatch(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookDispatch_RefusesInternalAddresses(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	// The default client, unlike receiver.Client(), checks the address it
+	// connects to, whatever the webhook was registered with.
+	repo := new(mockWebhooks)
+	svc := service.NewWebhookService(repo)
+	repo.On("FindByID", uint(1)).Return(&model.Webhook{ID: 1, URL: receiver.URL, Secret: "s"}, nil)
+	repo.On("ClaimDueDeliveries", mock.Anything, mock.Anything, mock.Anything).
+		Return([]model.WebhookDelivery{{ID: 5, WebhookID: 1, Status: model.DeliveryPending}}, nil)
+	repo.On("UpdateDelivery", mock.MatchedBy(func(d *model.WebhookDelivery) bool {
+		return d.Status == model.DeliveryFailed && strings.Contains(d.LastError, service.ErrWebhookTarget.Error())
+	})).Return(nil)
+
+	_, err := svc.Dispatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.Zero(t, calls.Load())
+	repo.AssertExpectations(t)
+}
+
+func TestCreateWebhook_RejectsInternalTargets(t *testing.T) {
+	repo := new(mockWebhooks)
+	svc := service.NewWebhookService(repo)
+
+	for _, target := range []string{
+		"ftp://example.com/hook",
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+	} {
+		_, err := svc.CreateWebhook(context.Background(), model.CreateWebhookRequest{URL: target})
+		assert.ErrorIs(t, err, service.ErrWebhookTarget, target)
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestReplayDelivery_CreatesNewDelivery(t *testing.T) {
+	repo := new(mockWebhooks)
+	svc := service.NewWebhookService(repo)
+
+	repo.On("FindDelivery", uint(1), uint(5)).Return(&model.WebhookDelivery{
+		ID: 5, WebhookID: 1, EventID: 7, EventType: model.EventUpdated, Payload: []byte(`{}`), Status: model.DeliveryFailed, Attempts: 8,
+	}, nil)
+	repo.On("CreateDeliveries", mock.MatchedBy(func(ds []model.WebhookDelivery) bool {
+		d := ds[0]
+		return d.EventID == 7 && d.Status == model.DeliveryPending && d.Attempts == 0 && d.ReplayOf != nil && *d.ReplayOf == 5
+	})).Return(nil)
+
+	replay, err := svc.ReplayDelivery(context.Background(), 1, 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), *replay.ReplayOf)
+	repo.AssertExpectations(t)
+}
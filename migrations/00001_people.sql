@@ -0,0 +1,21 @@
+-- +goose Up
+-- The people table as AutoMigrate creates it from model.Person, so that the
+-- migrations that follow can be applied to an empty database. The columns
+-- they add are left to them.
+CREATE TABLE IF NOT EXISTS people (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT,
+    surname TEXT,
+    patronymic TEXT,
+    gender TEXT,
+    age BIGINT,
+    nationality TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+ALTER TABLE people ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_people_deleted_at ON people (deleted_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS people;
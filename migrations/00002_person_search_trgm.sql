@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_people_name_trgm ON people USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_surname_trgm ON people USING GIN (surname gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_patronymic_trgm ON people USING GIN (patronymic gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_full_name_trgm ON people
+    USING GIN ((name || ' ' || surname || ' ' || coalesce(patronymic, '')) gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_people_full_name_trgm;
+DROP INDEX IF EXISTS idx_people_patronymic_trgm;
+DROP INDEX IF EXISTS idx_people_surname_trgm;
+DROP INDEX IF EXISTS idx_people_name_trgm;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE people ADD COLUMN IF NOT EXISTS name_translit TEXT NOT NULL DEFAULT '';
+ALTER TABLE people ADD COLUMN IF NOT EXISTS surname_translit TEXT NOT NULL DEFAULT '';
+ALTER TABLE people ADD COLUMN IF NOT EXISTS patronymic_translit TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_people_name_translit_trgm ON people USING GIN (name_translit gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_surname_translit_trgm ON people USING GIN (surname_translit gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_patronymic_translit_trgm ON people USING GIN (patronymic_translit gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_people_full_translit_trgm ON people
+    USING GIN ((name_translit || ' ' || surname_translit || ' ' || patronymic_translit) gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_people_full_translit_trgm;
+DROP INDEX IF EXISTS idx_people_patronymic_translit_trgm;
+DROP INDEX IF EXISTS idx_people_surname_translit_trgm;
+DROP INDEX IF EXISTS idx_people_name_translit_trgm;
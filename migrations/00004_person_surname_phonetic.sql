@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE people ADD COLUMN IF NOT EXISTS surname_phonetic TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_people_surname_phonetic ON people (surname_phonetic);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_people_surname_phonetic;
+ALTER TABLE people DROP COLUMN IF EXISTS surname_phonetic;
@@ -0,0 +1,59 @@
+-- +goose Up
+-- Every insert, versioned update and soft delete of a person takes the next
+-- value of person_changes_seq as its sequence, and every hard delete leaves
+-- a tombstone with one. The value is taken under a transaction-level
+-- advisory lock, so writers commit in sequence order and a reader of
+-- GET /person/changes never sees a change before an earlier one commits.
+CREATE SEQUENCE IF NOT EXISTS person_changes_seq;
+
+ALTER TABLE people ADD COLUMN IF NOT EXISTS sequence BIGINT NOT NULL DEFAULT 0;
+UPDATE people SET sequence = nextval('person_changes_seq') WHERE sequence = 0;
+CREATE INDEX IF NOT EXISTS idx_people_sequence ON people (sequence);
+
+CREATE TABLE IF NOT EXISTS person_tombstones (
+    sequence BIGINT PRIMARY KEY,
+    person_id BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_person_tombstones_person_id ON person_tombstones (person_id);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION person_changes_sequence() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.version IS DISTINCT FROM OLD.version OR NEW.deleted_at IS DISTINCT FROM OLD.deleted_at THEN
+        PERFORM pg_advisory_xact_lock(0, hashtext('person_changes'));
+        NEW.sequence := nextval('person_changes_seq');
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION person_changes_tombstone() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(0, hashtext('person_changes'));
+    INSERT INTO person_tombstones (sequence, person_id, deleted_at)
+    VALUES (nextval('person_changes_seq'), OLD.id, now());
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS people_changes_sequence ON people;
+CREATE TRIGGER people_changes_sequence BEFORE INSERT OR UPDATE ON people
+    FOR EACH ROW EXECUTE FUNCTION person_changes_sequence();
+
+DROP TRIGGER IF EXISTS people_changes_tombstone ON people;
+CREATE TRIGGER people_changes_tombstone AFTER DELETE ON people
+    FOR EACH ROW EXECUTE FUNCTION person_changes_tombstone();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS people_changes_tombstone ON people;
+DROP TRIGGER IF EXISTS people_changes_sequence ON people;
+DROP FUNCTION IF EXISTS person_changes_tombstone();
+DROP FUNCTION IF EXISTS person_changes_sequence();
+DROP TABLE IF EXISTS person_tombstones;
+DROP INDEX IF EXISTS idx_people_sequence;
+ALTER TABLE people DROP COLUMN IF EXISTS sequence;
+DROP SEQUENCE IF EXISTS person_changes_seq;
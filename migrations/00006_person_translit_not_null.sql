@@ -0,0 +1,20 @@
+-- +goose Up
+-- AutoMigrate may have added the translit columns as nullable before 00003
+-- ran, leaving NULL in the rows stored before them.
+UPDATE people SET name_translit = '' WHERE name_translit IS NULL;
+UPDATE people SET surname_translit = '' WHERE surname_translit IS NULL;
+UPDATE people SET patronymic_translit = '' WHERE patronymic_translit IS NULL;
+
+ALTER TABLE people
+    ALTER COLUMN name_translit SET DEFAULT '',
+    ALTER COLUMN name_translit SET NOT NULL,
+    ALTER COLUMN surname_translit SET DEFAULT '',
+    ALTER COLUMN surname_translit SET NOT NULL,
+    ALTER COLUMN patronymic_translit SET DEFAULT '',
+    ALTER COLUMN patronymic_translit SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE people
+    ALTER COLUMN name_translit DROP NOT NULL,
+    ALTER COLUMN surname_translit DROP NOT NULL,
+    ALTER COLUMN patronymic_translit DROP NOT NULL;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- AutoMigrate may have added surname_phonetic as nullable before 00004 ran,
+-- leaving NULL in the rows stored before it.
+UPDATE people SET surname_phonetic = '' WHERE surname_phonetic IS NULL;
+
+ALTER TABLE people
+    ALTER COLUMN surname_phonetic SET DEFAULT '',
+    ALTER COLUMN surname_phonetic SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE people ALTER COLUMN surname_phonetic DROP NOT NULL;
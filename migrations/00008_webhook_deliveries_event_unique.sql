@@ -0,0 +1,27 @@
+-- +goose Up
+-- The outbox may send a batch to the webhooks again, so a webhook keeps one
+-- delivery per event besides its replays. Duplicates recorded before are
+-- removed, keeping the first.
+-- On an empty database the table does not exist yet; AutoMigrate creates it
+-- with the index.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF to_regclass('webhook_deliveries') IS NULL THEN
+        RETURN;
+    END IF;
+
+    DELETE FROM webhook_deliveries d
+    USING webhook_deliveries first
+    WHERE d.replay_of IS NULL AND first.replay_of IS NULL
+      AND d.webhook_id = first.webhook_id AND d.event_id = first.event_id
+      AND d.id > first.id;
+
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event
+        ON webhook_deliveries (webhook_id, event_id) WHERE replay_of IS NULL;
+END
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_webhook_deliveries_event;
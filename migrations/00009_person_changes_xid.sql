@@ -0,0 +1,61 @@
+-- +goose Up
+-- Changes are no longer serialized with an advisory lock. Every change also
+-- records the transaction that made it, and GET /person/changes orders by
+-- transaction, then sequence, reading only changes of transactions older
+-- than any still running. Changes made before have transaction 0, so they
+-- come first in sequence order.
+ALTER TABLE people ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT '0';
+ALTER TABLE person_tombstones ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT '0';
+CREATE INDEX IF NOT EXISTS idx_people_change ON people (change_xid, sequence);
+CREATE INDEX IF NOT EXISTS idx_person_tombstones_change ON person_tombstones (change_xid, sequence);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION person_changes_sequence() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.version IS DISTINCT FROM OLD.version OR NEW.deleted_at IS DISTINCT FROM OLD.deleted_at THEN
+        NEW.sequence := nextval('person_changes_seq');
+        NEW.change_xid := pg_current_xact_id();
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION person_changes_tombstone() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO person_tombstones (sequence, change_xid, person_id, deleted_at)
+    VALUES (nextval('person_changes_seq'), pg_current_xact_id(), OLD.id, now());
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION person_changes_sequence() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.version IS DISTINCT FROM OLD.version OR NEW.deleted_at IS DISTINCT FROM OLD.deleted_at THEN
+        PERFORM pg_advisory_xact_lock(0, hashtext('person_changes'));
+        NEW.sequence := nextval('person_changes_seq');
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION person_changes_tombstone() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(0, hashtext('person_changes'));
+    INSERT INTO person_tombstones (sequence, person_id, deleted_at)
+    VALUES (nextval('person_changes_seq'), OLD.id, now());
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS idx_person_tombstones_change;
+DROP INDEX IF EXISTS idx_people_change;
+ALTER TABLE person_tombstones DROP COLUMN IF EXISTS change_xid;
+ALTER TABLE people DROP COLUMN IF EXISTS change_xid;
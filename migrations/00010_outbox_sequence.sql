@@ -0,0 +1,23 @@
+-- +goose Up
+-- Event IDs are numbered when the dispatcher fans messages out, instead of
+-- reusing the outbox ID, which is allocated at insert and so can commit out
+-- of order. Messages dispatched before keep their IDs, so that clients can
+-- resume from them. On an empty database the table does not exist yet;
+-- AutoMigrate creates it with the column.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF to_regclass('outbox') IS NULL THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE outbox ADD COLUMN IF NOT EXISTS sequence BIGINT NOT NULL DEFAULT 0;
+    UPDATE outbox SET sequence = id WHERE sequence = 0 AND dispatched_at IS NOT NULL;
+    CREATE INDEX IF NOT EXISTS idx_outbox_sequence ON outbox (sequence);
+END
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_outbox_sequence;
+ALTER TABLE IF EXISTS outbox DROP COLUMN IF EXISTS sequence;
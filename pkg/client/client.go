@@ -0,0 +1,280 @@
+// Package client is a typed Go client of the People API.
+//
+// Every method takes a context and returns the API types defined here.
+// Responses other than 2xx are returned as *Error, which errors.Is matches
+// against the sentinel errors of their status codes. Calls that are safe to
+// repeat — reads, unconditional PUT and DELETE, and creations, which are
+// sent with an Idempotency-Key — are retried on network errors and on 502,
+// 503 and 504 responses. A retried DELETE that finds nothing to delete
+// succeeds, since an earlier attempt may have deleted it.
+//
+//	c := client.New("http://localhost:8080").WithActor("billing")
+//	p, err := c.CreatePerson(ctx, client.CreatePersonRequest{Name: "Dmitriy", Surname: "Ushakov"})
+//	if errors.Is(err, client.ErrConflict) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// apiPrefix is the path of the version of the API the client speaks;
+	// request paths are relative to it.
+	apiPrefix = "/v1"
+
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	retryBaseDelay    = 200 * time.Millisecond
+	retryMaxDelay     = 5 * time.Second
+
+	// maxErrorTextSize caps the plain-text error messages kept in *Error.
+	// JSON errors, such as the item results of an atomic batch, are read
+	// in full.
+	maxErrorTextSize = 64 << 10
+)
+
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	actor      string
+	adminToken string
+	maxRetries int
+}
+
+// New returns a client of the API at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+	}
+}
+
+// WithHTTPClient sets the client requests are sent with. Its timeout does
+// not apply to StreamEvents.
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.http = hc
+	return c
+}
+
+// WithActor sets the X-Actor header that names the author of changes.
+func (c *Client) WithActor(actor string) *Client {
+	c.actor = actor
+	return c
+}
+
+// WithAdminToken sets the X-Admin-Token header that admin-only operations,
+// such as PurgePerson and the webhook methods, require.
+func (c *Client) WithAdminToken(token string) *Client {
+	c.adminToken = token
+	return c
+}
+
+// WithMaxRetries sets how many times a failed idempotent call is repeated.
+// Zero disables retries.
+func (c *Client) WithMaxRetries(n int) *Client {
+	c.maxRetries = n
+	return c
+}
+
+// request is one API call. Only a request with a payload, or none, can be
+// retried; a streamed body is sent once.
+type request struct {
+	method  string
+	path    string
+	query   url.Values
+	header  http.Header
+	payload []byte
+	stream  io.Reader
+	retry   bool
+}
+
+// newRequest returns a request to path; GET requests are retried.
+func newRequest(method, path string) *request {
+	return &request{method: method, path: path, query: url.Values{}, header: http.Header{}, retry: method == http.MethodGet}
+}
+
+// json sets v as the JSON body of the request.
+func (r *request) json(v any) *request {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// The API types always marshal.
+		panic(err)
+	}
+	r.payload = data
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// idempotent makes a POST safe to retry by sending it with a fresh
+// Idempotency-Key: the server replays the response of the first attempt
+// that got through.
+func (r *request) idempotent() *request {
+	key := make([]byte, 16)
+	rand.Read(key)
+	r.header.Set("Idempotency-Key", hex.EncodeToString(key))
+	r.retry = true
+	return r
+}
+
+// do sends req and decodes the JSON body of a 2xx response into out, if out
+// is not nil.
+func (c *Client) do(ctx context.Context, req *request, out any) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send sends req, retrying it when allowed, and returns the response if it
+// is a 2xx one. The caller closes its body.
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if err == nil {
+			err = readError(resp)
+		}
+		if attempt > 0 && req.method == http.MethodDelete && errors.Is(err, ErrNotFound) {
+			// An attempt whose response was lost went through.
+			return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+		}
+
+		if !req.retry || attempt >= c.maxRetries || !retryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryBackoff(attempt)):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req *request) (*http.Response, error) {
+	body := req.stream
+	if req.payload != nil {
+		body = bytes.NewReader(req.payload)
+	}
+
+	u := c.baseURL + apiPrefix + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	hr, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range req.header {
+		hr.Header[k] = v
+	}
+	if hr.Header.Get("Accept") == "" {
+		hr.Header.Set("Accept", "application/json")
+	}
+	if c.actor != "" {
+		hr.Header.Set("X-Actor", c.actor)
+	}
+	if c.adminToken != "" {
+		hr.Header.Set("X-Admin-Token", c.adminToken)
+	}
+	return c.http.Do(hr)
+}
+
+// retryable reports whether a failed attempt may succeed when repeated.
+func retryable(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryBackoff returns the delay before the retry that follows the given
+// attempt: exponential from retryBaseDelay up to retryMaxDelay, with up to
+// 20% jitter.
+func retryBackoff(attempt int) time.Duration {
+	d := retryMaxDelay
+	if attempt < 20 {
+		d = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+	return d + mathrand.N(d/5+1)
+}
+
+// Filter selects people like the query parameters of GET /person. Expr is a
+// filter expression, e.g. "age >= 30 and nationality in ('RU', 'KZ')".
+type Filter struct {
+	Name        string
+	Surname     string
+	Patronymic  string
+	Gender      string
+	Nationality string
+	AgeMin      int
+	AgeMax      int
+	Expr        string
+}
+
+func (f Filter) apply(q url.Values) {
+	for param, v := range map[string]string{
+		"name":        f.Name,
+		"surname":     f.Surname,
+		"patronymic":  f.Patronymic,
+		"gender":      f.Gender,
+		"nationality": f.Nationality,
+		"filter":      f.Expr,
+	} {
+		if v != "" {
+			q.Set(param, v)
+		}
+	}
+	setInt(q, "age_min", f.AgeMin)
+	setInt(q, "age_max", f.AgeMax)
+}
+
+// page sets limit and offset; zero values are left to the server defaults.
+func page(q url.Values, limit, offset int) {
+	setInt(q, "limit", limit)
+	setInt(q, "offset", offset)
+}
+
+func setInt(q url.Values, param string, v int) {
+	if v != 0 {
+		q.Set(param, strconv.Itoa(v))
+	}
+}
+
+// ifMatch sets the entity tag of the expected version, if any. Only
+// requests without one are retried: a repeat of a conditional change that
+// went through would fail with a version mismatch.
+func (r *request) ifMatch(version uint) *request {
+	if version == 0 {
+		r.retry = true
+		return r
+	}
+	r.header.Set("If-Match", `"`+strconv.FormatUint(uint64(version), 10)+`"`)
+	return r
+}
@@ -0,0 +1,477 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"effective-mobile/internal/model"
+	"effective-mobile/pkg/client"
+
+	"github.com/gorilla/websocket"
+)
+
+var errStop = errors.New("stop")
+
+// TestCoversSpec calls every client method against a server that serves
+// each operation of docs/swagger.json, and checks that every operation was
+// called.
+func TestCoversSpec(t *testing.T) {
+	data, err := os.ReadFile("../../docs/swagger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Schema struct {
+					Type string `json:"type"`
+				} `json:"schema"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	called := map[string]bool{}
+	mux := http.NewServeMux()
+	for path, ops := range spec.Paths {
+		for method, op := range ops {
+			pattern := strings.ToUpper(method) + " " + path
+			status, schema := 0, ""
+			for code, resp := range op.Responses {
+				if n, _ := strconv.Atoi(code); n < 300 && (status == 0 || n < status) {
+					status, schema = n, resp.Schema.Type
+				}
+			}
+			called[pattern] = false
+			mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				called[pattern] = true
+				mu.Unlock()
+				serveSpecResponse(w, r, status, schema)
+			})
+		}
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := client.New(srv.URL).WithMaxRetries(0)
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"CreatePerson": func() error {
+			_, err := c.CreatePerson(ctx, client.CreatePersonRequest{Name: "Dmitriy", Surname: "Ushakov"})
+			return err
+		},
+		"CreatePersons": func() error {
+			_, err := c.CreatePersons(ctx, []client.CreatePersonRequest{{Name: "Dmitriy", Surname: "Ushakov"}}, true)
+			return err
+		},
+		"ImportPersons": func() error {
+			_, err := c.ImportPersons(ctx, strings.NewReader("name,surname\nDmitriy,Ushakov\n"))
+			return err
+		},
+		"ListPersons": func() error { _, err := c.ListPersons(ctx, client.Filter{}, 10, 0); return err },
+		"ExportPersons": func() error {
+			rc, err := c.ExportPersons(ctx, client.FormatCSV, client.Filter{})
+			if err == nil {
+				rc.Close()
+			}
+			return err
+		},
+		"SearchPersons": func() error {
+			_, err := c.SearchPersons(ctx, "Ushakov", client.MatchFuzzy, client.Filter{}, 0, 0)
+			return err
+		},
+		"GetPerson":    func() error { _, err := c.GetPerson(ctx, 1); return err },
+		"UpdatePerson": func() error { _, err := c.UpdatePerson(ctx, 1, client.UpdatePersonRequest{Age: 30}); return err },
+		"DeletePerson": func() error { return c.DeletePerson(ctx, 1, 0) },
+		"UpdatePersons": func() error {
+			_, err := c.UpdatePersons(ctx, client.Filter{}, client.UpdatePersonRequest{Age: 30}, true, "")
+			return err
+		},
+		"DeletePersons":  func() error { _, err := c.DeletePersons(ctx, client.Filter{}, true, ""); return err },
+		"GetChanges":     func() error { _, err := c.GetChanges(ctx, "", 0); return err },
+		"GetStats":       func() error { _, err := c.GetStats(ctx, client.Filter{}, client.StatsOptions{}); return err },
+		"FindDuplicates": func() error { _, err := c.FindDuplicates(ctx, client.Filter{}, 0, 0); return err },
+		"MergePersons": func() error {
+			_, err := c.MergePersons(ctx, client.MergeRequest{TargetID: 1, SourceIDs: []uint{2}})
+			return err
+		},
+		"GetTrash":         func() error { _, err := c.GetTrash(ctx, client.Filter{}, 0, 0); return err },
+		"RestorePerson":    func() error { _, err := c.RestorePerson(ctx, 1); return err },
+		"GetPersonHistory": func() error { _, err := c.GetPersonHistory(ctx, 1); return err },
+		"RollbackPerson":   func() error { _, err := c.RollbackPerson(ctx, 1, 2); return err },
+		"StreamEvents": func() error {
+			err := c.StreamEvents(ctx, client.Filter{}, 0, func(*client.StreamMessage) error { return errStop })
+			if errors.Is(err, errStop) {
+				return nil
+			}
+			return err
+		},
+		"DialEvents": func() error {
+			conn, err := c.DialEvents(ctx, 0)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			if err := conn.Subscribe("all", nil, ""); err != nil {
+				return err
+			}
+			_, err = conn.Next()
+			return err
+		},
+		"SaveView":        func() error { _, err := c.SaveView(ctx, client.SaveViewRequest{Name: "adults"}); return err },
+		"GetViews":        func() error { _, err := c.GetViews(ctx); return err },
+		"GetView":         func() error { _, err := c.GetView(ctx, "adults", 0); return err },
+		"GetViewVersions": func() error { _, err := c.GetViewVersions(ctx, "adults"); return err },
+		"GetViewPersons":  func() error { _, err := c.GetViewPersons(ctx, "adults", 1, 0, 0); return err },
+		"CreateWebhook": func() error {
+			_, err := c.CreateWebhook(ctx, client.CreateWebhookRequest{URL: "https://example.com"})
+			return err
+		},
+		"GetWebhooks":    func() error { _, err := c.GetWebhooks(ctx); return err },
+		"DeleteWebhook":  func() error { return c.DeleteWebhook(ctx, 1) },
+		"GetDeliveries":  func() error { _, err := c.GetDeliveries(ctx, 1, "", 0, 0); return err },
+		"ReplayDelivery": func() error { _, err := c.ReplayDelivery(ctx, 1, 2); return err },
+		"GraphQL":        func() error { return c.GraphQL(ctx, "{ person(id: 1) { id } }", nil, nil) },
+	}
+	for name, call := range calls {
+		if err := call(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// Operations that are the same handler as one the client calls.
+	aliases := map[string]string{"PATCH /v1/person/{id}": "PUT /v1/person/{id}"}
+	// Operations that describe the API rather than use it.
+	skipped := map[string]bool{"GET /openapi.json": true}
+
+	var missing []string
+	for pattern, ok := range called {
+		if !ok && !called[aliases[pattern]] && !skipped[pattern] {
+			missing = append(missing, pattern)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Fatalf("operations of the spec without a client method: %v", missing)
+	}
+}
+
+// serveSpecResponse responds with the success status of an operation and
+// an empty body of its schema.
+func serveSpecResponse(w http.ResponseWriter, r *http.Request, status int, schema string) {
+	switch {
+	case status == http.StatusSwitchingProtocols:
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var cmd model.StreamCommand
+		conn.ReadJSON(&cmd)
+		conn.WriteJSON(model.StreamMessage{Type: model.StreamSubscribed, ID: cmd.ID})
+	case r.Header.Get("Accept") == "text/event-stream":
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 1\nevent: created\ndata: {\"id\": 1}\n\n")
+	case status == http.StatusNoContent:
+		w.WriteHeader(status)
+	case schema == "array":
+		w.WriteHeader(status)
+		fmt.Fprint(w, "[]")
+	default:
+		w.WriteHeader(status)
+		fmt.Fprint(w, "{}")
+	}
+}
+
+// TestTypesMatchTheServer checks that the client types encode like the
+// model types the server uses, with every field set.
+func TestTypesMatchTheServer(t *testing.T) {
+	pairs := []struct{ server, client any }{
+		{&model.Person{}, &client.Person{}},
+		{&model.DeletedPerson{}, &client.DeletedPerson{}},
+		{&model.PersonSearchResult{}, &client.PersonSearchResult{}},
+		{&model.CreatePersonRequest{}, &client.CreatePersonRequest{}},
+		{&model.UpdatePersonRequest{}, &client.UpdatePersonRequest{}},
+		{&model.BatchItemResult{}, &client.BatchItemResult{}},
+		{&model.BulkResult{}, &client.BulkResult{}},
+		{&model.ImportReport{}, &client.ImportReport{}},
+		{&model.PersonChanges{}, &client.PersonChanges{}},
+		{&model.PersonHistory{}, &client.PersonHistory{}},
+		{&model.RollbackRequest{}, &client.RollbackRequest{}},
+		{&model.PersonStats{}, &client.PersonStats{}},
+		{&model.DuplicateConflict{}, &client.DuplicateConflict{}},
+		{&model.DuplicateCluster{}, &client.DuplicateCluster{}},
+		{&model.MergeRequest{}, &client.MergeRequest{}},
+		{&model.MergeResult{}, &client.MergeResult{}},
+		{&model.StreamMessage{}, &client.StreamMessage{}},
+		{&model.StreamCommand{}, &client.StreamCommand{}},
+		{&model.SaveViewRequest{}, &client.SaveViewRequest{}},
+		{&model.View{}, &client.View{}},
+		{&model.CreateWebhookRequest{}, &client.CreateWebhookRequest{}},
+		{&model.Webhook{}, &client.Webhook{}},
+		{&model.WebhookDelivery{}, &client.WebhookDelivery{}},
+	}
+	for _, p := range pairs {
+		fill(reflect.ValueOf(p.server).Elem())
+		want, _ := json.Marshal(p.server)
+		if err := json.Unmarshal(want, p.client); err != nil {
+			t.Fatalf("%T: %v", p.client, err)
+		}
+		got, _ := json.Marshal(p.client)
+
+		var w, g any
+		json.Unmarshal(want, &w)
+		json.Unmarshal(got, &g)
+		if !reflect.DeepEqual(w, g) {
+			t.Errorf("%T encodes as\n%s\nbut the server sends\n%s", p.client, got, want)
+		}
+	}
+}
+
+// fill sets every exported field of v to a non-zero value.
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Slice:
+		if v.Type() == reflect.TypeOf(json.RawMessage{}) {
+			v.SetBytes([]byte(`{"id":1}`))
+			return
+		}
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fill(elem)
+		v.SetMapIndex(reflect.ValueOf("name"), elem)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf("value"))
+	case reflect.String:
+		v.SetString("value")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int64:
+		v.SetInt(7)
+	case reflect.Uint, reflect.Uint64:
+		v.SetUint(7)
+	case reflect.Float64:
+		v.SetFloat(1.5)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	keys := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.Method]++
+		n := attempts[r.Method]
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			keys[key] = true
+		}
+		mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodDelete && n > 1:
+			// The first attempt went through, its response was lost.
+			http.Error(w, "webhook not found", http.StatusNotFound)
+		case n < 3:
+			http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, `{"id": 7, "name": "Dmitriy"}`)
+		}
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL).WithMaxRetries(2)
+	ctx := context.Background()
+
+	if p, err := c.GetPerson(ctx, 7); err != nil || p.ID != 7 {
+		t.Fatalf("expected the third GET to succeed, got %v %v", p, err)
+	}
+	if _, err := c.CreatePerson(ctx, client.CreatePersonRequest{Name: "Dmitriy", Surname: "Ushakov"}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts[http.MethodPost] != 3 || len(keys) != 1 {
+		t.Fatalf("expected three POST attempts with one Idempotency-Key, got %d with %d keys", attempts[http.MethodPost], len(keys))
+	}
+
+	_, err := c.UpdatePersons(ctx, client.Filter{}, client.UpdatePersonRequest{Age: 30}, false, "token")
+	if !errors.Is(err, client.ErrUnavailable) || attempts[http.MethodPatch] != 1 {
+		t.Fatalf("expected a bulk update to be sent once, got %d attempts and %v", attempts[http.MethodPatch], err)
+	}
+
+	if err := c.DeleteWebhook(ctx, 3); err != nil || attempts[http.MethodDelete] != 2 {
+		t.Fatalf("expected a retried DELETE that finds nothing to succeed, got %d attempts and %v", attempts[http.MethodDelete], err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/person/batch":
+			results := make([]client.BatchItemResult, 2000)
+			for i := range results {
+				results[i] = client.BatchItemResult{Index: i, Status: "skipped", Error: "another item of the batch failed"}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(results)
+		case r.Method == http.MethodDelete:
+			http.Error(w, "webhook not found", http.StatusNotFound)
+		case r.Method == http.MethodPost:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error": "person may already exist", "candidates": [4, 9]}`)
+		case r.Method == http.MethodPut:
+			if r.Header.Get("If-Match") != `"3"` {
+				t.Errorf("unexpected If-Match %q", r.Header.Get("If-Match"))
+			}
+			http.Error(w, "version mismatch", http.StatusPreconditionFailed)
+		default:
+			http.Error(w, "failed to get persons: connection refused", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL).WithMaxRetries(0)
+	ctx := context.Background()
+
+	_, err := c.CreatePerson(ctx, client.CreatePersonRequest{Name: "Dmitriy", Surname: "Ushakov"})
+	var apiErr *client.Error
+	if !errors.Is(err, client.ErrConflict) || !errors.As(err, &apiErr) || len(apiErr.Candidates) != 2 {
+		t.Fatalf("expected a conflict with candidates, got %v", err)
+	}
+
+	_, err = c.UpdatePerson(ctx, 1, client.UpdatePersonRequest{Name: "Bob", Version: 3})
+	if !errors.Is(err, client.ErrVersionMismatch) || errors.Is(err, client.ErrConflict) {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+
+	_, err = c.ListPersons(ctx, client.Filter{}, 0, 0)
+	if !errors.Is(err, client.ErrServer) || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected a server error with its message, got %v", err)
+	}
+
+	if err := c.DeleteWebhook(ctx, 3); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected a first DELETE that finds nothing to fail, got %v", err)
+	}
+
+	results, err := c.CreatePersons(ctx, []client.CreatePersonRequest{{Name: "Dmitriy", Surname: "Ushakov"}}, true)
+	if !errors.Is(err, client.ErrUnprocessable) || len(results) != 2000 {
+		t.Fatalf("expected every item result of a large failed batch, got %d and %v", len(results), err)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		requests = append(requests, r.URL.Path+"?"+r.URL.RawQuery)
+		if r.URL.Path == "/v1/person/changes" {
+			since, _ := strconv.Atoi(q.Get("since"))
+			json.NewEncoder(w).Encode(model.PersonChanges{
+				Changed: []model.Person{{ID: uint(since + 1)}},
+				Token:   strconv.Itoa(since + 1),
+				HasMore: since < 2,
+			})
+			return
+		}
+
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		people := []model.Person{}
+		for id := offset + 1; id <= 5 && len(people) < limit; id++ {
+			people = append(people, model.Person{ID: uint(id)})
+		}
+		json.NewEncoder(w).Encode(people)
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL)
+	ctx := context.Background()
+
+	var ids []uint
+	it := c.Persons(client.Filter{Nationality: "RU"}, 2)
+	for it.Next(ctx) {
+		ids = append(ids, it.Value().ID)
+	}
+	if it.Err() != nil || len(ids) != 5 || ids[4] != 5 {
+		t.Fatalf("expected five people, got %v %v", ids, it.Err())
+	}
+	if len(requests) != 3 || requests[2] != "/v1/person?limit=2&nationality=RU&offset=4" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	var token string
+	changes := c.Changes("", 1)
+	for changes.Next(ctx) {
+		token = changes.Value().Token
+	}
+	if changes.Err() != nil || token != "3" {
+		t.Fatalf("expected to sync up to token 3, got %q %v", token, changes.Err())
+	}
+}
+
+func TestStreamEvents_Resumes(t *testing.T) {
+	var lastIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(lastIDs) == 1 {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\nid: 4\nevent: created\ndata: {\"id\": 4, \"person_id\": 1}\n\n: ping\n\nid: 5\n\n")
+			return
+		}
+		fmt.Fprint(w, "id: 6\nevent: updated\ndata: {\"id\": 6, \"person_id\": 2}\n\n")
+	}))
+	defer srv.Close()
+
+	var got []string
+	err := client.New(srv.URL).StreamEvents(context.Background(), client.Filter{}, 3, func(msg *client.StreamMessage) error {
+		if msg.Event == nil {
+			got = append(got, msg.Type)
+			return nil
+		}
+		got = append(got, strconv.FormatUint(msg.Event.ID, 10))
+		if msg.Event.ID == 6 {
+			return errStop
+		}
+		return nil
+	})
+
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+	if strings.Join(got, ",") != "reset,4,6" || strings.Join(lastIDs, ",") != "3,5" {
+		t.Fatalf("unexpected messages %v after resuming from %v", got, lastIDs)
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors matched by errors.Is against an *Error of the status code the
+// server responds with.
+var (
+	ErrBadRequest           = errors.New("bad request")            // 400: invalid parameters or body
+	ErrForbidden            = errors.New("forbidden")              // 403: admin token required
+	ErrNotFound             = errors.New("not found")              // 404
+	ErrConflict             = errors.New("conflict")               // 409: possible duplicate, stale confirm token, merge conflict
+	ErrVersionMismatch      = errors.New("version mismatch")       // 412: If-Match does not match the current version
+	ErrTooLarge             = errors.New("request too large")      // 413
+	ErrUnsupportedMedia     = errors.New("unsupported media type") // 415
+	ErrUnprocessable        = errors.New("unprocessable request")  // 422: atomic batch failed, Idempotency-Key reused
+	ErrConfirmationRequired = errors.New("confirmation required")  // 428: bulk change without confirm token
+	ErrUnavailable          = errors.New("service unavailable")    // 503
+	ErrServer               = errors.New("internal server error")  // any other 5xx
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:            ErrBadRequest,
+	http.StatusForbidden:             ErrForbidden,
+	http.StatusNotFound:              ErrNotFound,
+	http.StatusConflict:              ErrConflict,
+	http.StatusPreconditionFailed:    ErrVersionMismatch,
+	http.StatusRequestEntityTooLarge: ErrTooLarge,
+	http.StatusUnsupportedMediaType:  ErrUnsupportedMedia,
+	http.StatusUnprocessableEntity:   ErrUnprocessable,
+	http.StatusPreconditionRequired:  ErrConfirmationRequired,
+	http.StatusServiceUnavailable:    ErrUnavailable,
+}
+
+// Error is a response of the API with a status other than 2xx.
+type Error struct {
+	StatusCode int
+	// Message is the error text the server responded with.
+	Message string
+	// Candidates are the IDs of people that a created person may duplicate,
+	// reported with 409.
+	Candidates []uint
+
+	body []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("people api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is matches the sentinel error of the status code.
+func (e *Error) Is(target error) bool {
+	if err, ok := statusErrors[e.StatusCode]; ok {
+		return err == target
+	}
+	return e.StatusCode >= 500 && target == ErrServer
+}
+
+// readError reads the error of a response and closes its body. The server
+// reports errors as plain text, except for possible duplicates.
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+	isJSON := strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json")
+	var r io.Reader = resp.Body
+	if !isJSON {
+		r = io.LimitReader(r, maxErrorTextSize)
+	}
+	body, _ := io.ReadAll(r)
+
+	err := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body)), body: body}
+	if isJSON {
+		var conflict DuplicateConflict
+		if json.Unmarshal(body, &conflict) == nil && conflict.Error != "" {
+			err.Message, err.Candidates = conflict.Error, conflict.Candidates
+		}
+	}
+	return err
+}
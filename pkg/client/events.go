@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// StreamEvents follows the server-sent event feed of the people matching
+// the filter, from after lastEventID, and calls fn with every message: an
+// event, or a reset when the server no longer has the missed events and
+// the list has to be read again. Lost connections are resumed from the
+// last event seen. It returns when ctx is done, fn fails or the server
+// rejects the request.
+func (c *Client) StreamEvents(ctx context.Context, filter Filter, lastEventID uint64, fn func(msg *StreamMessage) error) error {
+	// The feed is open for as long as ctx is, and resuming it is up to the
+	// loop below.
+	hc := *c.http
+	hc.Timeout = 0
+	stream := *c
+	stream.http, stream.maxRetries = &hc, 0
+
+	failures := 0
+	for {
+		r := newRequest(http.MethodGet, "/person/events")
+		filter.apply(r.query)
+		r.header.Set("Accept", "text/event-stream")
+		if lastEventID > 0 {
+			r.header.Set("Last-Event-ID", strconv.FormatUint(lastEventID, 10))
+		}
+
+		resp, err := stream.send(ctx, r)
+		if err == nil {
+			failures = 0
+			err = readEvents(resp, &lastEventID, fn)
+			resp.Body.Close()
+		}
+		var fnErr *callbackError
+		if errors.As(err, &fnErr) {
+			return fnErr.err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && !retryable(err) {
+			return err
+		}
+
+		if err != nil {
+			failures++
+			if failures > c.maxRetries {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryBackoff(failures)):
+		}
+	}
+}
+
+// callbackError carries the error of the StreamEvents callback.
+type callbackError struct {
+	err error
+}
+
+func (e *callbackError) Error() string {
+	return e.err.Error()
+}
+
+// readEvents dispatches the events of an SSE stream until it ends, keeping
+// lastID at the ID of the last event read.
+func readEvents(resp *http.Response, lastID *uint64, fn func(msg *StreamMessage) error) error {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	var id, event string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "id":
+				id = value
+			case "event":
+				event = value
+			case "data":
+				data.WriteString(value)
+			}
+			continue
+		}
+
+		msg, err := eventMessage(event, data.String())
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+				*lastID = n
+			}
+		}
+		if msg != nil {
+			if err := fn(msg); err != nil {
+				return &callbackError{err: err}
+			}
+		}
+		id, event = "", ""
+		data.Reset()
+	}
+	return scanner.Err()
+}
+
+// eventMessage returns the message of an SSE event, or nil for an event
+// that only advances the ID.
+func eventMessage(event, data string) (*StreamMessage, error) {
+	switch {
+	case event == StreamReset:
+		return &StreamMessage{Type: StreamReset}, nil
+	case data == "":
+		return nil, nil
+	}
+	var ev PersonEvent
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		return nil, err
+	}
+	return &StreamMessage{Type: StreamEvent, Event: &ev}, nil
+}
+
+// EventConn is a WebSocket connection to the event feed. Subscriptions are
+// made with Subscribe and messages read with Next; one goroutine may write
+// while another reads.
+type EventConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+// DialEvents opens a WebSocket to the event feed that resumes after
+// lastEventID. Events arrive once subscribed to.
+func (c *Client) DialEvents(ctx context.Context, lastEventID uint64) (*EventConn, error) {
+	u := c.baseURL + apiPrefix + "/person/ws"
+	if lastEventID > 0 {
+		u += "?last_event_id=" + strconv.FormatUint(lastEventID, 10)
+	}
+	u = "ws" + strings.TrimPrefix(u, "http")
+
+	header := http.Header{}
+	if c.actor != "" {
+		header.Set("X-Actor", c.actor)
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u, header)
+	if err != nil {
+		if resp != nil {
+			return nil, readError(resp)
+		}
+		return nil, err
+	}
+	return &EventConn{conn: conn}, nil
+}
+
+// Subscribe starts a subscription, named by id, to the events of the people
+// with the IDs, of people matching the filter expression, or both. The
+// server confirms it with a subscribed message.
+func (e *EventConn) Subscribe(id string, ids []uint, filter string) error {
+	return e.send(StreamCommand{Type: streamSubscribe, ID: id, IDs: ids, Filter: filter})
+}
+
+func (e *EventConn) Unsubscribe(id string) error {
+	return e.send(StreamCommand{Type: streamUnsubscribe, ID: id})
+}
+
+func (e *EventConn) send(cmd StreamCommand) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.conn.WriteJSON(cmd)
+}
+
+// Next reads the next message: an event with the subscriptions it matched,
+// a confirmation or an error of a command.
+func (e *EventConn) Next() (*StreamMessage, error) {
+	var msg StreamMessage
+	if err := e.conn.ReadJSON(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (e *EventConn) Close() error {
+	return e.conn.Close()
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// GraphQLError is an error of a GraphQL field. Code is one of the codes of
+// the gql package, e.g. NOT_FOUND or VERSION_CONFLICT.
+type GraphQLError struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// GraphQLErrors are the errors of a GraphQL response.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Message
+	}
+	return "graphql: " + strings.Join(msgs, "; ")
+}
+
+// GraphQL runs a query or mutation and decodes its data into out. Field
+// errors are returned as GraphQLErrors along with the data that resolved; a
+// query rejected as a whole, e.g. for exceeding the limits, fails with
+// ErrBadRequest. Queries are not retried, as they may be mutations.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, out any) error {
+	r := newRequest(http.MethodPost, "/graphql").json(map[string]any{"query": query, "variables": variables})
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}
+	if err := c.do(ctx, r, &resp); err != nil {
+		return err
+	}
+	if out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return err
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package client
+
+import "context"
+
+const defaultIteratorPageSize = 100
+
+// Iterator walks a paginated list, fetching a page at a time:
+//
+//	it := c.Persons(client.Filter{Nationality: "RU"}, 0)
+//	for it.Next(ctx) {
+//		p := it.Value()
+//		...
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator[T any] struct {
+	fetch func(ctx context.Context) ([]T, bool, error)
+	items []T
+	cur   T
+	more  bool
+	err   error
+}
+
+// newIterator returns an iterator over the pages fetch returns; fetch
+// reports whether another page follows.
+func newIterator[T any](fetch func(ctx context.Context) ([]T, bool, error)) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch, more: true}
+}
+
+// Paginate returns an iterator over a list endpoint that takes a limit and
+// an offset, such as GetTrash or GetDeliveries. A page shorter than
+// pageSize ends the list; zero pageSize means 100.
+func Paginate[T any](pageSize int, list func(ctx context.Context, limit, offset int) ([]T, error)) *Iterator[T] {
+	if pageSize <= 0 {
+		pageSize = defaultIteratorPageSize
+	}
+	offset := 0
+	return newIterator(func(ctx context.Context) ([]T, bool, error) {
+		items, err := list(ctx, pageSize, offset)
+		offset += len(items)
+		return items, len(items) == pageSize, err
+	})
+}
+
+// Next advances to the next item, fetching the next page when the current
+// one is used up. It returns false at the end of the list or on an error.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for len(it.items) == 0 {
+		if !it.more || it.err != nil {
+			return false
+		}
+		it.items, it.more, it.err = it.fetch(ctx)
+	}
+	it.cur, it.items = it.items[0], it.items[1:]
+	return true
+}
+
+// Value returns the current item.
+func (it *Iterator[T]) Value() T {
+	return it.cur
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
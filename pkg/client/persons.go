@@ -0,0 +1,274 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CreatePerson creates and enriches a person. A possible duplicate fails
+// with ErrConflict and the candidates in the *Error, unless req.Force is
+// set.
+func (c *Client) CreatePerson(ctx context.Context, req CreatePersonRequest) (*Person, error) {
+	r := newRequest(http.MethodPost, "/person").json(req).idempotent()
+	if req.Force {
+		r.query.Set("force", "true")
+	}
+	var p Person
+	if err := c.do(ctx, r, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreatePersons creates people in one batch. An atomic batch creates all of
+// them or none; a failed one returns the results of the items along with
+// ErrUnprocessable. Otherwise every item gets its own result.
+func (c *Client) CreatePersons(ctx context.Context, reqs []CreatePersonRequest, atomic bool) ([]BatchItemResult, error) {
+	r := newRequest(http.MethodPost, "/person/batch").json(reqs).idempotent()
+	r.query.Set("mode", "partial")
+	if atomic {
+		r.query.Set("mode", "atomic")
+	}
+
+	var results []BatchItemResult
+	err := c.do(ctx, r, &results)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnprocessableEntity {
+		if json.Unmarshal(apiErr.body, &results) == nil {
+			apiErr.Message = "atomic batch failed"
+		}
+	}
+	return results, err
+}
+
+// ImportPersons creates people from a CSV stream in the format of
+// POST /person/import. The stream is sent once, without retries.
+func (c *Client) ImportPersons(ctx context.Context, csv io.Reader) (*ImportReport, error) {
+	r := newRequest(http.MethodPost, "/person/import")
+	r.stream = csv
+	r.header.Set("Content-Type", "text/csv")
+	var report ImportReport
+	if err := c.do(ctx, r, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListPersons returns a page of the people matching the filter.
+func (c *Client) ListPersons(ctx context.Context, filter Filter, limit, offset int) ([]Person, error) {
+	r := newRequest(http.MethodGet, "/person")
+	filter.apply(r.query)
+	page(r.query, limit, offset)
+	var people []Person
+	return people, c.do(ctx, r, &people)
+}
+
+// Persons iterates over all people matching the filter, pageSize at a time.
+func (c *Client) Persons(filter Filter, pageSize int) *Iterator[Person] {
+	return Paginate(pageSize, func(ctx context.Context, limit, offset int) ([]Person, error) {
+		return c.ListPersons(ctx, filter, limit, offset)
+	})
+}
+
+// ExportPersons streams the people matching the filter in one of FormatCSV,
+// FormatNDJSON and FormatXLSX. The caller closes the returned reader.
+func (c *Client) ExportPersons(ctx context.Context, format string, filter Filter) (io.ReadCloser, error) {
+	r := newRequest(http.MethodGet, "/person/export")
+	r.query.Set("format", format)
+	r.header.Set("Accept", "*/*")
+	filter.apply(r.query)
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// SearchPersons finds people by similarity of their name to the query, in
+// MatchFuzzy or MatchPhonetic mode. The name parts of the filter are not
+// used.
+func (c *Client) SearchPersons(ctx context.Context, query, match string, filter Filter, limit, offset int) ([]PersonSearchResult, error) {
+	r := newRequest(http.MethodGet, "/person/search")
+	filter.apply(r.query)
+	r.query.Set("q", query)
+	if match != "" {
+		r.query.Set("match", match)
+	}
+	page(r.query, limit, offset)
+	var results []PersonSearchResult
+	return results, c.do(ctx, r, &results)
+}
+
+func (c *Client) GetPerson(ctx context.Context, id uint) (*Person, error) {
+	var p Person
+	if err := c.do(ctx, newRequest(http.MethodGet, fmt.Sprintf("/person/%d", id)), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdatePerson changes the non-empty fields of req. With req.Version set it
+// fails with ErrVersionMismatch if the person has another version.
+func (c *Client) UpdatePerson(ctx context.Context, id uint, req UpdatePersonRequest) (*Person, error) {
+	r := newRequest(http.MethodPut, fmt.Sprintf("/person/%d", id)).json(req).ifMatch(req.Version)
+	var p Person
+	if err := c.do(ctx, r, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DeletePerson moves the person to the trash. A non-zero version must
+// match the current one.
+func (c *Client) DeletePerson(ctx context.Context, id uint, version uint) error {
+	return c.do(ctx, newRequest(http.MethodDelete, fmt.Sprintf("/person/%d", id)).ifMatch(version), nil)
+}
+
+// PurgePerson deletes the person for good. It needs the admin token.
+func (c *Client) PurgePerson(ctx context.Context, id uint) error {
+	r := newRequest(http.MethodDelete, fmt.Sprintf("/person/%d", id))
+	r.query.Set("hard", "true")
+	r.retry = true
+	return c.do(ctx, r, nil)
+}
+
+// UpdatePersons changes every person matching the filter. Call it with
+// dryRun first to learn the count and the confirm token to pass next.
+func (c *Client) UpdatePersons(ctx context.Context, filter Filter, req UpdatePersonRequest, dryRun bool, confirm string) (*BulkResult, error) {
+	r := newRequest(http.MethodPatch, "/person").json(req)
+	bulk(r, filter, dryRun, confirm)
+	var result BulkResult
+	if err := c.do(ctx, r, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeletePersons moves every person matching the filter to the trash, with
+// the dry run and confirmation of UpdatePersons.
+func (c *Client) DeletePersons(ctx context.Context, filter Filter, dryRun bool, confirm string) (*BulkResult, error) {
+	r := newRequest(http.MethodDelete, "/person")
+	bulk(r, filter, dryRun, confirm)
+	var result BulkResult
+	if err := c.do(ctx, r, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func bulk(r *request, filter Filter, dryRun bool, confirm string) {
+	filter.apply(r.query)
+	if dryRun {
+		r.query.Set("dry_run", "true")
+		// A dry run changes nothing.
+		r.retry = true
+	}
+	if confirm != "" {
+		r.query.Set("confirm", confirm)
+	}
+}
+
+// GetChanges returns the people changed and deleted after the since token,
+// in the order of the changes. An empty token starts a full sync.
+func (c *Client) GetChanges(ctx context.Context, since string, limit int) (*PersonChanges, error) {
+	r := newRequest(http.MethodGet, "/person/changes")
+	if since != "" {
+		r.query.Set("since", since)
+	}
+	setInt(r.query, "limit", limit)
+	var changes PersonChanges
+	if err := c.do(ctx, r, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// Changes iterates over the pages of changes after the since token until
+// the feed is caught up. The Token of the last page is the since of the
+// next sync.
+func (c *Client) Changes(since string, limit int) *Iterator[PersonChanges] {
+	return newIterator(func(ctx context.Context) ([]PersonChanges, bool, error) {
+		changes, err := c.GetChanges(ctx, since, limit)
+		if err != nil {
+			return nil, false, err
+		}
+		since = changes.Token
+		return []PersonChanges{*changes}, changes.HasMore, nil
+	})
+}
+
+// GetStats computes aggregates over the people matching the filter. Zero
+// options are left to the server defaults.
+func (c *Client) GetStats(ctx context.Context, filter Filter, opts StatsOptions) (*PersonStats, error) {
+	r := newRequest(http.MethodGet, "/person/stats")
+	filter.apply(r.query)
+	if len(opts.AgeBuckets) > 0 {
+		bounds := make([]string, len(opts.AgeBuckets))
+		for i, b := range opts.AgeBuckets {
+			bounds[i] = strconv.Itoa(b)
+		}
+		r.query.Set("age_buckets", strings.Join(bounds, ","))
+	}
+	setInt(r.query, "top", opts.TopNationalities)
+	var stats PersonStats
+	if err := c.do(ctx, r, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// FindDuplicates returns clusters of people who are likely the same person.
+func (c *Client) FindDuplicates(ctx context.Context, filter Filter, limit, offset int) ([]DuplicateCluster, error) {
+	r := newRequest(http.MethodGet, "/person/duplicates")
+	filter.apply(r.query)
+	page(r.query, limit, offset)
+	var clusters []DuplicateCluster
+	return clusters, c.do(ctx, r, &clusters)
+}
+
+// MergePersons merges the source people into the target one.
+func (c *Client) MergePersons(ctx context.Context, req MergeRequest) (*MergeResult, error) {
+	var result MergeResult
+	if err := c.do(ctx, newRequest(http.MethodPost, "/person/merge").json(req), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTrash returns a page of the deleted people matching the filter.
+func (c *Client) GetTrash(ctx context.Context, filter Filter, limit, offset int) ([]DeletedPerson, error) {
+	r := newRequest(http.MethodGet, "/person/trash")
+	filter.apply(r.query)
+	page(r.query, limit, offset)
+	var people []DeletedPerson
+	return people, c.do(ctx, r, &people)
+}
+
+func (c *Client) RestorePerson(ctx context.Context, id uint) (*Person, error) {
+	var p Person
+	if err := c.do(ctx, newRequest(http.MethodPost, fmt.Sprintf("/person/%d/restore", id)), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *Client) GetPersonHistory(ctx context.Context, id uint) ([]PersonHistory, error) {
+	var history []PersonHistory
+	return history, c.do(ctx, newRequest(http.MethodGet, fmt.Sprintf("/person/%d/history", id)), &history)
+}
+
+// RollbackPerson restores the person to its state at the revision.
+func (c *Client) RollbackPerson(ctx context.Context, id uint, revision uint) (*Person, error) {
+	r := newRequest(http.MethodPost, fmt.Sprintf("/person/%d/rollback", id)).json(RollbackRequest{Revision: revision})
+	var p Person
+	if err := c.do(ctx, r, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,325 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The request and response types of the API, as they are encoded on the
+// wire. The client does not depend on the server's packages.
+
+type Person struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Surname     string    `json:"surname"`
+	Patronymic  string    `json:"patronymic,omitempty"`
+	Gender      string    `json:"gender,omitempty"`
+	Age         int       `json:"age,omitempty"`
+	Nationality string    `json:"nationality,omitempty"`
+	Version     uint      `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// DeletedPerson is a person in the trash with the time it was deleted.
+type DeletedPerson struct {
+	Person
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// PersonSearchResult is a person found by SearchPersons with its
+// similarity to the query.
+type PersonSearchResult struct {
+	Person
+	Score float64 `json:"score"`
+}
+
+// CreatePersonRequest creates a person. Force creates it even if it may
+// duplicate someone.
+type CreatePersonRequest struct {
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic"`
+
+	Force bool `json:"-"`
+}
+
+// UpdatePersonRequest changes the non-empty fields of a person. A non-zero
+// Version must match the current one.
+type UpdatePersonRequest struct {
+	Name        string `json:"name,omitempty"`
+	Surname     string `json:"surname,omitempty"`
+	Patronymic  string `json:"patronymic,omitempty"`
+	Gender      string `json:"gender,omitempty"`
+	Age         int    `json:"age,omitempty"`
+	Nationality string `json:"nationality,omitempty"`
+
+	Version uint `json:"-"`
+}
+
+// BatchItemResult is the outcome of one item of CreatePersons.
+type BatchItemResult struct {
+	Index  int     `json:"index"`
+	Status string  `json:"status"`
+	Person *Person `json:"person,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// BulkResult is the outcome of UpdatePersons and DeletePersons.
+type BulkResult struct {
+	Matched      int64  `json:"matched"`
+	Affected     int64  `json:"affected"`
+	DryRun       bool   `json:"dry_run"`
+	ConfirmToken string `json:"confirm_token,omitempty"`
+}
+
+// ImportReport is the outcome of ImportPersons.
+type ImportReport struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Rows     []ImportRowResult `json:"rows"`
+}
+
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+	ID     uint   `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// PersonChanges is a page of GetChanges. Token is passed as since to get
+// the next page.
+type PersonChanges struct {
+	Changed []Person          `json:"changed"`
+	Deleted []PersonTombstone `json:"deleted"`
+	Token   string            `json:"token"`
+	HasMore bool              `json:"has_more"`
+}
+
+// PersonTombstone is a deleted person in GetChanges.
+type PersonTombstone struct {
+	PersonID  uint      `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// PersonHistory is one entry of the change log of a person.
+type PersonHistory struct {
+	ID        uint                   `json:"id"`
+	PersonID  uint                   `json:"person_id"`
+	Revision  uint                   `json:"revision"`
+	Action    string                 `json:"action"`
+	Source    string                 `json:"source"`
+	Actor     string                 `json:"actor"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Snapshot  PersonSnapshot         `json:"snapshot"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// PersonSnapshot is the data of a person at a revision.
+type PersonSnapshot struct {
+	Name        string `json:"name"`
+	Surname     string `json:"surname"`
+	Patronymic  string `json:"patronymic,omitempty"`
+	Gender      string `json:"gender,omitempty"`
+	Age         int    `json:"age,omitempty"`
+	Nationality string `json:"nationality,omitempty"`
+}
+
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type RollbackRequest struct {
+	Revision uint `json:"revision"`
+}
+
+// StatsOptions are the options of GetStats.
+type StatsOptions struct {
+	AgeBuckets       []int
+	TopNationalities int
+}
+
+type PersonStats struct {
+	Total            int64              `json:"total"`
+	Genders          []GenderCount      `json:"genders"`
+	AgeHistogram     []AgeBucket        `json:"age_histogram"`
+	TopNationalities []NationalityCount `json:"top_nationalities"`
+	AverageAge       []AverageAge       `json:"average_age"`
+}
+
+type GenderCount struct {
+	Gender string `json:"gender"`
+	Count  int64  `json:"count"`
+}
+
+// AgeBucket counts the people aged from Min up to Max, exclusive; the last
+// bucket has no Max.
+type AgeBucket struct {
+	Min   int   `json:"min"`
+	Max   *int  `json:"max,omitempty"`
+	Count int64 `json:"count"`
+}
+
+type NationalityCount struct {
+	Nationality string `json:"nationality"`
+	Count       int64  `json:"count"`
+}
+
+type AverageAge struct {
+	Nationality string  `json:"nationality"`
+	Gender      string  `json:"gender"`
+	Average     float64 `json:"average"`
+	Count       int64   `json:"count"`
+}
+
+// DuplicateConflict is the body of a 409 of CreatePerson.
+type DuplicateConflict struct {
+	Error      string `json:"error"`
+	Candidates []uint `json:"candidates"`
+}
+
+// DuplicateCluster is a group of people who are likely the same person.
+type DuplicateCluster struct {
+	ID      uint     `json:"id"`
+	Persons []Person `json:"persons"`
+}
+
+// MergeRequest merges the sources into the target. Fields picks the person
+// a field is taken from.
+type MergeRequest struct {
+	TargetID  uint            `json:"target_id"`
+	SourceIDs []uint          `json:"source_ids"`
+	Fields    map[string]uint `json:"fields,omitempty"`
+}
+
+type MergeResult struct {
+	Person    Person   `json:"person"`
+	MergedIDs []uint   `json:"merged_ids"`
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// PersonEvent is a change of a person, as the live feed and webhooks
+// deliver it.
+type PersonEvent struct {
+	ID       uint64                 `json:"id"`
+	Type     string                 `json:"type"`
+	PersonID uint                   `json:"person_id"`
+	Revision uint                   `json:"revision"`
+	Person   PersonSnapshot         `json:"person"`
+	Changes  map[string]FieldChange `json:"changes,omitempty"`
+	Actor    string                 `json:"actor"`
+	Source   string                 `json:"source"`
+	Time     time.Time              `json:"time"`
+}
+
+// StreamMessage is a message of the WebSocket feed or an event of the SSE
+// feed.
+type StreamMessage struct {
+	Type          string       `json:"type"`
+	ID            string       `json:"id,omitempty"`
+	Subscriptions []string     `json:"subscriptions,omitempty"`
+	Event         *PersonEvent `json:"event,omitempty"`
+	Error         string       `json:"error,omitempty"`
+}
+
+// StreamCommand is a command sent over the WebSocket feed.
+type StreamCommand struct {
+	Type   string `json:"type"`
+	ID     string `json:"id,omitempty"`
+	IDs    []uint `json:"ids,omitempty"`
+	Filter string `json:"filter,omitempty"`
+}
+
+type SaveViewRequest struct {
+	Name    string   `json:"name"`
+	Filter  string   `json:"filter,omitempty"`
+	Sort    string   `json:"sort,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+}
+
+// View is a version of a saved view.
+type View struct {
+	Name      string    `json:"name"`
+	Version   uint      `json:"version"`
+	Filter    string    `json:"filter,omitempty"`
+	Sort      string    `json:"sort,omitempty"`
+	Columns   []string  `json:"columns,omitempty"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhookRequest registers a URL for person events. Without events
+// the webhook receives all of them; without a secret one is generated.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// Webhook is a registered receiver of person events. The secret is only
+// returned by CreateWebhook.
+type Webhook struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook.
+type WebhookDelivery struct {
+	ID            uint            `json:"id"`
+	WebhookID     uint            `json:"webhook_id"`
+	EventID       uint64          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	ReplayOf      *uint           `json:"replay_of,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Export formats of ExportPersons.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// Match modes of SearchPersons.
+const (
+	MatchFuzzy    = "fuzzy"
+	MatchPhonetic = "phonetic"
+)
+
+// Statuses of a webhook delivery, for GetDeliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Types of a PersonEvent.
+const (
+	EventCreated  = "created"
+	EventUpdated  = "updated"
+	EventDeleted  = "deleted"
+	EventEnriched = "enriched"
+)
+
+// Types of stream commands and messages.
+const (
+	streamSubscribe   = "subscribe"
+	streamUnsubscribe = "unsubscribe"
+
+	StreamSubscribed   = "subscribed"
+	StreamUnsubscribed = "unsubscribed"
+	StreamEvent        = "event"
+	StreamReset        = "reset"
+	StreamError        = "error"
+)
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SaveView saves a filter, sort order and column set under a name, as a new
+// version if the name is taken.
+func (c *Client) SaveView(ctx context.Context, req SaveViewRequest) (*View, error) {
+	var v View
+	if err := c.do(ctx, newRequest(http.MethodPost, "/views").json(req), &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetViews returns the latest version of every view.
+func (c *Client) GetViews(ctx context.Context) ([]View, error) {
+	var views []View
+	return views, c.do(ctx, newRequest(http.MethodGet, "/views"), &views)
+}
+
+// GetView returns a version of the view; zero version means the latest.
+func (c *Client) GetView(ctx context.Context, name string, version uint) (*View, error) {
+	r := newRequest(http.MethodGet, "/views/"+url.PathEscape(name))
+	viewVersion(r, version)
+	var v View
+	if err := c.do(ctx, r, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (c *Client) GetViewVersions(ctx context.Context, name string) ([]View, error) {
+	var views []View
+	return views, c.do(ctx, newRequest(http.MethodGet, "/views/"+url.PathEscape(name)+"/versions"), &views)
+}
+
+// GetViewPersons returns a page of the people a version of the view
+// selects, in its order. Attributes outside the columns of the view are
+// left zero.
+func (c *Client) GetViewPersons(ctx context.Context, name string, version uint, limit, offset int) ([]Person, error) {
+	r := newRequest(http.MethodGet, "/views/"+url.PathEscape(name)+"/persons")
+	viewVersion(r, version)
+	page(r.query, limit, offset)
+	var people []Person
+	return people, c.do(ctx, r, &people)
+}
+
+func viewVersion(r *request, version uint) {
+	if version != 0 {
+		r.query.Set("version", strconv.FormatUint(uint64(version), 10))
+	}
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CreateWebhook registers a webhook. The secret deliveries are signed with
+// is only returned here.
+func (c *Client) CreateWebhook(ctx context.Context, req CreateWebhookRequest) (*Webhook, error) {
+	var hook Webhook
+	if err := c.do(ctx, newRequest(http.MethodPost, "/webhooks").json(req), &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// GetWebhooks returns the registered webhooks without their secrets.
+func (c *Client) GetWebhooks(ctx context.Context) ([]Webhook, error) {
+	var hooks []Webhook
+	return hooks, c.do(ctx, newRequest(http.MethodGet, "/webhooks"), &hooks)
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, id uint) error {
+	r := newRequest(http.MethodDelete, fmt.Sprintf("/webhooks/%d", id))
+	r.retry = true
+	return c.do(ctx, r, nil)
+}
+
+// GetDeliveries returns a page of the delivery log of a webhook, newest
+// first. An empty status means all of them.
+func (c *Client) GetDeliveries(ctx context.Context, webhookID uint, status string, limit, offset int) ([]WebhookDelivery, error) {
+	r := newRequest(http.MethodGet, fmt.Sprintf("/webhooks/%d/deliveries", webhookID))
+	if status != "" {
+		r.query.Set("status", status)
+	}
+	page(r.query, limit, offset)
+	var deliveries []WebhookDelivery
+	return deliveries, c.do(ctx, r, &deliveries)
+}
+
+// ReplayDelivery sends a logged delivery again and returns the new
+// delivery.
+func (c *Client) ReplayDelivery(ctx context.Context, webhookID, deliveryID uint) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	if err := c.do(ctx, newRequest(http.MethodPost, fmt.Sprintf("/webhooks/%d/deliveries/%d/replay", webhookID, deliveryID)), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,109 @@
+// Package filterexpr parses a small boolean expression language for
+// filtering records, such as
+//
+//	age >= 30 and (nationality in ("RU", "KZ") or gender = "female")
+//
+// Expressions compare allowlisted fields with string or integer literals and
+// combine the comparisons with and, or, not and parentheses. Parse checks
+// field names and value types, so a parsed expression can be compiled to a
+// parameterized query without further validation.
+package filterexpr
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Type is the value type of a field.
+type Type int
+
+const (
+	String Type = iota
+	Int
+)
+
+func (t Type) String() string {
+	if t == Int {
+		return "integer"
+	}
+	return "string"
+}
+
+// Comparison operators.
+const (
+	OpEq    = "="
+	OpNe    = "!="
+	OpLt    = "<"
+	OpLe    = "<="
+	OpGt    = ">"
+	OpGe    = ">="
+	OpIn    = "in"
+	OpNotIn = "not in"
+)
+
+// Node is a node of a parsed expression. Its String form is the canonical
+// text of the expression and parses back to an equal tree.
+type Node interface {
+	// Pos is the 1-based character position of the node in the source.
+	Pos() int
+	String() string
+}
+
+// And is true when both operands are.
+type And struct {
+	Left, Right Node
+	At          int
+}
+
+// Or is true when either operand is.
+type Or struct {
+	Left, Right Node
+	At          int
+}
+
+// Not negates its operand.
+type Not struct {
+	X  Node
+	At int
+}
+
+// Compare compares a field with one value, or with a list of values for the
+// in operators. Values hold strings for String fields and int64s for Int
+// fields.
+type Compare struct {
+	Field  string
+	Type   Type
+	Op     string
+	Values []any
+	At     int
+}
+
+func (n *And) Pos() int     { return n.At }
+func (n *Or) Pos() int      { return n.At }
+func (n *Not) Pos() int     { return n.At }
+func (n *Compare) Pos() int { return n.At }
+
+func (n *And) String() string { return "(" + n.Left.String() + " and " + n.Right.String() + ")" }
+func (n *Or) String() string  { return "(" + n.Left.String() + " or " + n.Right.String() + ")" }
+func (n *Not) String() string { return "not " + n.X.String() }
+
+func (n *Compare) String() string {
+	values := make([]string, len(n.Values))
+	for i, v := range n.Values {
+		switch v := v.(type) {
+		case string:
+			values[i] = quote(v)
+		case int64:
+			values[i] = strconv.FormatInt(v, 10)
+		}
+	}
+	if n.Op == OpIn || n.Op == OpNotIn {
+		return n.Field + " " + n.Op + " (" + strings.Join(values, ", ") + ")"
+	}
+	return n.Field + " " + n.Op + " " + values[0]
+}
+
+// quote is the inverse of lexString: it escapes only quotes and backslashes.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,76 @@
+package filterexpr
+
+import "strings"
+
+// Eval reports whether the expression holds for a record whose field values
+// are returned by value: strings for String fields and int64s for Int
+// fields. Strings are compared case-insensitively, as compiled queries do.
+func Eval(n Node, value func(field string) any) bool {
+	switch n := n.(type) {
+	case *And:
+		return Eval(n.Left, value) && Eval(n.Right, value)
+	case *Or:
+		return Eval(n.Left, value) || Eval(n.Right, value)
+	case *Not:
+		return !Eval(n.X, value)
+	case *Compare:
+		return evalCompare(n, value(n.Field))
+	}
+	return false
+}
+
+func evalCompare(n *Compare, v any) bool {
+	switch n.Op {
+	case OpIn, OpNotIn:
+		found := false
+		for _, want := range n.Values {
+			if c, ok := compareValues(v, want); ok && c == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (n.Op == OpIn)
+	}
+
+	c, ok := compareValues(v, n.Values[0])
+	if !ok {
+		return n.Op == OpNe
+	}
+	switch n.Op {
+	case OpEq:
+		return c == 0
+	case OpNe:
+		return c != 0
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	case OpGt:
+		return c > 0
+	case OpGe:
+		return c >= 0
+	}
+	return false
+}
+
+// compareValues orders a against b. It reports false for values of
+// different types, which are neither equal nor ordered.
+func compareValues(a, b any) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(strings.ToLower(a), strings.ToLower(b)), true
+		}
+	case int64:
+		if b, ok := b.(int64); ok {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
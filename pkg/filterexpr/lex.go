@@ -0,0 +1,128 @@
+package filterexpr
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokInt
+	tokOp
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) is(punct string) bool {
+	return t.kind == tokPunct && t.text == punct
+}
+
+func (t token) isKeyword(kw string) bool {
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return "string " + strconv.Quote(t.text)
+	case tokInt:
+		return "integer " + t.text
+	}
+	return strconv.Quote(t.text)
+}
+
+// lex splits src into tokens. Positions count characters, not bytes, so
+// they point at the right place in non-ASCII input.
+func lex(src string) ([]token, error) {
+	runes := []rune(src)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, token{kind: tokPunct, text: string(r), pos: pos})
+			i++
+
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				op += string(runes[i+1])
+			}
+			if op == "!" {
+				return nil, &SyntaxError{Pos: pos, Msg: "unexpected \"!\", did you mean !="}
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			i += len(op)
+
+		case r == '"' || r == '\'':
+			text, n, err := lexString(runes[i:], pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: pos})
+			i += n
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokInt, text: string(runes[i:j]), pos: pos})
+			i = j
+
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[i:j]), pos: pos})
+			i = j
+
+		default:
+			return nil, &SyntaxError{Pos: pos, Msg: "unexpected character " + strconv.QuoteRune(r)}
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+// lexString reads a literal quoted with its first rune, in which a backslash
+// escapes the next character. It returns the unquoted text and the number of
+// runes consumed.
+func lexString(runes []rune, pos int) (string, int, error) {
+	quote := runes[0]
+	var b strings.Builder
+	for i := 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+			if i == len(runes) {
+				return "", 0, &SyntaxError{Pos: pos, Msg: "unterminated string"}
+			}
+			b.WriteRune(runes[i])
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return "", 0, &SyntaxError{Pos: pos, Msg: "unterminated string"}
+}
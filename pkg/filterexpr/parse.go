@@ -0,0 +1,225 @@
+package filterexpr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxDepth limits the nesting of parentheses and not.
+	MaxDepth = 32
+	// MaxValues limits the length of an in list.
+	MaxValues = 100
+)
+
+// SyntaxError describes an invalid expression. Pos is the 1-based character
+// position of the offending token.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+// Parse parses src into an expression tree. Only the keys of fields may be
+// used as field names, and each is compared with values of its type.
+func Parse(src string, fields map[string]Type) (Node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return n, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+	fields map[string]Type
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) or() (Node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("or") {
+		op := p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right, At: op.pos}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("and") {
+		op := p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right, At: op.pos}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Node, error) {
+	t := p.peek()
+	if !t.isKeyword("not") && !t.is("(") {
+		return p.compare()
+	}
+
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > MaxDepth {
+		return nil, p.errorf(t, "expression is nested deeper than %d levels", MaxDepth)
+	}
+
+	p.next()
+	if t.isKeyword("not") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x, At: t.pos}, nil
+	}
+
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if c := p.next(); !c.is(")") {
+		return nil, p.errorf(c, "expected ) to close ( at position %d, found %s", t.pos, c)
+	}
+	return n, nil
+}
+
+func (p *parser) compare() (Node, error) {
+	f := p.next()
+	if f.kind != tokIdent || isKeyword(f.text) {
+		return nil, p.errorf(f, "expected field name, found %s", f)
+	}
+	name := strings.ToLower(f.text)
+	typ, ok := p.fields[name]
+	if !ok {
+		return nil, p.errorf(f, "unknown field %q, expected one of %s", f.text, fieldList(p.fields))
+	}
+	n := &Compare{Field: name, Type: typ, At: f.pos}
+
+	op := p.next()
+	switch {
+	case op.kind == tokOp:
+		n.Op = op.text
+		if n.Op == "<>" {
+			n.Op = OpNe
+		}
+		if typ == String && n.Op != OpEq && n.Op != OpNe {
+			return nil, p.errorf(op, "operator %s is not supported for string field %s", op.text, name)
+		}
+		v, err := p.value(n)
+		if err != nil {
+			return nil, err
+		}
+		n.Values = []any{v}
+		return n, nil
+	case op.isKeyword("in"):
+		n.Op = OpIn
+	case op.isKeyword("not") && p.peek().isKeyword("in"):
+		p.next()
+		n.Op = OpNotIn
+	default:
+		return nil, p.errorf(op, "expected comparison operator after %s, found %s", name, op)
+	}
+
+	if t := p.next(); !t.is("(") {
+		return nil, p.errorf(t, "expected ( after %s, found %s", n.Op, t)
+	}
+	for {
+		v, err := p.value(n)
+		if err != nil {
+			return nil, err
+		}
+		n.Values = append(n.Values, v)
+		if len(n.Values) > MaxValues {
+			return nil, p.errorf(p.tokens[p.pos-1], "in list has more than %d values", MaxValues)
+		}
+
+		t := p.next()
+		if t.is(")") {
+			return n, nil
+		}
+		if !t.is(",") {
+			return nil, p.errorf(t, "expected , or ) in value list, found %s", t)
+		}
+	}
+}
+
+func (p *parser) value(n *Compare) (any, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokString && n.Type == String:
+		return t.text, nil
+	case t.kind == tokInt && n.Type == Int:
+		v, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf(t, "integer %s is out of range", t.text)
+		}
+		return v, nil
+	case t.kind == tokString || t.kind == tokInt:
+		return nil, p.errorf(t, "field %s expects a %s value, found %s", n.Field, n.Type, t)
+	}
+	return nil, p.errorf(t, "expected %s value, found %s", n.Type, t)
+}
+
+func fieldList(fields map[string]Type) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func isKeyword(s string) bool {
+	switch strings.ToLower(s) {
+	case "and", "or", "not", "in":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package filterexpr_test
+
+import (
+	"errors"
+	"testing"
+
+	"effective-mobile/pkg/filterexpr"
+)
+
+var fields = map[string]filterexpr.Type{
+	"name":        filterexpr.String,
+	"gender":      filterexpr.String,
+	"nationality": filterexpr.String,
+	"age":         filterexpr.Int,
+}
+
+func TestParse(t *testing.T) {
+	cases := map[string]string{
+		`age >= 30 and (nationality in ("RU","KZ") or gender = "female")`: `(age >= 30 and (nationality in ("RU", "KZ") or gender = "female"))`,
+		`NOT name = 'O\'Brien' OR age <> -1`:                              `(not name = "O'Brien" or age != -1)`,
+		`age > 1 and age < 9 and gender not in ("male")`:                  `((age > 1 and age < 9) and gender not in ("male"))`,
+		`name = "Дмитрий"`:                                                `name = "Дмитрий"`,
+		`name = "a\\b\"c"`:                                                `name = "a\\b\"c"`,
+	}
+	for src, want := range cases {
+		n, err := filterexpr.Parse(src, fields)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", src, err)
+			continue
+		}
+		if got := n.String(); got != want {
+			t.Errorf("Parse(%q) = %s, want %s", src, got, want)
+		}
+		if again, err := filterexpr.Parse(n.String(), fields); err != nil || again.String() != want {
+			t.Errorf("canonical form %s does not parse back: %v", want, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		src string
+		pos int
+	}{
+		{`age >= `, 8},
+		{`salary > 10`, 1},
+		{`age = "old"`, 7},
+		{`name > "A"`, 6},
+		{`(age = 1`, 9},
+		{`age = 1 gender = "male"`, 9},
+		{`name = "Дмитрий" and ?`, 22},
+		{`name = "unterminated`, 8},
+		{`nationality in ("RU" "KZ")`, 22},
+	}
+	for _, c := range cases {
+		_, err := filterexpr.Parse(c.src, fields)
+		var syntaxErr *filterexpr.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) error = %v, want SyntaxError", c.src, err)
+			continue
+		}
+		if syntaxErr.Pos != c.pos {
+			t.Errorf("Parse(%q) error at %d, want %d: %v", c.src, syntaxErr.Pos, c.pos, err)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	n, err := filterexpr.Parse(`age >= 30 and (nationality in ("RU","KZ") or gender = "female")`, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		record map[string]any
+		want   bool
+	}{
+		{map[string]any{"age": int64(35), "nationality": "ru", "gender": "male"}, true},
+		{map[string]any{"age": int64(35), "nationality": "US", "gender": "Female"}, true},
+		{map[string]any{"age": int64(35), "nationality": "US", "gender": "male"}, false},
+		{map[string]any{"age": int64(20), "nationality": "RU", "gender": "female"}, false},
+	}
+	for _, c := range cases {
+		got := filterexpr.Eval(n, func(field string) any { return c.record[field] })
+		if got != c.want {
+			t.Errorf("Eval(%v) = %v, want %v", c.record, got, c.want)
+		}
+	}
+}
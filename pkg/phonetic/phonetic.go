@@ -0,0 +1,102 @@
+// Package phonetic computes sound-alike keys for Slavic names, a Russian
+// metaphone variant that works on any romanization produced by
+// translit.Normalize, so that English, German and Polish spellings of the
+// same surname share a key.
+package phonetic
+
+import (
+	"strings"
+
+	"effective-mobile/pkg/translit"
+)
+
+// sounds maps spellings of one sound to a single code. Uppercase codes stand
+// for sounds that need several Latin letters: S is "sh", C is "ch", Z is "zh"
+// and T is "ts". Longer spellings come first because the replacer prefers
+// the earlier of the patterns matching at the same position.
+var sounds = strings.NewReplacer(
+	"shch", "S",
+	"tsch", "C",
+	"dzh", "Z",
+	"sch", "S",
+	"tch", "C",
+	"sh", "S",
+	"sz", "S",
+	"ch", "C",
+	"cz", "C",
+	"zh", "Z",
+	"rz", "Z",
+	"ts", "T",
+	"tz", "T",
+	"cki", "Tki",
+	"cka", "Tka",
+	"ck", "k",
+	"ph", "f",
+	"ce", "Te",
+	"ci", "Ti",
+	"c", "k",
+	"q", "k",
+	"š", "S",
+	"ś", "S",
+	"č", "C",
+	"ć", "C",
+	"ž", "Z",
+	"ż", "Z",
+	"ź", "Z",
+	"ł", "l",
+	"ń", "n",
+)
+
+// devoiced maps voiced consonants to their voiceless pairs.
+var devoiced = map[rune]rune{'b': 'p', 'v': 'f', 'g': 'k', 'd': 't', 'z': 's', 'Z': 'S'}
+
+// voiceless are the codes before which a voiced consonant loses its voice.
+const voiceless = "pfktsSCTh"
+
+// Key returns the phonetic key of a name, one code group per word.
+// "Шевченко", "Shevchenko" and "Schewtschenko" all have the key "SfCnk".
+func Key(s string) string {
+	words := strings.Fields(translit.Normalize(s))
+	for i, w := range words {
+		words[i] = wordKey(w)
+	}
+	return strings.Join(words, " ")
+}
+
+func wordKey(word string) string {
+	var codes []rune
+	for i, r := range sounds.Replace(word) {
+		if isVowel(r) {
+			// Only a leading vowel is kept, which also makes gendered
+			// endings such as -ov/-ova and -skii/-skaia equivalent.
+			if i == 0 {
+				codes = append(codes, 'a')
+			}
+			continue
+		}
+		codes = append(codes, r)
+	}
+
+	// A voiced consonant is pronounced voiceless at the end of a word and
+	// before a voiceless one.
+	for i := len(codes) - 1; i >= 0; i-- {
+		pair, ok := devoiced[codes[i]]
+		if ok && (i == len(codes)-1 || strings.ContainsRune(voiceless, codes[i+1])) {
+			codes[i] = pair
+		}
+	}
+
+	var b strings.Builder
+	var prev rune
+	for _, r := range codes {
+		if r != prev {
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyáàâäéèêëíìîïóòôöúùûüýąę", r)
+}
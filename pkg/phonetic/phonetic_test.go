@@ -0,0 +1,44 @@
+package phonetic_test
+
+import (
+	"testing"
+
+	"effective-mobile/pkg/phonetic"
+)
+
+func TestKeyMatchesSpellingVariants(t *testing.T) {
+	groups := [][]string{
+		{"Шевченко", "Shevchenko", "Schewtschenko", "Szewczenko"},
+		{"Иванов", "Ivanov", "Iwanow", "Иванова"},
+		{"Жуков", "Zhukov", "Shukow", "Žukov"},
+		{"Савицкий", "Savitskiy", "Sawicki", "Савицкая"},
+		{"Чайковский", "Tchaikovsky", "Tschaikowski", "Czajkowski"},
+	}
+	for _, group := range groups {
+		want := phonetic.Key(group[0])
+		for _, name := range group[1:] {
+			if got := phonetic.Key(name); got != want {
+				t.Errorf("Key(%q) = %q, want %q like %q", name, got, want, group[0])
+			}
+		}
+	}
+}
+
+func TestKeyKeepsDistinctNamesApart(t *testing.T) {
+	pairs := [][2]string{
+		{"Иванов", "Ивашов"},
+		{"Петров", "Федоров"},
+		{"Шевченко", "Савченко"},
+	}
+	for _, pair := range pairs {
+		if a, b := phonetic.Key(pair[0]), phonetic.Key(pair[1]); a == b {
+			t.Errorf("Key(%q) and Key(%q) are both %q", pair[0], pair[1], a)
+		}
+	}
+}
+
+func TestKeyPerWord(t *testing.T) {
+	if got, want := phonetic.Key("Римский-Корсаков"), "rmsk krskf"; got != want {
+		t.Errorf("Key = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+// Package translit romanizes Russian names and folds spelling variants so
+// that names written in Cyrillic and Latin can be matched against each other.
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// icao is the ICAO Doc 9303 romanization used in Russian passports.
+var icao = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+	// Ukrainian and Belarusian letters that show up in Russian records.
+	'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g", 'ў': "u",
+}
+
+// ToLatin returns the lowercase ICAO romanization of s. Latin letters are
+// kept as is.
+func ToLatin(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if latin, ok := icao[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// latinFolds rewrite common alternative spellings to a single form. They are
+// applied in order after romanization.
+var latinFolds = strings.NewReplacer(
+	"kh", "h",
+	"x", "ks",
+	"w", "v",
+	"j", "i",
+	"y", "i",
+)
+
+// Normalize returns the matching key of a name: its ICAO romanization with
+// spelling variants folded, doubled letters collapsed and every run of
+// other characters, such as spaces and hyphens, replaced by one space.
+// "Дмитрий", "Dmitriy", "Dmitrij" and "Dmitry" all normalize to "dmitri".
+func Normalize(s string) string {
+	folded := latinFolds.Replace(ToLatin(s))
+
+	var b strings.Builder
+	var prev rune
+	for _, r := range folded {
+		if !unicode.IsLetter(r) {
+			r = ' '
+		}
+		if r == prev || (r == ' ' && b.Len() == 0) {
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return strings.TrimRight(b.String(), " ")
+}
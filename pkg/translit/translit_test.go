@@ -0,0 +1,41 @@
+package translit_test
+
+import (
+	"testing"
+
+	"effective-mobile/pkg/translit"
+)
+
+func TestToLatin(t *testing.T) {
+	cases := map[string]string{
+		"Дмитрий":    "dmitrii",
+		"Ушаков":     "ushakov",
+		"Щукина":     "shchukina",
+		"Юлия":       "iuliia",
+		"Васильевич": "vasilevich",
+		"Dmitriy":    "dmitriy",
+	}
+	for in, want := range cases {
+		if got := translit.ToLatin(in); got != want {
+			t.Errorf("ToLatin(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeMatchesAcrossScripts(t *testing.T) {
+	groups := [][]string{
+		{"Дмитрий", "Dmitriy", "Dmitry", "Dmitrij", "DMITRII"},
+		{"Юлия", "Yulia", "Julia", "Yuliya"},
+		{"Михаил", "Mikhail", "Mihail"},
+		{"Ушаков", "Ushakov"},
+		{"Анна-Мария", "Anna-Maria", "anna - maria"},
+	}
+	for _, group := range groups {
+		want := translit.Normalize(group[0])
+		for _, name := range group[1:] {
+			if got := translit.Normalize(name); got != want {
+				t.Errorf("Normalize(%q) = %q, want %q like %q", name, got, want, group[0])
+			}
+		}
+	}
+}
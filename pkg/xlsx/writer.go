@@ -0,0 +1,146 @@
+// Package xlsx writes single-sheet XLSX workbooks row by row, so that large
+// tables can be streamed without holding them in memory.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+
+	rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+
+	workbookTemplate = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+
+	sheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+	sheetFooter = `</sheetData></worksheet>`
+)
+
+// StreamWriter writes rows of a single worksheet. Close must be called to
+// finish the workbook.
+type StreamWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+// NewStreamWriter writes the workbook skeleton to w and opens a sheet with
+// the given name for writing.
+func NewStreamWriter(w io.Writer, sheetName string) (*StreamWriter, error) {
+	zw := zip.NewWriter(w)
+
+	var name xmlText
+	xml.EscapeText(&name, []byte(sheetName))
+
+	parts := []struct{ path, body string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbookTemplate, name)},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+	}
+	for _, p := range parts {
+		f, err := create(zw, p.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := create(zw, "xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, sheetHeader); err != nil {
+		return nil, err
+	}
+
+	return &StreamWriter{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Integers and floats become numeric cells,
+// time.Time is written in RFC 3339 and everything else as inline strings.
+func (s *StreamWriter) WriteRow(cells ...any) error {
+	s.row++
+
+	var buf xmlText
+	fmt.Fprintf(&buf, `<row r="%d">`, s.row)
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(s.row)
+		switch v := cell.(type) {
+		case int, int32, int64, uint, uint32, uint64, float32, float64:
+			fmt.Fprintf(&buf, `<c r="%s"><v>%v</v></c>`, ref, v)
+		case time.Time:
+			fmt.Fprintf(&buf, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, v.Format(time.RFC3339))
+		default:
+			fmt.Fprintf(&buf, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(&buf, []byte(fmt.Sprint(v)))
+			buf = append(buf, "</t></is></c>"...)
+		}
+	}
+	buf = append(buf, "</row>"...)
+
+	_, err := s.sheet.Write(buf)
+	return err
+}
+
+// Close finishes the sheet and the archive. It does not close the
+// underlying writer.
+func (s *StreamWriter) Close() error {
+	if _, err := io.WriteString(s.sheet, sheetFooter); err != nil {
+		return err
+	}
+	return s.zw.Close()
+}
+
+func create(zw *zip.Writer, name string) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+// columnName converts a zero-based column index to its letter name:
+// 0 -> A, 25 -> Z, 26 -> AA.
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+type xmlText []byte
+
+func (b *xmlText) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
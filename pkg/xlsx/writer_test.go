@@ -0,0 +1,59 @@
+package xlsx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"effective-mobile/pkg/xlsx"
+)
+
+func TestStreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	sw, err := xlsx.NewStreamWriter(&buf, "persons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.WriteRow("name", "age"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.WriteRow("Tom & <Jerry>", 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("missing part %s", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<row r="2">`,
+		`Tom &amp; &lt;Jerry&gt;`,
+		`<c r="B2"><v>42</v></c>`,
+		`</sheetData></worksheet>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Fatalf("sheet does not contain %q:\n%s", want, sheet)
+		}
+	}
+}